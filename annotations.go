@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Escape a GitHub Actions workflow command's message
+func escapeAnnotationData(value string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(value)
+}
+
+// Escape a GitHub Actions workflow command's property; properties are also split on colons and commas
+func escapeAnnotationProperty(value string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(value)
+}
+
+// Format a problem as a GitHub Actions annotation, so it's shown inline on a PR. Files are made
+// relative to root, the repository's checkout
+func GithubAnnotation(problem Problem, root string) string {
+	properties := []string{}
+
+	if len(problem.File) > 0 {
+		properties = append(properties, "file="+escapeAnnotationProperty(RelativeProblemFile(problem.File, root)))
+
+		if problem.Line > 0 {
+			properties = append(properties, "line="+strconv.Itoa(problem.Line))
+		}
+
+		if problem.Column > 0 {
+			properties = append(properties, "col="+strconv.Itoa(problem.Column))
+		}
+	}
+
+	properties = append(properties, "title="+escapeAnnotationProperty(problem.Source))
+
+	return "::" + ProblemLevel(problem) + " " + strings.Join(properties, ",") + "::" + escapeAnnotationData(problem.Message)
+}
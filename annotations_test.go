@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestGithubAnnotation(t *testing.T) {
+	tests := []struct {
+		name    string
+		problem Problem
+		want    string
+	}{
+		{
+			"Annotates files relative to the workspace",
+			Problem{"python", "/work/repo/src/main.py", 3, 0, "ValueError: bad", 6},
+			"::error file=src/main.py,line=3,title=python::ValueError: bad",
+		},
+		{
+			"Keeps files outside the workspace, and includes columns",
+			Problem{"go", "/tmp/main.go", 2, 14, "undefined: x", 2},
+			"::error file=/tmp/main.go,line=2,col=14,title=go::undefined: x",
+		},
+		{
+			"Annotates warnings as warnings",
+			Problem{"lint", "main.rs", 1, 0, "warning: unused variable", 1},
+			"::warning file=main.rs,line=1,title=lint::warning: unused variable",
+		},
+		{
+			"Escapes messages and properties",
+			Problem{"a,b", "a:b.py", 0, 0, "100% broken\nreally", 0},
+			"::error file=a%3Ab.py,title=a%2Cb::100%25 broken%0Areally",
+		},
+		{
+			"Omits the file of problems without one",
+			Problem{"todo", "", 0, 0, "tidy up", 0},
+			"::error title=todo::tidy up",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GithubAnnotation(tt.problem, "/work/repo"); got != tt.want {
+				t.Errorf("GithubAnnotation() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
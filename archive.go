@@ -0,0 +1,315 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docopt/docopt-go"
+)
+
+// How to recreate a session; written to its directory, and to exported archives
+type SessionManifest struct {
+	Version  int               `json:"version"`
+	Lang     string            `json:"lang"`
+	File     string            `json:"file"`
+	Command  string            `json:"command"`
+	Options  []string          `json:"options,omitempty"`
+	Env      map[string]string `json:"env,omitempty"`
+	Dotenv   string            `json:"dotenv,omitempty"`
+	StdinURL string            `json:"stdin_url,omitempty"`
+	Stdin    string            `json:"stdin,omitempty"`
+	Run      int64             `json:"run,omitempty"`
+	ExitCode int               `json:"exit_code,omitempty"`
+	Created  time.Time         `json:"created"`
+}
+
+// Describe how a session was started. The dotenv file's path is kept, so exports can include it
+func NewSessionManifest(args *ReplitArgs, dotenvPath string) SessionManifest {
+	name := filepath.Base(args.EditorFile.File.Name())
+
+	command := []string{}
+	for _, arg := range args.Options {
+		command = append(command, ShellQuote(arg))
+	}
+	command = append(command, args.Lang, ShellQuote(name))
+
+	stdinURL := ""
+	if args.Stdin != nil {
+		stdinURL = args.Stdin.URL
+	}
+
+	return SessionManifest{
+		Version:  SESSION_MANIFEST_VERSION,
+		Lang:     args.Lang,
+		File:     name,
+		Command:  strings.Join(command, " "),
+		Options:  args.Options,
+		Env:      args.Env,
+		Dotenv:   dotenvPath,
+		StdinURL: stdinURL,
+		Created:  time.Now(),
+	}
+}
+
+// The options a session was started with, as arguments. Defaults are left out, as are options
+// the manifest records separately
+func SessionOptions(opts docopt.Opts) []string {
+	defaults, _ := docopt.ParseArgs(Usage, []string{"lang"}, "")
+
+	keys := []string{}
+	for key := range opts {
+		if strings.HasPrefix(key, "--") && !MANIFEST_OWN_OPTIONS[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	argv := []string{}
+	for _, key := range keys {
+		if reflect.DeepEqual(opts[key], defaults[key]) {
+			continue
+		}
+
+		switch value := opts[key].(type) {
+		case bool:
+			if value {
+				argv = append(argv, key)
+			}
+		case string:
+			argv = append(argv, key, value)
+		case []string:
+			for _, item := range value {
+				argv = append(argv, key, item)
+			}
+		case int:
+			for count := 0; count < value; count++ {
+				argv = append(argv, key)
+			}
+		}
+	}
+
+	return argv
+}
+
+// Write a session's manifest to its directory
+func (session *Session) SaveManifest(manifest SessionManifest) error {
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(session.Dir, SESSION_MANIFEST), content, 0644)
+}
+
+// The most recently started session beneath a root directory
+func LatestSession(root string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(root, "*", SESSION_MANIFEST))
+	if err != nil {
+		return "", err
+	}
+
+	if len(matches) == 0 {
+		return "", fmt.Errorf("no sessions in %s", root)
+	}
+
+	// session directories are named by their start time
+	sort.Strings(matches)
+	return filepath.Dir(matches[len(matches)-1]), nil
+}
+
+// Add a file to an archive
+func addToArchive(archive *tar.Writer, name string, content []byte) error {
+	header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), ModTime: time.Now()}
+
+	if err := archive.WriteHeader(header); err != nil {
+		return err
+	}
+
+	_, err := archive.Write(content)
+	return err
+}
+
+// Bundle a session's manifest, its latest code, environment file, and output into a .tar.gz
+func ExportSession(dir string, fpath string) error {
+	content, err := ioutil.ReadFile(filepath.Join(dir, SESSION_MANIFEST))
+	if err != nil {
+		return errors.New("it has no manifest; was it started with --persist?")
+	}
+
+	var manifest SessionManifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return fmt.Errorf("could not parse its manifest: %v", err)
+	}
+
+	entries, err := LoadSessionRuns(dir)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		return errors.New("it has no finished runs")
+	}
+
+	last := entries[len(entries)-1]
+	manifest.Run = last.Run
+	manifest.ExitCode = last.Result.ExitCode
+
+	files := []struct {
+		name    string
+		content []byte
+	}{
+		{manifest.File, last.Source},
+		{"last-run.stdout", last.Stdout},
+		{"last-run.stderr", last.Stderr},
+	}
+
+	// the content piped to the last run, as its URL may have changed since
+	if stdin, err := ioutil.ReadFile(filepath.Join(dir, fmt.Sprintf("run-%d.stdin", last.Run))); err == nil {
+		files = append(files, struct {
+			name    string
+			content []byte
+		}{"last-run.stdin", stdin})
+		manifest.Stdin = "last-run.stdin"
+	}
+
+	if len(manifest.Dotenv) > 0 {
+		dotenv, err := ioutil.ReadFile(manifest.Dotenv)
+		if err != nil {
+			return fmt.Errorf("could not read its environment file: %v", err)
+		}
+
+		files = append(files, struct {
+			name    string
+			content []byte
+		}{".env", dotenv})
+		manifest.Dotenv = ".env"
+	}
+
+	encoded, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(fpath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	compressed := gzip.NewWriter(out)
+	archive := tar.NewWriter(compressed)
+
+	if err := addToArchive(archive, SESSION_MANIFEST, encoded); err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if err := addToArchive(archive, file.name, file.content); err != nil {
+			return err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+
+	return compressed.Close()
+}
+
+// Extract an exported session into a directory, returning its manifest. Only files at the archive's
+// top level are extracted, so an archive can't write elsewhere
+func ImportSession(fpath string, dir string) (SessionManifest, error) {
+	var manifest SessionManifest
+
+	conn, err := os.Open(fpath)
+	if err != nil {
+		return manifest, err
+	}
+	defer conn.Close()
+
+	compressed, err := gzip.NewReader(conn)
+	if err != nil {
+		return manifest, fmt.Errorf("not a .tar.gz archive: %v", err)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return manifest, err
+	}
+
+	archive := tar.NewReader(compressed)
+	found := false
+
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return manifest, err
+		}
+
+		name := header.Name
+		if header.Typeflag != tar.TypeReg || name != filepath.Base(name) || strings.HasPrefix(name, "..") {
+			continue
+		}
+
+		content, err := ioutil.ReadAll(archive)
+		if err != nil {
+			return manifest, err
+		}
+
+		if name == SESSION_MANIFEST {
+			if err := json.Unmarshal(content, &manifest); err != nil {
+				return manifest, fmt.Errorf("could not parse its manifest: %v", err)
+			}
+
+			found = true
+			continue
+		}
+
+		if err := ioutil.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			return manifest, err
+		}
+	}
+
+	if !found || len(manifest.File) == 0 || manifest.File != filepath.Base(manifest.File) {
+		return manifest, errors.New("the archive has no valid manifest")
+	}
+
+	return manifest, nil
+}
+
+// The arguments that restart an imported session in a directory, with the options it was started with
+func (manifest SessionManifest) Argv(dir string) []string {
+	argv := []string{"--directory", dir}
+
+	keys := []string{}
+	for key := range manifest.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		argv = append(argv, "--env", key+"="+manifest.Env[key])
+	}
+
+	if len(manifest.Dotenv) > 0 {
+		argv = append(argv, "--dotenv", filepath.Join(dir, manifest.Dotenv))
+	}
+
+	argv = append(argv, manifest.Options...)
+
+	return append(argv, manifest.Lang, filepath.Join(dir, manifest.File))
+}
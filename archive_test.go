@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/docopt/docopt-go"
+)
+
+func TestExportImportSession(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replit-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sessionDir := filepath.Join(dir, "session")
+	os.Mkdir(sessionDir, 0755)
+
+	dotenv := filepath.Join(dir, "original.env")
+	ioutil.WriteFile(dotenv, []byte("TOKEN=abc\n"), 0644)
+
+	session := &Session{Dir: sessionDir}
+	session.SaveManifest(SessionManifest{
+		Version:  SESSION_MANIFEST_VERSION,
+		Lang:     "python3",
+		File:     "main.py",
+		Command:  "--stdin-url https://example.com/in.txt --timeout 5s python3 main.py",
+		Options:  []string{"--stdin-url", "https://example.com/in.txt", "--timeout", "5s"},
+		Env:      map[string]string{"DEBUG": "1"},
+		Dotenv:   dotenv,
+		StdinURL: "https://example.com/in.txt",
+	})
+
+	for _, run := range []int64{1, 2} {
+		entry := HistoryEntry{Run: run, Result: RunResult{ExitCode: int(run)}, Source: []byte(fmt.Sprintf("print(%d)\n", run))}
+		if err := session.SaveRun(entry, ".py"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := session.SaveStdin(run, []byte(fmt.Sprintf("input %d\n", run))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archive := filepath.Join(dir, "session.tar.gz")
+	if err := ExportSession(sessionDir, archive); err != nil {
+		t.Fatal(err)
+	}
+
+	target := filepath.Join(dir, "imported")
+	manifest, err := ImportSession(archive, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if manifest.Run != 2 || manifest.ExitCode != 2 {
+		t.Errorf("expected the manifest to describe the last run, but got %+v", manifest)
+	}
+
+	if source, _ := ioutil.ReadFile(filepath.Join(target, "main.py")); string(source) != "print(2)\n" {
+		t.Errorf("expected the last run's code, but got %q", source)
+	}
+
+	if env, _ := ioutil.ReadFile(filepath.Join(target, ".env")); string(env) != "TOKEN=abc\n" {
+		t.Errorf("expected the environment file, but got %q", env)
+	}
+
+	if stdin, _ := ioutil.ReadFile(filepath.Join(target, manifest.Stdin)); string(stdin) != "input 2\n" {
+		t.Errorf("expected the last run's stdin, but got %q", stdin)
+	}
+
+	want := []string{
+		"--directory", target, "--env", "DEBUG=1", "--dotenv", filepath.Join(target, ".env"),
+		"--stdin-url", "https://example.com/in.txt", "--timeout", "5s", "python3", filepath.Join(target, "main.py"),
+	}
+	if got := manifest.Argv(target); !reflect.DeepEqual(got, want) {
+		t.Errorf("Argv() = %v, want %v", got, want)
+	}
+}
+
+func TestSessionOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		argv []string
+		want []string
+	}{
+		{"Leaves out defaults", []string{"python3", "main.py"}, []string{}},
+		{
+			"Keeps options given",
+			[]string{"--charset", "latin1", "--retries", "2", "--stdin-url", "https://example.com/in.txt", "--refetch", "python3", "main.py"},
+			[]string{"--charset", "latin1", "--refetch", "--retries", "2", "--stdin-url", "https://example.com/in.txt"},
+		},
+		{
+			"Leaves out options the manifest records",
+			[]string{"--directory", "/tmp", "--env", "A=1", "--dotenv", ".env", "--combined", "python3"},
+			[]string{"--combined"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := docopt.ParseArgs(Usage, tt.argv, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := SessionOptions(opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SessionOptions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// The size and modification time of a file, for noticing when a run creates or modifies it
+type FileStamp struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// Record the size and modification time of every file beneath a directory
+func SnapshotDirectory(dir string) map[string]FileStamp {
+	stamps := map[string]FileStamp{}
+
+	filepath.Walk(dir, func(fpath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		stamps[fpath] = FileStamp{info.Size(), info.ModTime()}
+		return nil
+	})
+
+	return stamps
+}
+
+// List the files created or modified between two snapshots
+func ChangedFiles(before map[string]FileStamp, after map[string]FileStamp) []string {
+	changed := []string{}
+
+	for fpath, stamp := range after {
+		if prev, ok := before[fpath]; !ok || prev != stamp {
+			changed = append(changed, fpath)
+		}
+	}
+
+	sort.Strings(changed)
+	return changed
+}
+
+// List the files created, modified, or removed between two snapshots
+func TouchedFiles(before map[string]FileStamp, after map[string]FileStamp) []string {
+	touched := ChangedFiles(before, after)
+
+	for fpath := range before {
+		if _, ok := after[fpath]; !ok {
+			touched = append(touched, fpath)
+		}
+	}
+
+	sort.Strings(touched)
+	return touched
+}
+
+// Open a file with the desktop's default application
+func OpenFile(fpath string) error {
+	opener := "xdg-open"
+	if runtime.GOOS == "darwin" {
+		opener = "open"
+	}
+
+	return exec.Command(opener, fpath).Start()
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// The outcome of running one file in a batch
+type BatchResult struct {
+	File     string
+	Status   string
+	ExitCode int
+	Duration time.Duration
+	Output   []byte
+}
+
+// The processes of the batch in flight, and each file's latest result. Slots limits how many
+// processes run at once; each Run or Kill starts a new generation, so queued files of an
+// earlier batch never start
+type BatchState struct {
+	Lock       sync.Mutex
+	Cmds       []*exec.Cmd
+	Results    []*BatchResult
+	Slots      chan struct{}
+	generation int
+}
+
+// Construct a batch that runs at most jobs processes at once
+func NewBatchState(jobs int) *BatchState {
+	return &BatchState{Slots: make(chan struct{}, jobs)}
+}
+
+// List the files beneath a directory that match a batch glob
+func MatchBatch(dpath string, pattern string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dpath, pattern))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// Describe a batch result in one line of the batch pane
+func BatchLabel(result BatchResult, dpath string) string {
+	name, err := filepath.Rel(dpath, result.File)
+	if err != nil {
+		name = result.File
+	}
+
+	switch result.Status {
+	case BATCH_PASSED:
+		return fmt.Sprintf("[green]✔[reset] %s  %s", tview.Escape(name), FormatDuration(result.Duration))
+	case BATCH_FAILED:
+		return fmt.Sprintf("[red]✘[reset] %s  exit %d · %s", tview.Escape(name), result.ExitCode, FormatDuration(result.Duration))
+	case BATCH_RUNNING:
+		return "[yellow]…[reset] " + tview.Escape(name)
+	}
+
+	return "  " + tview.Escape(name)
+}
+
+// Copy the batch results, so they can be rendered outside the lock
+func (state *BatchState) Snapshot() []BatchResult {
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	results := make([]BatchResult, len(state.Results))
+	for idx, result := range state.Results {
+		results[idx] = *result
+	}
+
+	return results
+}
+
+// Kill every process of the batch in flight
+func (state *BatchState) Kill() {
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	for _, cmd := range state.Cmds {
+		KillProcess(cmd)
+	}
+
+	state.Cmds = nil
+	state.generation += 1
+}
+
+// Stop tracking a finished process, so it is never signalled after its pid is reused
+func (state *BatchState) forget(cmd *exec.Cmd) {
+	for idx, tracked := range state.Cmds {
+		if tracked == cmd {
+			state.Cmds = append(state.Cmds[:idx], state.Cmds[idx+1:]...)
+			return
+		}
+	}
+}
+
+// Run one file of a batch once a slot is free, recording its combined output and exit code
+func (state *BatchState) runFile(args *ReplitArgs, tui *TUI, result *BatchResult, generation int) {
+	state.Slots <- struct{}{}
+	defer func() { <-state.Slots }()
+
+	cmd := LanguageCommand(args, result.File)
+
+	var output bytes.Buffer
+	// the charset was checked when reading arguments
+	writer, _ := NewCharsetWriter(&output, args.Charset)
+
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	cmd.Dir = args.Dpath
+	cmd.Env = ChildEnv(args)
+	ConfigureProcess(cmd, args)
+
+	startTime := time.Now()
+
+	// the batch was killed or replaced while this file was queued
+	state.Lock.Lock()
+	if state.generation != generation {
+		state.Lock.Unlock()
+		return
+	}
+
+	err := StartProcess(cmd)
+	if err == nil {
+		state.Cmds = append(state.Cmds, cmd)
+	}
+	result.Status = BATCH_RUNNING
+	state.Lock.Unlock()
+	tui.ShowBatch(state.Snapshot())
+
+	exitCode := -1
+	if err != nil {
+		fmt.Fprintf(writer, "could not run %s: %v\n", args.Lang, err)
+	} else {
+		if err := PrioritizeProcess(cmd, args); err != nil {
+			fmt.Fprintf(writer, "could not lower the priority of %s: %v\n", args.Lang, err)
+		}
+
+		cmd.Wait()
+		exitCode = cmd.ProcessState.ExitCode()
+	}
+
+	writer.Close()
+
+	state.Lock.Lock()
+	state.forget(cmd)
+	result.ExitCode = exitCode
+	result.Duration = time.Since(startTime)
+	result.Output = output.Bytes()
+
+	if exitCode == 0 {
+		result.Status = BATCH_PASSED
+	} else {
+		result.Status = BATCH_FAILED
+	}
+	state.Lock.Unlock()
+
+	tui.ShowBatch(state.Snapshot())
+}
+
+// Rerun every file matching the batch glob, replacing any batch still in flight
+func (state *BatchState) Run(args *ReplitArgs, tui *TUI) {
+	state.Kill()
+
+	files, err := MatchBatch(args.Dpath, args.Batch)
+	if err != nil {
+		tui.ReportError(fmt.Errorf("invalid --batch pattern: %v", err))
+		return
+	}
+
+	results := []*BatchResult{}
+	for _, fpath := range files {
+		results = append(results, &BatchResult{File: fpath, Status: BATCH_PENDING, ExitCode: -1})
+	}
+
+	state.Lock.Lock()
+	state.Results = results
+	generation := state.generation
+	state.Lock.Unlock()
+
+	tui.ShowBatch(state.Snapshot())
+
+	for _, result := range results {
+		go state.runFile(args, tui, result, generation)
+	}
+}
+
+// Rerun the batch whenever a file changes, and kill it on request
+func RunBatches(args *ReplitArgs, tui *TUI, state *BatchState) {
+	attachListener(tui.actions.killProcess, state.Kill)
+
+	attachListener(tui.actions.fileChange, func() {
+		state.Run(args, tui)
+	})
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMatchBatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replit-batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.Mkdir(filepath.Join(dir, "examples"), 0755)
+	for _, name := range []string{"examples/b.py", "examples/a.py", "examples/notes.md", "main.py"} {
+		ioutil.WriteFile(filepath.Join(dir, name), []byte{}, 0644)
+	}
+
+	tests := []struct {
+		name    string
+		pattern string
+		want    []string
+	}{
+		{"Matches files in a subdirectory, sorted", "examples/*.py", []string{"examples/a.py", "examples/b.py"}},
+		{"Matches files at the top level only", "*.py", []string{"main.py"}},
+		{"Matches nothing", "*.rb", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := MatchBatch(dir, tt.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, fpath := range files {
+				rel, _ := filepath.Rel(dir, fpath)
+				got = append(got, rel)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MatchBatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBatchSkipsQueuedFilesOfKilledBatch(t *testing.T) {
+	state := NewBatchState(1)
+	result := &BatchResult{File: "main.py", Status: BATCH_PENDING, ExitCode: -1}
+
+	state.Kill()
+	state.runFile(&ReplitArgs{Lang: "true", Config: &Config{}}, nil, result, 0)
+
+	if result.Status != BATCH_PENDING || len(state.Cmds) != 0 {
+		t.Errorf("expected the queued file not to start, but its status is %s", result.Status)
+	}
+
+	if len(state.Slots) != 0 {
+		t.Errorf("expected the file's slot to be released")
+	}
+}
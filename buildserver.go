@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// A long-lived build daemon, as shell commands with {file}, {lang}, and {dir} placeholders; one that
+// starts it with the session, one that builds and runs the file through it, and one that stops it
+type BuildServer struct {
+	Start string `json:"start"`
+	Run   string `json:"run"`
+	Stop  string `json:"stop"`
+}
+
+// A build server started for a session, and whether its start command has exited
+type RunningBuildServer struct {
+	server *BuildServer
+	cmd    *exec.Cmd
+	done   chan struct{}
+}
+
+// Choose the build server; a named preset, or else the config's build_server, if it has a run command
+func ResolveBuildServer(name string, configured BuildServer) (*BuildServer, error) {
+	if len(name) == 0 {
+		if len(configured.Run) == 0 {
+			return nil, nil
+		}
+
+		return &configured, nil
+	}
+
+	preset, ok := BUILD_SERVERS[name]
+	if !ok {
+		names := []string{}
+		for known := range BUILD_SERVERS {
+			names = append(names, known)
+		}
+		sort.Strings(names)
+
+		return nil, fmt.Errorf("unknown build server '%s'; expected one of %s", name, strings.Join(names, ", "))
+	}
+
+	if program := strings.Fields(preset.Run)[0]; !CommandExists(program) {
+		return nil, errors.New(program + " is not in PATH")
+	}
+
+	return &preset, nil
+}
+
+// Expand one of the server's commands for a file
+func (server *BuildServer) Script(command string, args *ReplitArgs, fpath string) string {
+	return ExpandTemplate(command, map[string]string{
+		"file": ShellQuote(fpath),
+		"lang": args.Lang,
+		"dir":  ShellQuote(args.Dpath),
+	})
+}
+
+// Run the build server's start command in the watched directory, reporting if it fails. Clients
+// such as gradle's connect to the daemon once it is up, or start one themselves, so runs don't wait for it
+func StartBuildServer(args *ReplitArgs, tui *TUI) *RunningBuildServer {
+	server := args.BuildServer
+	if server == nil || len(server.Start) == 0 {
+		return nil
+	}
+
+	var stderr bytes.Buffer
+
+	cmd := exec.Command("sh", "-c", server.Script(server.Start, args, args.EditorFile.File.Name()))
+	cmd.Dir = args.Dpath
+	cmd.Env = ChildEnv(args)
+	cmd.Stderr = &stderr
+	ConfigureProcess(cmd, args)
+
+	if err := StartProcess(cmd); err != nil {
+		tui.ReportError(fmt.Errorf("could not start the build server: %v", err))
+		return nil
+	}
+
+	running := &RunningBuildServer{server, cmd, make(chan struct{})}
+
+	go func() {
+		defer close(running.done)
+
+		if err := cmd.Wait(); err != nil && cmd.ProcessState != nil && cmd.ProcessState.ExitCode() > 0 {
+			tui.ReportError(fmt.Errorf("the build server exited: %s", LastLine(stderr.String(), err.Error())))
+		}
+	}()
+
+	return running
+}
+
+// Run the build server's stop command, then kill its start command if it is still running
+func (running *RunningBuildServer) Stop(args *ReplitArgs) {
+	if len(running.server.Stop) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), BUILD_SERVER_STOP_TIMEOUT)
+		defer cancel()
+
+		cmd := exec.CommandContext(ctx, "sh", "-c", running.server.Script(running.server.Stop, args, args.EditorFile.File.Name()))
+		cmd.Dir = args.Dpath
+		cmd.Env = ChildEnv(args)
+		cmd.Run()
+	}
+
+	select {
+	case <-running.done:
+	default:
+		KillProcess(running.cmd)
+	}
+}
+
+// The last non-empty line of text, or a fallback if there is none
+func LastLine(text string, fallback string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	if last := strings.TrimSpace(lines[len(lines)-1]); len(last) > 0 {
+		return last
+	}
+
+	return fallback
+}
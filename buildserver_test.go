@@ -0,0 +1,55 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveBuildServer(t *testing.T) {
+	configured := BuildServer{Start: "bloop server", Run: "bloop run root"}
+
+	tests := []struct {
+		name       string
+		server     string
+		configured BuildServer
+		want       *BuildServer
+		wantErr    bool
+	}{
+		{"No server without a name or config", "", BuildServer{}, nil, false},
+		{"Uses the config's server", "", configured, &configured, false},
+		{"Ignores a config without a run command", "", BuildServer{Start: "bloop server"}, nil, false},
+		{"Rejects unknown names", "maven", configured, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveBuildServer(tt.server, tt.configured)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveBuildServer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveBuildServer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildServerPipeline(t *testing.T) {
+	args := &ReplitArgs{
+		Lang:        "kotlin",
+		Dpath:       "/src/my app",
+		Config:      &Config{},
+		BuildServer: &BuildServer{Run: "client run {file} --dir {dir} --lang {lang}"},
+	}
+
+	commands := Pipeline(args, []string{"/src/my app/Main.kt"})
+	if len(commands) != 1 {
+		t.Fatalf("Pipeline() returned %d commands, want 1", len(commands))
+	}
+
+	want := []string{"sh", "-c", "client run '/src/my app/Main.kt' --dir '/src/my app' --lang kotlin"}
+	if cmd := commands[0].Cmd; !reflect.DeepEqual(cmd.Args, want) || cmd.Dir != args.Dpath {
+		t.Errorf("Pipeline() = %v in %s, want %v in %s", cmd.Args, cmd.Dir, want, args.Dpath)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// A screen that reduces the styles drawn on it to those a terminal with few colours can show
+type ReducedScreen struct {
+	tcell.Screen
+	colors int
+}
+
+func (screen *ReducedScreen) SetContent(x int, y int, primary rune, combining []rune, style tcell.Style) {
+	screen.Screen.SetContent(x, y, primary, combining, ReduceStyle(style, screen.colors))
+}
+
+func (screen *ReducedScreen) Fill(char rune, style tcell.Style) {
+	screen.Screen.Fill(char, ReduceStyle(style, screen.colors))
+}
+
+// How many colours the terminal shows; none if $NO_COLOR is set
+func ScreenColors(screen tcell.Screen) int {
+	if len(os.Getenv("NO_COLOR")) > 0 {
+		return 0
+	}
+
+	return screen.Colors()
+}
+
+// Open the terminal for an application, reducing what's drawn to the colours it shows. If the
+// terminal can't be opened, the application reports why when it runs
+func OpenScreen(app *tview.Application, mouse bool) {
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		return
+	}
+
+	if err := screen.Init(); err != nil {
+		return
+	}
+
+	if mouse {
+		screen.EnableMouse()
+	}
+
+	if colors := ScreenColors(screen); colors < MIN_FULL_COLORS {
+		app.SetScreen(&ReducedScreen{screen, colors})
+	} else {
+		app.SetScreen(screen)
+	}
+}
+
+// Reduce a style to the colours a terminal shows. Colours become the nearest basic colour, except
+// black, white and grey, which use the terminal's own text colour so they're legible on light and dark
+// backgrounds. Without colours, red text is bold, yellow underlined, grey dim, and backgrounds reversed
+func ReduceStyle(style tcell.Style, colors int) tcell.Style {
+	if colors >= MIN_FULL_COLORS {
+		return style
+	}
+
+	fg, bg, attrs := style.Decompose()
+
+	if colors < len(BASIC_COLORS)/2 {
+		if fg.Valid() {
+			switch tcell.FindColor(fg, BASIC_COLORS) {
+			case tcell.ColorRed, tcell.ColorMaroon, tcell.ColorFuchsia, tcell.ColorPurple:
+				attrs |= tcell.AttrBold
+			case tcell.ColorYellow, tcell.ColorOlive:
+				attrs |= tcell.AttrUnderline
+			case tcell.ColorGray, tcell.ColorBlack:
+				attrs |= tcell.AttrDim
+			}
+		}
+
+		if bg.Valid() {
+			attrs |= tcell.AttrReverse
+		}
+
+		return style.Foreground(tcell.ColorDefault).Background(tcell.ColorDefault).Attributes(attrs)
+	}
+
+	fg, dim := reduceColor(fg, colors)
+	if dim {
+		attrs |= tcell.AttrDim
+	}
+	bg, _ = reduceColor(bg, colors)
+
+	return style.Foreground(fg).Background(bg).Attributes(attrs)
+}
+
+// The basic colour nearest a colour, and whether it's a grey that should be dimmed instead
+func reduceColor(color tcell.Color, colors int) (tcell.Color, bool) {
+	if !color.Valid() {
+		return color, false
+	}
+
+	basic := tcell.FindColor(color, BASIC_COLORS)
+
+	switch basic {
+	case tcell.ColorBlack, tcell.ColorGray:
+		return tcell.ColorDefault, true
+	case tcell.ColorSilver, tcell.ColorWhite:
+		return tcell.ColorDefault, false
+	}
+
+	// terminals with eight colours show the bright colours' darker counterparts
+	if index := int(basic - tcell.ColorValid); index >= colors {
+		return BASIC_COLORS[index-len(BASIC_COLORS)/2], false
+	}
+
+	return basic, false
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestReduceStyle(t *testing.T) {
+	plain := tcell.StyleDefault
+
+	tests := []struct {
+		name   string
+		style  tcell.Style
+		colors int
+		want   tcell.Style
+	}{
+		{"Keeps styles on full colour terminals", plain.Foreground(tcell.NewHexColor(0x336699)), 256, plain.Foreground(tcell.NewHexColor(0x336699))},
+		{"Keeps default colours", plain.Bold(true), 8, plain.Bold(true)},
+		{"Keeps basic colours", plain.Foreground(tcell.ColorRed), 16, plain.Foreground(tcell.ColorRed)},
+		{"Darkens bright colours on eight colour terminals", plain.Foreground(tcell.ColorRed), 8, plain.Foreground(tcell.ColorMaroon)},
+		{"Finds the nearest basic colour", plain.Foreground(tcell.NewHexColor(0x00ee00)), 16, plain.Foreground(tcell.ColorLime)},
+		{"Dims grey text", plain.Foreground(tcell.ColorGray), 16, plain.Dim(true)},
+		{"Uses the terminal's text colour for white", plain.Foreground(tcell.ColorWhite), 8, plain},
+		{"Uses the terminal's background for black", plain.Background(tcell.ColorBlack), 16, plain},
+		{"Emboldens red without colours", plain.Foreground(tcell.ColorRed), 0, plain.Bold(true)},
+		{"Underlines yellow without colours", plain.Foreground(tcell.ColorYellow), 0, plain.Underline(true)},
+		{"Dims grey without colours", plain.Foreground(tcell.ColorGray), 0, plain.Dim(true)},
+		{"Reverses backgrounds without colours", plain.Foreground(tcell.ColorWhite).Background(tcell.ColorBlue), 0, plain.Reverse(true)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ReduceStyle(tt.style, tt.colors); got != tt.want {
+				t.Errorf("ReduceStyle() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
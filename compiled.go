@@ -0,0 +1,246 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The family of compiled language a language's program builds, or "" if it runs files directly
+func CompiledLanguage(lang string) string {
+	program, _ := SplitLanguage(lang)
+
+	switch filepath.Base(program) {
+	case "cc", "gcc", "clang", "c++", "g++", "clang++", "tcc":
+		return COMPILED_C
+	case "rustc":
+		return COMPILED_RUST
+	}
+
+	return ""
+}
+
+// The extension a scratch file needs for the compiler to recognise its language
+func ScratchExtension(lang string) string {
+	program, _ := SplitLanguage(lang)
+
+	switch filepath.Base(program) {
+	case "cc", "gcc", "clang", "tcc":
+		return ".c"
+	case "c++", "g++", "clang++":
+		return ".cpp"
+	case "rustc":
+		return ".rs"
+	case SQL_LANGUAGE:
+		return ".sql"
+	}
+
+	return ""
+}
+
+// A path for scratch files this process owns, such as binaries and diagnostics logs
+func ScratchPath(kind string) string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("replit-%s-%d", kind, os.Getpid()))
+}
+
+// Check sanitizers and valgrind can be used with the language
+func ValidateNativeChecks(lang string, sanitizers []string, valgrind bool) error {
+	family := CompiledLanguage(lang)
+
+	if len(sanitizers) > 0 {
+		if family != COMPILED_C {
+			return errors.New("sanitizers are supported for C and C++ compilers")
+		}
+
+		for _, sanitizer := range sanitizers {
+			switch sanitizer {
+			case "address", "undefined", "leak", "thread", "memory":
+			default:
+				return fmt.Errorf("unknown sanitizer '%s'", sanitizer)
+			}
+		}
+	}
+
+	if valgrind {
+		if len(family) == 0 {
+			return errors.New("valgrind requires a compiled language, such as gcc or rustc")
+		}
+
+		if !CommandExists("valgrind") {
+			return errors.New("valgrind is not in PATH")
+		}
+	}
+
+	return nil
+}
+
+// Build a file, then run the binary; under valgrind, or with sanitizers, if requested. Their
+// reports are logged to scratch files rather than stderr, so they can be listed as problems.
+// Builds are cached, so the build is skipped when the source and flags haven't changed
+func CompiledPipeline(args *ReplitArgs, fpath string) []PipelineCommand {
+	program, flags := SplitLanguage(args.Lang)
+	diagnostics := ScratchPath("diagnostics")
+
+	build := append([]string{program}, flags...)
+	if len(args.Sanitize) > 0 {
+		build = append(build, "-g", "-fno-omit-frame-pointer", "-fsanitize="+strings.Join(args.Sanitize, ","))
+	}
+	build = append(build, fpath)
+
+	binary := ScratchPath("build")
+	output := binary
+	cached := false
+	var store func() error
+
+	// sandboxes give each command its own /tmp, and a read-only home, so their builds aren't cached
+	if !args.NoBuildCache && len(args.Sandbox) == 0 {
+		if cachePath, err := BuildCachePath(build, fpath); err == nil {
+			binary = cachePath
+			cached = UseCachedBuild(cachePath)
+
+			// compilers may leave partial output when killed, so builds only enter the cache once they succeed
+			output = fmt.Sprintf("%s.partial-%d", cachePath, os.Getpid())
+			store = func() error {
+				return StoreBuild(output, cachePath)
+			}
+		}
+	}
+
+	commands := []PipelineCommand{}
+	if !cached {
+		commands = append(commands, PipelineCommand{Name: program, Cmd: WrapCommand(args, fpath, append(build, "-o", output)), OnSuccess: store})
+	}
+
+	run := []string{binary}
+	if args.Valgrind {
+		run = append([]string{"valgrind", "--quiet", "--error-exitcode=1", "--log-file=" + diagnostics + ".valgrind"}, run...)
+	}
+
+	return append(commands, PipelineCommand{
+		Name: "run",
+		Cmd:  WrapCommand(args, fpath, run),
+		Env: []string{
+			"ASAN_OPTIONS=log_path=" + diagnostics + ".asan",
+			"UBSAN_OPTIONS=print_stacktrace=1:log_path=" + diagnostics + ".ubsan",
+		},
+	})
+}
+
+// The directory builds are cached in, across sessions
+func BuildCacheDir() string {
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "replit", "builds")
+	}
+
+	return filepath.Join(os.TempDir(), "replit-builds")
+}
+
+// Where a build is cached; keyed by a hash of the compiler, its flags, and the source. Files beside the
+// source that a compiler may read, such as headers and rust modules, are hashed too
+func BuildCachePath(build []string, fpath string) (string, error) {
+	hash := sha256.New()
+
+	compiler, err := exec.LookPath(build[0])
+	if err != nil {
+		return "", err
+	}
+
+	for _, arg := range append([]string{compiler}, build[1:]...) {
+		hash.Write([]byte(arg + "\x00"))
+	}
+
+	content, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return "", err
+	}
+
+	hash.Write(content)
+
+	neighbours, _ := filepath.Glob(filepath.Join(filepath.Dir(fpath), "*"))
+	for _, neighbour := range neighbours {
+		if neighbour == fpath || !BUILD_INPUT_EXTENSIONS[filepath.Ext(neighbour)] {
+			continue
+		}
+
+		if content, err := ioutil.ReadFile(neighbour); err == nil {
+			hash.Write([]byte("\x00" + filepath.Base(neighbour) + "\x00"))
+			hash.Write(content)
+		}
+	}
+
+	dir := BuildCacheDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, hex.EncodeToString(hash.Sum(nil))), nil
+}
+
+// Is the build cached? Using a build marks it recent, so it's kept when the cache is pruned
+func UseCachedBuild(cachePath string) bool {
+	if _, err := os.Stat(cachePath); err != nil {
+		return false
+	}
+
+	now := time.Now()
+	os.Chtimes(cachePath, now, now)
+
+	return true
+}
+
+// Move a finished build into the cache, and remove the least recently used builds beyond its limit
+func StoreBuild(output string, cachePath string) error {
+	if err := os.Rename(output, cachePath); err != nil {
+		return err
+	}
+
+	entries, err := ioutil.ReadDir(filepath.Dir(cachePath))
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime().After(entries[j].ModTime())
+	})
+
+	for idx, entry := range entries {
+		if idx >= BUILD_CACHE_ENTRIES {
+			os.Remove(filepath.Join(filepath.Dir(cachePath), entry.Name()))
+		}
+	}
+
+	return nil
+}
+
+// Read and remove the sanitizer and valgrind reports of the last run
+func CollectDiagnostics() []Problem {
+	problems := []Problem{}
+
+	// sanitizers suffix their logs with the reporting process's pid
+	logs, _ := filepath.Glob(ScratchPath("diagnostics") + ".*")
+
+	for _, fpath := range logs {
+		data, err := ioutil.ReadFile(fpath)
+		os.Remove(fpath)
+
+		if err != nil {
+			continue
+		}
+
+		if strings.Contains(fpath, ".valgrind") {
+			problems = append(problems, ParseValgrind(data)...)
+		} else {
+			problems = append(problems, ParseSanitizer(data)...)
+		}
+	}
+
+	return problems
+}
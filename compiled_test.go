@@ -0,0 +1,59 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildCachePath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replit-build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer os.Setenv("XDG_CACHE_HOME", os.Getenv("XDG_CACHE_HOME"))
+	os.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+
+	src := filepath.Join(dir, "src")
+	os.Mkdir(src, 0755)
+
+	fpath := filepath.Join(src, "main.c")
+	ioutil.WriteFile(fpath, []byte("int main() { return 0; }"), 0644)
+	ioutil.WriteFile(filepath.Join(src, "notes.md"), []byte("notes"), 0644)
+
+	key := func(build ...string) string {
+		cachePath, err := BuildCachePath(append(build, fpath), fpath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return cachePath
+	}
+
+	original := key("sh", "-O2")
+
+	if key("sh", "-O2") != original {
+		t.Error("expected the same source and flags to share a build")
+	}
+
+	if key("sh", "-O0") == original {
+		t.Error("expected other flags to need another build")
+	}
+
+	ioutil.WriteFile(filepath.Join(src, "notes.md"), []byte("edited"), 0644)
+	if key("sh", "-O2") != original {
+		t.Error("expected files compilers don't read to be ignored")
+	}
+
+	ioutil.WriteFile(filepath.Join(src, "util.h"), []byte("#define X 1"), 0644)
+	if key("sh", "-O2") == original {
+		t.Error("expected a header beside the source to need another build")
+	}
+
+	if filepath.Dir(original) != filepath.Join(dir, "cache", "replit", "builds") {
+		t.Errorf("expected builds to be cached in the user's cache directory, not %s", filepath.Dir(original))
+	}
+}
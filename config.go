@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Settings read from a JSON config file
+type Config struct {
+	Header   string                     `json:"header"`
+	Help     string                     `json:"help"`
+	Sandbox  SandboxProfile             `json:"sandbox"`
+	Schedule Schedules                  `json:"schedule"`
+	Pipeline []PipelineStep             `json:"pipeline"`
+	Build    BuildServer                `json:"build_server"`
+	MaxJobs  int                        `json:"max_jobs"`
+	DrawFPS  int                        `json:"draw_fps"`
+	NoMouse  bool                       `json:"no_mouse"`
+	MaxFiles *int                       `json:"max_watched_files"`
+	Matchers []ProblemMatcher           `json:"problem_matchers"`
+	Webhooks []Webhook                  `json:"webhooks"`
+	Profiles map[string]json.RawMessage `json:"profiles"`
+}
+
+// Cron expressions to rerun on; either one expression, or a list of them
+type Schedules []string
+
+func (schedules *Schedules) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*schedules = Schedules{single}
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return errors.New("schedule must be a cron expression, or a list of them")
+	}
+
+	*schedules = many
+	return nil
+}
+
+// Find the config file; an explicit path, the watched directory's .replit.json, or the user's config
+func ConfigPath(explicit string, dpath string) string {
+	if len(explicit) > 0 {
+		return explicit
+	}
+
+	local := filepath.Join(dpath, LOCAL_CONFIG_NAME)
+	if _, err := os.Stat(local); err == nil {
+		return local
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(configDir, "replit", "config.json")
+}
+
+// Read a config file, and apply the named profile's settings over it. A missing file is an empty config
+func LoadConfig(fpath string, profile string) (*Config, error) {
+	config := Config{}
+
+	content, err := ioutil.ReadFile(fpath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if err == nil {
+		if err := json.Unmarshal(content, &config); err != nil {
+			return nil, fmt.Errorf("could not parse %s: %v", fpath, err)
+		}
+	}
+
+	if len(profile) == 0 {
+		return &config, nil
+	}
+
+	raw, ok := config.Profiles[profile]
+	if !ok {
+		return nil, fmt.Errorf("profile '%s' is not defined in %s", profile, fpath)
+	}
+
+	// profiles only set the keys they define, so decode over the base settings
+	if err := json.Unmarshal(raw, &config); err != nil {
+		return nil, fmt.Errorf("could not parse profile '%s': %v", profile, err)
+	}
+
+	return &config, nil
+}
+
+// Substitute {placeholder} values into a template
+func ExpandTemplate(template string, values map[string]string) string {
+	pairs := []string{}
+
+	for key, value := range values {
+		pairs = append(pairs, "{"+key+"}", value)
+	}
+
+	return strings.NewReplacer(pairs...).Replace(template)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		profile    string
+		wantHeader string
+		wantHelp   string
+		wantErr    bool
+	}{
+		{
+			"Reads top-level settings",
+			`{"header": "{lang}", "help": "{file}"}`,
+			"",
+			"{lang}",
+			"{file}",
+			false,
+		},
+		{
+			"Profiles override only the keys they set",
+			`{"header": "base", "help": "help", "profiles": {"demo": {"header": "demo"}}}`,
+			"demo",
+			"demo",
+			"help",
+			false,
+		},
+		{
+			"Unknown profiles are an error",
+			`{"header": "base"}`,
+			"missing",
+			"",
+			"",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "replit-config")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			fpath := filepath.Join(dir, LOCAL_CONFIG_NAME)
+			if err := ioutil.WriteFile(fpath, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			config, err := LoadConfig(fpath, tt.profile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if config.Header != tt.wantHeader || config.Help != tt.wantHelp {
+				t.Errorf("LoadConfig() = %v/%v, want %v/%v", config.Header, config.Help, tt.wantHeader, tt.wantHelp)
+			}
+		})
+	}
+}
+
+func TestExpandTemplate(t *testing.T) {
+	got := ExpandTemplate("run {file} with {lang} ({other})", map[string]string{"file": "a.py", "lang": "python3"})
+
+	if want := "run a.py with python3 ({other})"; got != want {
+		t.Errorf("ExpandTemplate() = %v, want %v", got, want)
+	}
+}
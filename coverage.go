@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// go test's "coverage: 81.2% of statements", per package
+var goCoveragePattern = regexp.MustCompile(`coverage: ([\d.]+)% of statements`)
+
+// pytest-cov and coverage.py's "TOTAL   120   10   92%" summary line
+var pythonCoveragePattern = regexp.MustCompile(`(?m)^TOTAL\s+.*?\s([\d.]+)%\s*$`)
+
+// jest and istanbul's "All files |   85.5 | ..." summary row; the first column is statements
+var istanbulCoveragePattern = regexp.MustCompile(`(?m)^All files\s*\|\s*([\d.]+)`)
+
+// Find the total coverage a test run reported. go test reports each package, so they are averaged
+func ParseCoverage(output []byte) (float64, bool) {
+	for _, pattern := range []*regexp.Regexp{pythonCoveragePattern, istanbulCoveragePattern} {
+		if match := pattern.FindSubmatch(output); match != nil {
+			if value, err := strconv.ParseFloat(string(match[1]), 64); err == nil {
+				return value, true
+			}
+		}
+	}
+
+	matches := goCoveragePattern.FindAllSubmatch(output, -1)
+	if len(matches) == 0 {
+		return 0, false
+	}
+
+	total := 0.0
+	for _, match := range matches {
+		value, err := strconv.ParseFloat(string(match[1]), 64)
+		if err != nil {
+			return 0, false
+		}
+
+		total += value
+	}
+
+	return total / float64(len(matches)), true
+}
+
+// Describe coverage and its change since the previous run; green if it rose, red if it fell
+func FormatCoverage(coverage float64, previous float64, hasPrevious bool) string {
+	text := fmt.Sprintf("coverage %.1f%%", coverage)
+
+	if !hasPrevious {
+		return text
+	}
+
+	switch delta := coverage - previous; {
+	case delta > 0.05:
+		return text + fmt.Sprintf(" [green](+%.1f)[reset]", delta)
+	case delta < -0.05:
+		return text + fmt.Sprintf(" [red](%.1f)[reset]", delta)
+	}
+
+	return text + " (±0)"
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseCoverage(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   float64
+		wantOk bool
+	}{
+		{"Averages go test packages", "ok  a  0.1s  coverage: 80.0% of statements\nok  b  0.1s  coverage: 60.0% of statements\n", 70, true},
+		{"Reads the pytest-cov total", "Name    Stmts   Miss  Cover\nTOTAL     120     10    92%\n", 92, true},
+		{"Reads the istanbul summary", "File      | % Stmts | % Branch\nAll files |   85.5 |    70\n", 85.5, true},
+		{"Finds nothing in other output", "hello world\n", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseCoverage([]byte(tt.output))
+
+			if ok != tt.wantOk || got != tt.want {
+				t.Errorf("ParseCoverage() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
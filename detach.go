@@ -0,0 +1,85 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Log files that a run writes its output to directly, rather than through pipes to replit, so
+// it can keep running once replit exits
+type DetachLogs struct {
+	Stdout *os.File
+	Stderr *os.File
+	tails  sync.WaitGroup
+	done   chan bool
+}
+
+// Create a directory only the user can read, for a session's detachable runs to write their output to
+func NewDetachDir() (string, error) {
+	return ioutil.TempDir("", "replit-detach-")
+}
+
+// Where detachable runs' output is written, in a session's directory
+func DetachLogPaths(dir string) (string, string) {
+	return filepath.Join(dir, "stdout.log"), filepath.Join(dir, "stderr.log")
+}
+
+// Replace the last run's log files with new ones, and copy their output to the writers as it's written
+func OpenDetachLogs(dir string, stdoutDst io.Writer, stderrDst io.Writer) (*DetachLogs, error) {
+	stdoutPath, stderrPath := DetachLogPaths(dir)
+	logs := &DetachLogs{done: make(chan bool)}
+
+	os.Remove(stdoutPath)
+	os.Remove(stderrPath)
+
+	var err error
+	if logs.Stdout, err = os.OpenFile(stdoutPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0600); err != nil {
+		return nil, err
+	}
+
+	if logs.Stderr, err = os.OpenFile(stderrPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0600); err != nil {
+		logs.Stdout.Close()
+		return nil, err
+	}
+
+	logs.tails.Add(2)
+	go logs.tail(stdoutPath, stdoutDst)
+	go logs.tail(stderrPath, stderrDst)
+
+	return logs, nil
+}
+
+// Copy a log file to a writer as it grows, until the logs are closed and the rest is read
+func (logs *DetachLogs) tail(fpath string, dst io.Writer) {
+	defer logs.tails.Done()
+
+	conn, err := os.Open(fpath)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	for {
+		select {
+		case <-logs.done:
+			io.Copy(dst, conn)
+			return
+		case <-time.After(TAIL_POLL_INTERVAL):
+		}
+
+		io.Copy(dst, conn)
+	}
+}
+
+// Stop tailing once the run has finished, copying any remaining output
+func (logs *DetachLogs) Close() {
+	logs.Stdout.Close()
+	logs.Stderr.Close()
+
+	close(logs.done)
+	logs.tails.Wait()
+}
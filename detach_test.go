@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+func TestDetachLogs(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	dir, err := NewDetachDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if info, err := os.Stat(dir); err != nil || info.Mode().Perm() != 0700 {
+		t.Fatalf("expected a private log directory, but got %v, %v", info.Mode(), err)
+	}
+
+	// a second run replaces the first's logs
+	for run := 0; run < 2; run++ {
+		stdout.Reset()
+		stderr.Reset()
+
+		logs, err := OpenDetachLogs(dir, &stdout, &stderr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cmd := exec.Command("sh", "-c", "echo out; echo err >&2")
+		cmd.Stdout = logs.Stdout
+		cmd.Stderr = logs.Stderr
+
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+
+		logs.Close()
+
+		if stdout.String() != "out\n" || stderr.String() != "err\n" {
+			t.Errorf("expected the logs to be copied to their writers, but got %q and %q", stdout.String(), stderr.String())
+		}
+	}
+}
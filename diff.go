@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// A line of a diff; '+' added, '-' removed, or ' ' unchanged
+type DiffLine struct {
+	Op   byte
+	Text string
+}
+
+// Diff two texts line by line, by their longest common subsequence
+func DiffLines(before string, after string) []DiffLine {
+	oldLines := strings.Split(strings.TrimSuffix(before, "\n"), "\n")
+	newLines := strings.Split(strings.TrimSuffix(after, "\n"), "\n")
+
+	if len(before) == 0 {
+		oldLines = []string{}
+	}
+	if len(after) == 0 {
+		newLines = []string{}
+	}
+
+	// common[i][j] is the length of the longest common subsequence of oldLines[i:] and newLines[j:]
+	common := make([][]int, len(oldLines)+1)
+	for idx := range common {
+		common[idx] = make([]int, len(newLines)+1)
+	}
+
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else if common[i+1][j] >= common[i][j+1] {
+				common[i][j] = common[i+1][j]
+			} else {
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	lines := []DiffLine{}
+	i, j := 0, 0
+
+	for i < len(oldLines) && j < len(newLines) {
+		switch {
+		case oldLines[i] == newLines[j]:
+			lines = append(lines, DiffLine{' ', oldLines[i]})
+			i, j = i+1, j+1
+		case common[i+1][j] >= common[i][j+1]:
+			lines = append(lines, DiffLine{'-', oldLines[i]})
+			i += 1
+		default:
+			lines = append(lines, DiffLine{'+', newLines[j]})
+			j += 1
+		}
+	}
+
+	for ; i < len(oldLines); i++ {
+		lines = append(lines, DiffLine{'-', oldLines[i]})
+	}
+
+	for ; j < len(newLines); j++ {
+		lines = append(lines, DiffLine{'+', newLines[j]})
+	}
+
+	return lines
+}
+
+// Render a diff's changes with a few lines of context, eliding the unchanged lines between them
+func FormatDiff(lines []DiffLine, context int) string {
+	var out strings.Builder
+
+	// keep lines within the context of any change
+	keep := make([]bool, len(lines))
+	for idx, line := range lines {
+		if line.Op == ' ' {
+			continue
+		}
+
+		for near := idx - context; near <= idx+context; near++ {
+			if near >= 0 && near < len(lines) {
+				keep[near] = true
+			}
+		}
+	}
+
+	elided := false
+	for idx, line := range lines {
+		if !keep[idx] {
+			elided = true
+			continue
+		}
+
+		if elided && out.Len() > 0 {
+			out.WriteString("[gray]···[reset]\n")
+		}
+		elided = false
+
+		text := tview.Escape(line.Text)
+
+		switch line.Op {
+		case '+':
+			out.WriteString("[green]+" + text + "[reset]\n")
+		case '-':
+			out.WriteString("[red]-" + text + "[reset]\n")
+		default:
+			out.WriteString(" " + text + "\n")
+		}
+	}
+
+	if out.Len() == 0 {
+		return "[gray]unchanged[reset]\n"
+	}
+
+	return out.String()
+}
+
+// Diff two texts for the diff pane, unless they're too long to diff quickly
+func DiffText(before string, after string) string {
+	if strings.Count(before, "\n") > DIFF_MAX_LINES || strings.Count(after, "\n") > DIFF_MAX_LINES {
+		return fmt.Sprintf("[gray]too long to diff; over %d lines[reset]\n", DIFF_MAX_LINES)
+	}
+
+	return FormatDiff(DiffLines(before, after), DIFF_CONTEXT_LINES)
+}
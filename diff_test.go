@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   []DiffLine
+	}{
+		{
+			"Marks an edited line as removed and added",
+			"a\nb\nc\n",
+			"a\nx\nc\n",
+			[]DiffLine{{' ', "a"}, {'-', "b"}, {'+', "x"}, {' ', "c"}},
+		},
+		{
+			"Adds every line of an empty file",
+			"",
+			"a\n",
+			[]DiffLine{{'+', "a"}},
+		},
+		{
+			"Keeps unchanged text unchanged",
+			"a\nb",
+			"a\nb\n",
+			[]DiffLine{{' ', "a"}, {' ', "b"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiffLines(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffLines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatDiff(t *testing.T) {
+	lines := DiffLines("1\n2\n3\n4\n5\n6\n7\n", "1\n2\n3\n4\n5\n6\nseven\n")
+
+	want := " 5\n 6\n[red]-7[reset]\n[green]+seven[reset]\n"
+	if got := FormatDiff(lines, 2); got != want {
+		t.Errorf("FormatDiff() = %q, want %q", got, want)
+	}
+
+	if got := FormatDiff(DiffLines("a\n", "a\n"), 2); got != "[gray]unchanged[reset]\n" {
+		t.Errorf("expected an unchanged diff, but got %q", got)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Parse KEY=VALUE pairs
+func ParseEnvPairs(pairs []string) (map[string]string, error) {
+	env := map[string]string{}
+
+	for _, pair := range pairs {
+		idx := strings.Index(pair, "=")
+		if idx <= 0 {
+			return nil, fmt.Errorf("expected KEY=VALUE, but received '%s'", pair)
+		}
+
+		env[pair[:idx]] = pair[idx+1:]
+	}
+
+	return env, nil
+}
+
+// Merge overrides into a KEY=VALUE environment
+func MergeEnv(environ []string, overrides map[string]string) []string {
+	merged := []string{}
+
+	for _, pair := range environ {
+		key := strings.SplitN(pair, "=", 2)[0]
+
+		if _, ok := overrides[key]; !ok {
+			merged = append(merged, pair)
+		}
+	}
+
+	for key, value := range overrides {
+		merged = append(merged, key+"="+value)
+	}
+
+	sort.Strings(merged)
+	return merged
+}
+
+// The environment the child process receives; --env pairs take precedence over the .env file,
+// which takes precedence over the .envrc
+func ChildEnv(args *ReplitArgs) []string {
+	environ := ApplyEnvDiff(os.Environ(), args.Direnv)
+	environ = MergeEnv(environ, args.Dotenv)
+
+	if args.SQL != nil {
+		environ = MergeEnv(environ, args.SQL.Env)
+	}
+
+	return MergeEnv(environ, args.Env)
+}
+
+// Should a variable's value be hidden in the environment pane? Values from .env files are often secrets
+func IsMasked(args *ReplitArgs, key string) bool {
+	_, fromDotenv := args.Dotenv[key]
+	_, overridden := args.Env[key]
+
+	return fromDotenv && !overridden
+}
+
+// Evaluate a directory's .envrc with direnv, returning the variables it sets; unset variables are nil
+func DirenvDiff(dpath string) (map[string]*string, error) {
+	if _, err := os.Stat(filepath.Join(dpath, ".envrc")); err != nil {
+		return nil, fmt.Errorf("%s has no .envrc", dpath)
+	}
+
+	if !CommandExists("direnv") {
+		return nil, errors.New("direnv is not in PATH")
+	}
+
+	var stderr bytes.Buffer
+
+	cmd := exec.Command("direnv", "export", "json")
+	cmd.Dir = dpath
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("direnv failed: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	diff := map[string]*string{}
+
+	// direnv prints nothing when the environment is unchanged
+	if len(bytes.TrimSpace(out)) == 0 {
+		return diff, nil
+	}
+
+	if err := json.Unmarshal(out, &diff); err != nil {
+		return nil, fmt.Errorf("could not parse direnv output: %v", err)
+	}
+
+	return diff, nil
+}
+
+// Apply set and unset variables to a KEY=VALUE environment
+func ApplyEnvDiff(environ []string, diff map[string]*string) []string {
+	overrides := map[string]string{}
+	for key, value := range diff {
+		if value != nil {
+			overrides[key] = *value
+		}
+	}
+
+	applied := []string{}
+	for _, pair := range MergeEnv(environ, overrides) {
+		key := strings.SplitN(pair, "=", 2)[0]
+
+		if value, ok := diff[key]; !ok || value != nil {
+			applied = append(applied, pair)
+		}
+	}
+
+	return applied
+}
+
+// Parse a .env file's KEY=VALUE lines, ignoring blank lines and comments
+func ParseDotenv(content string) (map[string]string, error) {
+	env := map[string]string{}
+
+	for idx, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+
+		sep := strings.Index(line, "=")
+		if sep <= 0 {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", idx+1)
+		}
+
+		key := strings.TrimSpace(line[:sep])
+		value := strings.TrimSpace(line[sep+1:])
+
+		switch {
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			value = strings.NewReplacer(`\n`, "\n", `\"`, `"`, `\\`, `\`).Replace(value[1 : len(value)-1])
+		default:
+			// unquoted values may end with a comment
+			if comment := strings.Index(value, " #"); comment >= 0 {
+				value = strings.TrimSpace(value[:comment])
+			}
+		}
+
+		env[key] = value
+	}
+
+	return env, nil
+}
+
+// Read a .env file
+func LoadDotenv(fpath string) (map[string]string, error) {
+	content, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseDotenv(string(content))
+}
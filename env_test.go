@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyEnvDiff(t *testing.T) {
+	value := "2"
+
+	tests := []struct {
+		name    string
+		environ []string
+		diff    map[string]*string
+		want    []string
+	}{
+		{"Keeps the environment without a diff", []string{"A=1"}, nil, []string{"A=1"}},
+		{"Sets and overrides variables", []string{"A=1", "B=1"}, map[string]*string{"B": &value, "C": &value}, []string{"A=1", "B=2", "C=2"}},
+		{"Unsets variables", []string{"A=1", "B=1"}, map[string]*string{"A": nil}, []string{"B=1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ApplyEnvDiff(tt.environ, tt.diff); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ApplyEnvDiff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDotenv(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"Skips comments and blank lines", "# secrets\n\nA=1\n", map[string]string{"A": "1"}, false},
+		{"Strips export and trailing comments", "export A=1 # note\n", map[string]string{"A": "1"}, false},
+		{"Unquotes values", "A='x # y'\nB=\"line\\nbreak\"\n", map[string]string{"A": "x # y", "B": "line\nbreak"}, false},
+		{"Rejects lines without a key", "=1\n", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDotenv(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDotenv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDotenv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
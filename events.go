@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Where structured run events are sent; syslog, on platforms that have it
+type eventWriter interface {
+	Info(message string) error
+	Err(message string) error
+	Close() error
+}
+
+// Log session and run events as key=value pairs, so log tooling can find and filter them. Methods
+// of a nil logger do nothing
+type EventLogger struct {
+	writer eventWriter
+}
+
+// Format an event as "event=name key=value ..." with keys in order; values with spaces are quoted
+func FormatEvent(name string, fields map[string]interface{}) string {
+	keys := []string{}
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := []string{"event=" + name}
+	for _, key := range keys {
+		value := fmt.Sprint(fields[key])
+		if len(value) == 0 || strings.ContainsAny(value, " \t\n\"=") {
+			value = strconv.Quote(value)
+		}
+
+		pairs = append(pairs, key+"="+value)
+	}
+
+	return strings.Join(pairs, " ")
+}
+
+// Log an event; failures are logged at error priority
+func (logger *EventLogger) Event(name string, failed bool, fields map[string]interface{}) {
+	if logger == nil {
+		return
+	}
+
+	message := FormatEvent(name, fields)
+	if failed {
+		logger.writer.Err(message)
+	} else {
+		logger.writer.Info(message)
+	}
+}
+
+func (logger *EventLogger) Close() error {
+	if logger == nil {
+		return nil
+	}
+
+	return logger.writer.Close()
+}
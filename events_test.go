@@ -0,0 +1,60 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+type fakeEventWriter struct {
+	lines []string
+}
+
+func (writer *fakeEventWriter) Info(message string) error {
+	writer.lines = append(writer.lines, "info "+message)
+	return nil
+}
+
+func (writer *fakeEventWriter) Err(message string) error {
+	writer.lines = append(writer.lines, "err "+message)
+	return nil
+}
+
+func (writer *fakeEventWriter) Close() error {
+	return nil
+}
+
+func TestFormatEvent(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]interface{}
+		want   string
+	}{
+		{"Orders keys", map[string]interface{}{"run": 2, "exit_code": 0}, "event=run exit_code=0 run=2"},
+		{"Quotes values with spaces", map[string]interface{}{"lang": "node --inspect"}, `event=run lang="node --inspect"`},
+		{"Quotes empty values", map[string]interface{}{"file": ""}, `event=run file=""`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatEvent("run", tt.fields); got != tt.want {
+				t.Errorf("FormatEvent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventLogger(t *testing.T) {
+	writer := &fakeEventWriter{}
+	logger := &EventLogger{writer}
+
+	logger.Event("run", false, map[string]interface{}{"run": 1})
+	logger.Event("run", true, map[string]interface{}{"run": 2})
+
+	want := []string{"info event=run run=1", "err event=run run=2"}
+	if !reflect.DeepEqual(writer.lines, want) {
+		t.Errorf("logged %v, want %v", writer.lines, want)
+	}
+
+	// a nil logger ignores events
+	var disabled *EventLogger
+	disabled.Event("run", false, nil)
+}
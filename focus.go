@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+)
+
+// A pattern selecting which tests run, which can be widened to the full suite and narrowed again
+type TestFocus struct {
+	lock    sync.Mutex
+	Pattern string
+	flag    string
+	widened bool
+}
+
+// The flag a test runner filters tests by, or "" if the language isn't a known test runner
+func FocusFlag(lang string) string {
+	program, flags := SplitLanguage(lang)
+	program = filepath.Base(program)
+
+	// runners invoked through another program, such as 'go test' or 'npx jest'
+	subcommand := ""
+	if len(flags) > 0 {
+		subcommand = flags[0]
+	}
+
+	switch {
+	case program == "go" && subcommand == "test":
+		return "-run"
+	case program == "pytest" || program == "py.test":
+		return "-k"
+	case program == "jest" || program == "vitest":
+		return "-t"
+	case program == "npx" && (subcommand == "jest" || subcommand == "vitest"):
+		return "-t"
+	case program == "bun" && subcommand == "test":
+		return "-t"
+	case program == "deno" && subcommand == "test":
+		return "--filter"
+	case program == "mocha":
+		return "--grep"
+	}
+
+	for idx, flag := range flags {
+		if flag == "-m" && idx+1 < len(flags) && flags[idx+1] == "pytest" {
+			return "-k"
+		}
+	}
+
+	return ""
+}
+
+// Focus a test runner on tests matching a pattern
+func NewTestFocus(lang string, pattern string) (*TestFocus, error) {
+	flag := FocusFlag(lang)
+	if len(flag) == 0 {
+		return nil, fmt.Errorf("%s is not a test runner replit can filter", lang)
+	}
+
+	return &TestFocus{Pattern: pattern, flag: flag}, nil
+}
+
+// The flags that filter the runner, or none when widened to the full suite
+func (focus *TestFocus) Flags() []string {
+	focus.lock.Lock()
+	defer focus.lock.Unlock()
+
+	if focus.widened {
+		return []string{}
+	}
+
+	return []string{focus.flag, focus.Pattern}
+}
+
+// Switch between the focused tests and the full suite, reporting whether the tests are now focused
+func (focus *TestFocus) Toggle() bool {
+	focus.lock.Lock()
+	defer focus.lock.Unlock()
+
+	focus.widened = !focus.widened
+	return !focus.widened
+}
+
+// Describe the focus for the header
+func (focus *TestFocus) String() string {
+	focus.lock.Lock()
+	defer focus.lock.Unlock()
+
+	if focus.widened {
+		return "[gray]full suite[reset]"
+	}
+
+	return "[yellow]focus " + focus.Pattern + "[reset]"
+}
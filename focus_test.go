@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFocusFlag(t *testing.T) {
+	tests := []struct {
+		lang string
+		want string
+	}{
+		{"go test", "-run"},
+		{"go run", ""},
+		{"pytest -x", "-k"},
+		{"python3 -m pytest", "-k"},
+		{"python3", ""},
+		{"npx jest", "-t"},
+		{"deno test", "--filter"},
+		{"mocha", "--grep"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.lang, func(t *testing.T) {
+			if got := FocusFlag(tt.lang); got != tt.want {
+				t.Errorf("FocusFlag() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTestFocusToggle(t *testing.T) {
+	focus, err := NewTestFocus("go test", "TestParse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args := &ReplitArgs{Lang: "go test -v", Focus: focus}
+
+	if got := LanguageArgv(args, "x_test.go"); !reflect.DeepEqual(got, []string{"go", "test", "-v", "-run", "TestParse", "x_test.go"}) {
+		t.Errorf("expected the focused tests' filter, but got %v", got)
+	}
+
+	if focus.Toggle() {
+		t.Error("expected the first toggle to widen to the full suite")
+	}
+
+	if got := LanguageArgv(args, "x_test.go"); !reflect.DeepEqual(got, []string{"go", "test", "-v", "x_test.go"}) {
+		t.Errorf("expected no filter for the full suite, but got %v", got)
+	}
+
+	if _, err := NewTestFocus("python3", "x"); err == nil {
+		t.Error("expected an error for a language that isn't a test runner")
+	}
+}
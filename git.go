@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Run git in a directory, reporting its stderr on failure. Commits use a fixed identity, so they
+// work without any git config
+func runGit(dir string, args ...string) error {
+	var stderr bytes.Buffer
+
+	cmd := exec.Command("git", append([]string{"-c", "user.name=replit", "-c", "user.email=replit@localhost"}, args...)...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git %s failed: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+// Create a git repository to commit each run's code and output to
+func InitGitHistory(dir string) error {
+	if !CommandExists("git") {
+		return errors.New("git is not in PATH")
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	return runGit(dir, "init", "--quiet")
+}
+
+// Commit a finished run's code and output, with its run number and exit code in the message. The
+// files keep the same names across commits, so git can diff and bisect them
+func CommitRun(dir string, entry HistoryEntry, name string) error {
+	files := map[string][]byte{name: entry.Source, "stdout": entry.Stdout, "stderr": entry.Stderr}
+
+	for fname, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, fname), content, 0644); err != nil {
+			return err
+		}
+	}
+
+	if err := runGit(dir, "add", "--all"); err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("run %d: exit %d", entry.Run, entry.Result.ExitCode)
+	if footer := entry.Result.Footer(); len(footer) > 0 {
+		message += "\n\n" + footer
+	}
+
+	return runGit(dir, "commit", "--quiet", "--allow-empty", "--message", message)
+}
+
+// The files git rewrites on commits, checkouts, and rebases; HEAD, and the index if there is one
+func GitFiles(dpath string) ([]string, error) {
+	var stderr bytes.Buffer
+
+	cmd := exec.Command("git", "rev-parse", "--absolute-git-dir")
+	cmd.Dir = dpath
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not find a git repository for %s: %s", dpath, strings.TrimSpace(stderr.String()))
+	}
+
+	gitDir := strings.TrimSpace(string(out))
+	files := []string{}
+
+	for _, name := range []string{"HEAD", "index"} {
+		fpath := filepath.Join(gitDir, name)
+
+		if _, err := os.Stat(fpath); err == nil {
+			files = append(files, fpath)
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGitFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is unavailable")
+	}
+
+	dir, err := ioutil.TempDir("", "replit-git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// resolve symlinks such as macOS's /tmp, as git does
+	dir, _ = filepath.EvalSymlinks(dir)
+	gitDir := filepath.Join(dir, ".git")
+
+	// don't find a repository the temporary directory is itself inside
+	defer os.Setenv("GIT_CEILING_DIRECTORIES", os.Getenv("GIT_CEILING_DIRECTORIES"))
+	os.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(dir))
+
+	if _, err := GitFiles(dir); err == nil {
+		t.Fatal("expected an error outside a git repository")
+	}
+
+	if err := runGit(dir, "init", "--quiet"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		setup func()
+		want  []string
+	}{
+		{
+			"Watches only HEAD before anything is staged, as there's no index yet",
+			func() {},
+			[]string{filepath.Join(gitDir, "HEAD")},
+		},
+		{
+			"Watches HEAD and the index once a file is staged",
+			func() {
+				ioutil.WriteFile(filepath.Join(dir, "main.py"), []byte("print(1)\n"), 0644)
+				runGit(dir, "add", "main.py")
+			},
+			[]string{filepath.Join(gitDir, "HEAD"), filepath.Join(gitDir, "index")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+
+			got, err := GitFiles(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GitFiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
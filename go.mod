@@ -6,5 +6,8 @@ require (
 	github.com/docopt/docopt-go v0.0.0-20180111231733-ee0de3bc6815
 	github.com/gdamore/tcell v1.4.0
 	github.com/gdamore/tcell/v2 v2.4.1-0.20210905002822-f057f0a857a1
+	github.com/mattn/go-runewidth v0.0.13
 	github.com/rivo/tview v0.0.0-20210923051754-2cb20002bc4c
+	github.com/rivo/uniseg v0.2.0
+	golang.org/x/text v0.3.6
 )
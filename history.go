@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// A run's output and the file content that produced it, kept for review once later runs replace it.
+// Older runs' output is paged out to a directory, and read back by History.Load
+type HistoryEntry struct {
+	Run     int64
+	Result  RunResult
+	Stdout  []byte
+	Stderr  []byte
+	Source  []byte
+	Partial bool
+	SavedAt time.Time
+	Paged   string
+}
+
+// Every run, oldest first. Only the latest Keep runs' output is held in memory; older runs are read
+// from the session directory if they were persisted there, or are written to a temporary directory
+type History struct {
+	lock       sync.Mutex
+	Entries    []HistoryEntry
+	Keep       int
+	SessionDir string
+	spillDir   string
+}
+
+// Keep the output of the latest runs in memory, paging older runs from a session directory if there is one
+func NewHistory(keep int, session *Session) *History {
+	history := &History{Keep: keep}
+	if session != nil {
+		history.SessionDir = session.Dir
+	}
+
+	return history
+}
+
+// Add a run to the history. A snapshot of a run in progress is replaced by later snapshots, and
+// by the finished run
+func (history *History) Record(entry HistoryEntry) {
+	history.lock.Lock()
+	defer history.lock.Unlock()
+
+	last := len(history.Entries) - 1
+	if last >= 0 && history.Entries[last].Partial && history.Entries[last].Run == entry.Run {
+		history.Entries[last] = entry
+		return
+	}
+
+	history.Entries = append(history.Entries, entry)
+
+	if history.Keep > 0 && len(history.Entries) > history.Keep {
+		history.pageOut(len(history.Entries) - history.Keep - 1)
+	}
+}
+
+// Drop a run's output from memory, once it's on disk. Output stays in memory if it can't be written
+func (history *History) pageOut(idx int) {
+	entry := &history.Entries[idx]
+	if len(entry.Paged) > 0 {
+		return
+	}
+
+	// finished runs of a persisted session were already streamed to its directory
+	dir := history.SessionDir
+	persisted := len(dir) > 0 && !entry.Partial
+	if persisted {
+		_, err := os.Stat(filepath.Join(dir, fmt.Sprintf("run-%d.stdout", entry.Run)))
+		persisted = err == nil
+	}
+
+	if !persisted {
+		if err := history.spill(*entry); err != nil {
+			return
+		}
+
+		dir = history.spillDir
+	}
+
+	entry.Stdout, entry.Stderr, entry.Source = nil, nil, nil
+	entry.Paged = dir
+}
+
+// Write a run's output to the temporary directory, creating it with the first run written
+func (history *History) spill(entry HistoryEntry) error {
+	if len(history.spillDir) == 0 {
+		dir, err := ioutil.TempDir("", "replit-history-")
+		if err != nil {
+			return err
+		}
+
+		history.spillDir = dir
+	}
+
+	files := map[string][]byte{"stdout": entry.Stdout, "stderr": entry.Stderr, "source": entry.Source}
+	for suffix, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(history.spillDir, fmt.Sprintf("run-%d.%s", entry.Run, suffix)), content, 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// A run with its output, read back from disk if it was paged out
+func (history *History) Load(entry HistoryEntry) HistoryEntry {
+	if len(entry.Paged) == 0 {
+		return entry
+	}
+
+	prefix := filepath.Join(entry.Paged, fmt.Sprintf("run-%d", entry.Run))
+	entry.Stdout, _ = ioutil.ReadFile(prefix + ".stdout")
+	entry.Stderr, _ = ioutil.ReadFile(prefix + ".stderr")
+
+	// sessions name the source after the watched file's extension
+	if sources, _ := filepath.Glob(prefix + ".source*"); len(sources) > 0 {
+		entry.Source, _ = ioutil.ReadFile(sources[0])
+	}
+
+	return entry
+}
+
+// Remove output written to the temporary directory
+func (history *History) Close() {
+	history.lock.Lock()
+	defer history.lock.Unlock()
+
+	if len(history.spillDir) > 0 {
+		os.RemoveAll(history.spillDir)
+	}
+}
+
+// Copy the history, so it can be rendered outside the lock
+func (history *History) List() []HistoryEntry {
+	history.lock.Lock()
+	defer history.lock.Unlock()
+
+	return append([]HistoryEntry{}, history.Entries...)
+}
+
+// Describe a history entry in one line of the history pane
+func HistoryLabel(entry HistoryEntry) string {
+	if entry.Partial {
+		return fmt.Sprintf("[yellow]…[reset] run %d · snapshot at %s", entry.Run, entry.SavedAt.Format(LAST_RUN_FORMAT))
+	}
+
+	mark := "[green]✔[reset]"
+	if !entry.Result.Succeeded() {
+		mark = "[red]✘[reset]"
+	}
+
+	return fmt.Sprintf("%s run %d · %s · exit %d · %s", mark, entry.Run, entry.Result.StartedAt.Format(LAST_RUN_FORMAT),
+		entry.Result.ExitCode, FormatDuration(entry.Result.Duration))
+}
+
+// Write a snapshot of a run's stdout to disk, replacing the previous snapshot of that run
+func SaveSnapshot(dir string, entry HistoryEntry) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	fpath := filepath.Join(dir, fmt.Sprintf("run-%d.stdout", entry.Run))
+	return ioutil.WriteFile(fpath, entry.Stdout, 0644)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestHistoryRecord(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []HistoryEntry
+		want    []bool
+	}{
+		{
+			"Keeps finished runs",
+			[]HistoryEntry{{Run: 1}, {Run: 2}},
+			[]bool{false, false},
+		},
+		{
+			"Replaces snapshots with later snapshots and the finished run",
+			[]HistoryEntry{{Run: 1}, {Run: 2, Partial: true}, {Run: 2, Partial: true}, {Run: 2}},
+			[]bool{false, false},
+		},
+		{
+			"Keeps a snapshot of a run that never finished",
+			[]HistoryEntry{{Run: 1, Partial: true}, {Run: 2}},
+			[]bool{true, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := History{}
+
+			for _, entry := range tt.entries {
+				history.Record(entry)
+			}
+
+			got := history.List()
+			if len(got) != len(tt.want) {
+				t.Fatalf("History has %v entries, want %v", len(got), len(tt.want))
+			}
+
+			for idx, partial := range tt.want {
+				if got[idx].Partial != partial {
+					t.Errorf("History.Entries[%v].Partial = %v, want %v", idx, got[idx].Partial, partial)
+				}
+			}
+		})
+	}
+}
+
+func TestHistoryPaging(t *testing.T) {
+	history := NewHistory(2, nil)
+	defer history.Close()
+
+	for run := int64(1); run <= 4; run++ {
+		history.Record(HistoryEntry{Run: run, Stdout: []byte(fmt.Sprintf("out %d", run)), Source: []byte("src")})
+	}
+
+	entries := history.List()
+	for idx, paged := range []bool{true, true, false, false} {
+		if got := entries[idx].Stdout == nil; got != paged {
+			t.Errorf("run %d paged out = %v, want %v", entries[idx].Run, got, paged)
+		}
+	}
+
+	loaded := history.Load(entries[0])
+	if string(loaded.Stdout) != "out 1" || string(loaded.Source) != "src" {
+		t.Errorf("Load() = %q and %q, want run 1's output and source", loaded.Stdout, loaded.Source)
+	}
+}
+
+func TestHistoryPagingSession(t *testing.T) {
+	session := &Session{Dir: t.TempDir()}
+	history := NewHistory(1, session)
+	defer history.Close()
+
+	// the session streamed run 1's output, so it's read from there
+	ioutil.WriteFile(filepath.Join(session.Dir, "run-1.stdout"), []byte("streamed"), 0644)
+	ioutil.WriteFile(filepath.Join(session.Dir, "run-1.source.py"), []byte("src"), 0644)
+
+	history.Record(HistoryEntry{Run: 1, Stdout: []byte("streamed")})
+	history.Record(HistoryEntry{Run: 2, Stdout: []byte("latest")})
+
+	entries := history.List()
+	if entries[0].Paged != session.Dir {
+		t.Fatalf("run 1 was paged to %q, want the session directory", entries[0].Paged)
+	}
+
+	loaded := history.Load(entries[0])
+	if string(loaded.Stdout) != "streamed" || string(loaded.Source) != "src" {
+		t.Errorf("Load() = %q and %q, want the session's output and source", loaded.Stdout, loaded.Source)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// A line of a gitignore-syntax file
+type IgnoreRule struct {
+	pattern *regexp.Regexp
+	negate  bool
+	dirOnly bool
+	base    string
+}
+
+// Rules from ignore files; later rules take precedence, as git's do
+type IgnoreRules []IgnoreRule
+
+// Parse gitignore syntax. Patterns apply to paths beneath base, a slash-separated path
+// relative to the watched directory; empty for its top level
+func ParseIgnoreRules(content string, base string) IgnoreRules {
+	rules := IgnoreRules{}
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+
+		// trailing spaces are dropped, unless escaped
+		for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+			line = strings.TrimSuffix(line, " ")
+		}
+
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := IgnoreRule{base: base}
+
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+
+		pattern, err := regexp.Compile(IgnorePatternRegexp(line))
+		if len(line) == 0 || err != nil {
+			continue
+		}
+
+		rule.pattern = pattern
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
+
+// Translate a gitignore glob into a regexp over slash-separated paths. Globs containing a slash
+// are relative to their ignore file's directory; others match a name at any depth
+func IgnorePatternRegexp(glob string) string {
+	var out strings.Builder
+
+	if strings.Contains(glob, "/") {
+		out.WriteString("^")
+	} else {
+		out.WriteString("^(?:.*/)?")
+	}
+
+	glob = strings.TrimPrefix(glob, "/")
+
+	for idx := 0; idx < len(glob); idx++ {
+		rest := glob[idx:]
+
+		switch {
+		case strings.HasPrefix(rest, "**/"):
+			out.WriteString("(?:.*/)?")
+			idx += 2
+		case rest == "**":
+			out.WriteString(".*")
+			idx += 1
+		case rest[0] == '*':
+			out.WriteString("[^/]*")
+		case rest[0] == '?':
+			out.WriteString("[^/]")
+		case rest[0] == '\\' && len(rest) > 1:
+			out.WriteString(regexp.QuoteMeta(rest[1:2]))
+			idx += 1
+		case rest[0] == '[' && strings.Contains(rest[1:], "]"):
+			end := strings.Index(rest[1:], "]") + 1
+			class := rest[1:end]
+
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+
+			out.WriteString("[" + strings.Replace(class, "\\", "\\\\", -1) + "]")
+			idx += end
+		default:
+			out.WriteString(regexp.QuoteMeta(rest[:1]))
+		}
+	}
+
+	out.WriteString("$")
+
+	return out.String()
+}
+
+// Read the ignore files in a directory, .gitignore and then .replitignore, so the latter can
+// override the former. Missing files have no rules
+func LoadIgnoreRules(dpath string, base string) IgnoreRules {
+	rules := IgnoreRules{}
+
+	for _, name := range IGNORE_FILES {
+		content, err := ioutil.ReadFile(filepath.Join(dpath, name))
+		if err != nil {
+			continue
+		}
+
+		rules = append(rules, ParseIgnoreRules(string(content), base)...)
+	}
+
+	return rules
+}
+
+// Is a slash-separated path, relative to the watched directory, ignored? The last matching rule decides
+func (rules IgnoreRules) Ignored(rel string, isDir bool) bool {
+	ignored := false
+
+	for _, rule := range rules {
+		target := rel
+
+		if len(rule.base) > 0 {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+
+			target = strings.TrimPrefix(rel, rule.base+"/")
+		}
+
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		if rule.pattern.MatchString(target) {
+			ignored = !rule.negate
+		}
+	}
+
+	return ignored
+}
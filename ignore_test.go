@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestIgnoreRules(t *testing.T) {
+	rules := ParseIgnoreRules(`# build output
+*.log
+/dist
+build/
+docs/**/*.md
+!keep.log
+\#notes
+`, "")
+	nested := append(rules, ParseIgnoreRules("*.tmp\n", "src")...)
+
+	tests := []struct {
+		name  string
+		rules IgnoreRules
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{"Matches names at any depth", rules, "a/b/debug.log", false, true},
+		{"Negates earlier rules", rules, "keep.log", false, false},
+		{"Anchors patterns with a leading slash", rules, "dist", true, true},
+		{"Leaves anchored names in subdirectories", rules, "src/dist", true, false},
+		{"Only matches directories with a trailing slash", rules, "build", false, false},
+		{"Matches directories with a trailing slash", rules, "src/build", true, true},
+		{"Matches any depth with **", rules, "docs/a/b/intro.md", false, true},
+		{"Matches no depth with **", rules, "docs/intro.md", false, true},
+		{"Reads escaped comments as patterns", rules, "#notes", false, true},
+		{"Leaves other files", rules, "main.py", false, false},
+		{"Applies nested rules beneath their directory", nested, "src/x.tmp", false, true},
+		{"Leaves files outside nested rules' directory", nested, "x.tmp", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rules.Ignored(tt.rel, tt.isDir); got != tt.want {
+				t.Errorf("Ignored(%q) = %v, want %v", tt.rel, got, tt.want)
+			}
+		})
+	}
+}
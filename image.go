@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	_ "image/gif"
+	_ "image/jpeg"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Preview an image inside the TUI, with the terminal's image protocol: sixel, kitty, or iTerm's.
+// tview owns every terminal cell, so the pane is left blank and the image is written over it once
+// the frame is shown. Terminals without a protocol draw two pixels to a cell, as half-blocks
+type ImageView struct {
+	*tview.Box
+	lock       sync.Mutex
+	image      image.Image
+	generation int
+	protocol   string
+	out        io.Writer
+
+	// where the image was last laid out, whether it was this frame, and what was last written
+	rect    [4]int
+	drawn   bool
+	written string
+}
+
+func NewImageView(protocol string) *ImageView {
+	return &ImageView{Box: tview.NewBox(), protocol: protocol, out: os.Stdout}
+}
+
+// The image protocol the terminal supports, from $REPLIT_IMAGE_PROTOCOL or detected from the
+// terminal's variables. Sixel support can't be detected from them reliably, so only terminals known
+// to have it use it. Inside tmux and screen, images are drawn as half-blocks
+func ImageProtocol() string {
+	switch protocol := os.Getenv("REPLIT_IMAGE_PROTOCOL"); protocol {
+	case IMAGE_SIXEL, IMAGE_KITTY, IMAGE_ITERM, IMAGE_BLOCKS:
+		return protocol
+	}
+
+	term := os.Getenv("TERM")
+
+	switch {
+	case len(os.Getenv("TMUX")) > 0 || strings.HasPrefix(term, "screen") || strings.HasPrefix(term, "tmux"):
+		return IMAGE_BLOCKS
+	case len(os.Getenv("KITTY_WINDOW_ID")) > 0 || term == "xterm-kitty" || term == "xterm-ghostty":
+		return IMAGE_KITTY
+	case os.Getenv("TERM_PROGRAM") == "iTerm.app" || os.Getenv("TERM_PROGRAM") == "WezTerm" || os.Getenv("LC_TERMINAL") == "iTerm2":
+		return IMAGE_ITERM
+	case strings.HasPrefix(term, "foot") || strings.HasPrefix(term, "mlterm") || strings.HasPrefix(term, "yaft") || strings.HasPrefix(term, "contour"):
+		return IMAGE_SIXEL
+	}
+
+	return IMAGE_BLOCKS
+}
+
+// Is the file an image that can be previewed?
+func IsImage(fpath string) bool {
+	switch strings.ToLower(filepath.Ext(fpath)) {
+	case ".png", ".jpg", ".jpeg", ".gif":
+		return true
+	}
+
+	return false
+}
+
+// The image a run's output last named to preview, with a line such as 'replit:image plot.png'.
+// Relative paths are relative to the watched directory
+func ImageSentinel(output string, dpath string) string {
+	fpath := ""
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); strings.HasPrefix(line, IMAGE_SENTINEL) {
+			fpath = strings.TrimSpace(strings.TrimPrefix(line, IMAGE_SENTINEL))
+		}
+	}
+
+	if len(fpath) > 0 && !filepath.IsAbs(fpath) {
+		fpath = filepath.Join(dpath, fpath)
+	}
+
+	return fpath
+}
+
+// Decode an image file
+func LoadImage(fpath string) (image.Image, error) {
+	conn, err := os.Open(fpath)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	img, _, err := image.Decode(conn)
+	return img, err
+}
+
+// The largest size an image fits in, preserving its aspect ratio
+func FitImage(width int, height int, imageWidth int, imageHeight int) (int, int) {
+	if imageWidth <= 0 || imageHeight <= 0 {
+		return 0, 0
+	}
+
+	if width*imageHeight <= height*imageWidth {
+		return width, imageHeight * width / imageWidth
+	}
+
+	return imageWidth * height / imageHeight, height
+}
+
+// Resize an image, sampling its nearest pixels
+func ScaleImage(img image.Image, width int, height int) *image.RGBA {
+	bounds := img.Bounds()
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			scaled.Set(x, y, img.At(bounds.Min.X+x*bounds.Dx()/width, bounds.Min.Y+y*bounds.Dy()/height))
+		}
+	}
+
+	return scaled
+}
+
+// Decode and display an image file
+func (view *ImageView) Load(fpath string) error {
+	img, err := LoadImage(fpath)
+	if err != nil {
+		return err
+	}
+
+	view.lock.Lock()
+	view.image = img
+	view.generation++
+	view.lock.Unlock()
+
+	return nil
+}
+
+func (view *ImageView) Draw(screen tcell.Screen) {
+	view.Box.DrawForSubclass(screen, view)
+
+	view.lock.Lock()
+	defer view.lock.Unlock()
+
+	if view.image == nil {
+		return
+	}
+
+	x, y, width, height := view.GetInnerRect()
+	if width <= 0 || height <= 0 {
+		return
+	}
+
+	if view.protocol != IMAGE_BLOCKS {
+		view.rect = [4]int{x, y, width, height}
+		view.drawn = true
+
+		for row := 0; row < height; row++ {
+			for col := 0; col < width; col++ {
+				screen.SetContent(x+col, y+row, ' ', nil, tcell.StyleDefault)
+			}
+		}
+
+		return
+	}
+
+	// fit the image into the pane, two pixels to a cell
+	bounds := view.image.Bounds()
+	cols, pixelRows := FitImage(width, height*2, bounds.Dx(), bounds.Dy())
+	if cols == 0 || pixelRows == 0 {
+		return
+	}
+
+	scaled := ScaleImage(view.image, cols, pixelRows)
+
+	sample := func(col int, pixelRow int) tcell.Color {
+		if pixelRow >= pixelRows {
+			return tcell.ColorDefault
+		}
+
+		red, green, blue, _ := scaled.At(col, pixelRow).RGBA()
+		return tcell.NewRGBColor(int32(red>>8), int32(green>>8), int32(blue>>8))
+	}
+
+	for row := 0; row*2 < pixelRows; row++ {
+		for col := 0; col < cols; col++ {
+			style := tcell.StyleDefault.
+				Foreground(sample(col, row*2)).
+				Background(sample(col, row*2+1))
+
+			screen.SetContent(x+col, y+row, '▀', nil, style)
+		}
+	}
+}
+
+// Write the image over its pane with the terminal's protocol, once tview has drawn the frame. The
+// cells beneath are blank and unchanged between frames, so images are written again only when they
+// or the pane change; the last is cleared first, since tcell doesn't know it's there
+func (view *ImageView) AfterDraw(screen tcell.Screen) {
+	view.lock.Lock()
+	defer view.lock.Unlock()
+
+	if view.protocol == IMAGE_BLOCKS {
+		return
+	}
+
+	key := ""
+	if view.drawn {
+		key = fmt.Sprintf("%d %v", view.generation, view.rect)
+	}
+	view.drawn = false
+
+	if key == view.written {
+		return
+	}
+
+	if len(view.written) > 0 {
+		if view.protocol == IMAGE_KITTY {
+			io.WriteString(view.out, KITTY_DELETE)
+		}
+		screen.Sync()
+	} else {
+		screen.Show()
+	}
+
+	view.written = key
+	if len(key) == 0 {
+		return
+	}
+
+	cellWidth, cellHeight := CellSize()
+	x, y, width, height := view.rect[0], view.rect[1], view.rect[2], view.rect[3]
+
+	// the cursor's saved and restored around the image, so tcell's idea of where it is stays right
+	fmt.Fprintf(view.out, "\x1b7\x1b[%d;%dH%s\x1b8", y+1, x+1, EncodeImage(view.image, view.protocol, width, height, cellWidth, cellHeight))
+}
+
+// Encode an image with a protocol, fitted into a number of cells of a size in pixels
+func EncodeImage(img image.Image, protocol string, cols int, rows int, cellWidth int, cellHeight int) string {
+	bounds := img.Bounds()
+
+	width, height := FitImage(cols*cellWidth, rows*cellHeight, bounds.Dx(), bounds.Dy())
+	if width == 0 || height == 0 {
+		return ""
+	}
+
+	scaled := ScaleImage(img, width, height)
+	if protocol == IMAGE_SIXEL {
+		return EncodeSixel(scaled)
+	}
+
+	// kitty and iTerm are sent PNGs, and told how many cells they cover
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, scaled); err != nil {
+		return ""
+	}
+
+	cols = (width + cellWidth - 1) / cellWidth
+	rows = (height + cellHeight - 1) / cellHeight
+
+	if protocol == IMAGE_KITTY {
+		return EncodeKitty(encoded.Bytes(), cols, rows)
+	}
+
+	return fmt.Sprintf("\x1b]1337;File=inline=1;size=%d;width=%d;height=%d;preserveAspectRatio=1:%s\a",
+		encoded.Len(), cols, rows, base64.StdEncoding.EncodeToString(encoded.Bytes()))
+}
+
+// Encode a PNG with kitty's graphics protocol, in chunks, without moving the cursor
+func EncodeKitty(data []byte, cols int, rows int) string {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var out strings.Builder
+
+	for start := 0; start < len(encoded); start += KITTY_CHUNK_SIZE {
+		end := start + KITTY_CHUNK_SIZE
+		more := 1
+		if end >= len(encoded) {
+			end, more = len(encoded), 0
+		}
+
+		if start == 0 {
+			fmt.Fprintf(&out, "\x1b_Ga=T,f=100,q=2,C=1,c=%d,r=%d,m=%d;%s\x1b\\", cols, rows, more, encoded[start:end])
+		} else {
+			fmt.Fprintf(&out, "\x1b_Gm=%d;%s\x1b\\", more, encoded[start:end])
+		}
+	}
+
+	return out.String()
+}
+
+// Encode an image as sixels, dithered to the web-safe palette. Each band of six rows is drawn once per
+// colour it uses, with runs of the same sixel compressed
+func EncodeSixel(img image.Image) string {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	paletted := image.NewPaletted(image.Rect(0, 0, width, height), palette.WebSafe)
+	draw.FloydSteinberg.Draw(paletted, paletted.Bounds(), img, bounds.Min)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "\x1bPq\"1;1;%d;%d", width, height)
+
+	for idx, color := range paletted.Palette {
+		red, green, blue, _ := color.RGBA()
+		fmt.Fprintf(&out, "#%d;2;%d;%d;%d", idx, red*100/0xffff, green*100/0xffff, blue*100/0xffff)
+	}
+
+	for top := 0; top < height; top += 6 {
+		bands := map[uint8][]byte{}
+
+		for bit := 0; bit < 6 && top+bit < height; bit++ {
+			for x := 0; x < width; x++ {
+				idx := paletted.ColorIndexAt(x, top+bit)
+				if _, ok := bands[idx]; !ok {
+					bands[idx] = make([]byte, width)
+				}
+				bands[idx][x] |= 1 << bit
+			}
+		}
+
+		colors := []int{}
+		for idx := range bands {
+			colors = append(colors, int(idx))
+		}
+		sort.Ints(colors)
+
+		for nth, idx := range colors {
+			if nth > 0 {
+				out.WriteByte('$')
+			}
+			fmt.Fprintf(&out, "#%d", idx)
+
+			sixels := bands[uint8(idx)]
+			for x := 0; x < width; {
+				run := 1
+				for x+run < width && sixels[x+run] == sixels[x] {
+					run++
+				}
+
+				char := string(rune(63 + sixels[x]))
+				if run > 3 {
+					fmt.Fprintf(&out, "!%d%s", run, char)
+				} else {
+					out.WriteString(strings.Repeat(char, run))
+				}
+				x += run
+			}
+		}
+
+		out.WriteByte('-')
+	}
+
+	out.WriteString("\x1b\\")
+	return out.String()
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestIsImage(t *testing.T) {
+	tests := []struct {
+		fpath string
+		want  bool
+	}{
+		{"plot.png", true},
+		{"out/PHOTO.JPG", true},
+		{"photo.jpeg", true},
+		{"anim.gif", true},
+		{"plot.svg", false},
+		{"main.py", false},
+		{"png", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fpath, func(t *testing.T) {
+			if got := IsImage(tt.fpath); got != tt.want {
+				t.Errorf("IsImage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImageSentinel(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"Finds no sentinel", "plotting\ndone\n", ""},
+		{"Resolves relative paths", "replit:image plot.png\n", "/work/plot.png"},
+		{"Keeps absolute paths", "replit:image /tmp/plot.png\n", "/tmp/plot.png"},
+		{"Uses the last sentinel", "replit:image a.png\nreplit:image b.png\n", "/work/b.png"},
+		{"Ignores surrounding space", "  replit:image  plot.png  \n", "/work/plot.png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ImageSentinel(tt.output, "/work"); got != tt.want {
+				t.Errorf("ImageSentinel() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFitImage(t *testing.T) {
+	tests := []struct {
+		name                    string
+		width, height           int
+		imageWidth, imageHeight int
+		wantWidth, wantHeight   int
+	}{
+		{"Fits wide images to the width", 100, 100, 200, 50, 100, 25},
+		{"Fits tall images to the height", 100, 100, 50, 200, 25, 100},
+		{"Enlarges small images", 100, 50, 10, 10, 50, 50},
+		{"Fits empty images to nothing", 100, 100, 0, 10, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			width, height := FitImage(tt.width, tt.height, tt.imageWidth, tt.imageHeight)
+			if width != tt.wantWidth || height != tt.wantHeight {
+				t.Errorf("FitImage() = %d, %d, want %d, %d", width, height, tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}
+
+// Write a PNG, red on its left half and blue on its right
+func writeTestImage(t *testing.T, width int, height int) string {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x < width/2 {
+				img.Set(x, y, color.RGBA{255, 0, 0, 255})
+			} else {
+				img.Set(x, y, color.RGBA{0, 0, 255, 255})
+			}
+		}
+	}
+
+	fpath := filepath.Join(t.TempDir(), "plot.png")
+	conn, err := os.Create(fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err := png.Encode(conn, img); err != nil {
+		t.Fatal(err)
+	}
+
+	return fpath
+}
+
+func TestLoadAndScaleImage(t *testing.T) {
+	img, err := LoadImage(writeTestImage(t, 40, 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	width, height := FitImage(10, 10, img.Bounds().Dx(), img.Bounds().Dy())
+	scaled := ScaleImage(img, width, height)
+	if size := scaled.Bounds().Size(); size != image.Pt(10, 5) {
+		t.Fatalf("expected the image scaled to 10x5, but got %v", size)
+	}
+
+	if left, right := scaled.RGBAAt(0, 0), scaled.RGBAAt(9, 4); left.R != 255 || right.B != 255 {
+		t.Errorf("expected red on the left and blue on the right, but got %v and %v", left, right)
+	}
+
+	if _, err := LoadImage(filepath.Join(t.TempDir(), "missing.png")); err == nil {
+		t.Error("expected an error loading a missing image")
+	}
+}
+
+func TestEncodeSixel(t *testing.T) {
+	img, err := LoadImage(writeTestImage(t, 8, 7))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sixel := EncodeSixel(img)
+
+	if !strings.HasPrefix(sixel, "\x1bPq\"1;1;8;7") || !strings.HasSuffix(sixel, "\x1b\\") {
+		t.Fatalf("expected a sixel sequence with the image's size, but got %q", sixel)
+	}
+
+	// two bands of six rows; the first has every bit of each column set, in runs of four
+	if bands := strings.Count(sixel, "-"); bands != 2 {
+		t.Errorf("expected 2 bands, but got %d", bands)
+	}
+
+	if !strings.Contains(sixel, "!4~") {
+		t.Errorf("expected runs of full sixels to be compressed, but got %q", sixel)
+	}
+}
+
+func TestEncodeKitty(t *testing.T) {
+	data := bytes.Repeat([]byte{1}, KITTY_CHUNK_SIZE)
+	encoded := EncodeKitty(data, 4, 2)
+
+	chunks := strings.Count(encoded, "\x1b_G")
+	if chunks != 2 {
+		t.Fatalf("expected the image sent in 2 chunks, but got %d", chunks)
+	}
+
+	if !strings.HasPrefix(encoded, "\x1b_Ga=T,f=100,q=2,C=1,c=4,r=2,m=1;") || !strings.Contains(encoded, "\x1b_Gm=0;") {
+		t.Errorf("expected the first chunk to place the image and the last to end it, but got %q", encoded[:40])
+	}
+}
+
+func TestImageViewAfterDraw(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	screen.Init()
+	screen.SetSize(40, 20)
+
+	var out bytes.Buffer
+	view := NewImageView(IMAGE_ITERM)
+	view.out = &out
+	view.SetRect(0, 0, 40, 20)
+
+	if err := view.Load(writeTestImage(t, 40, 20)); err != nil {
+		t.Fatal(err)
+	}
+
+	view.Draw(screen)
+	view.AfterDraw(screen)
+
+	if !strings.Contains(out.String(), "\x1b]1337;File=inline=1;") {
+		t.Fatalf("expected the image written with iTerm's protocol, but got %q", out.String())
+	}
+
+	// unchanged frames don't write the image again
+	out.Reset()
+	view.Draw(screen)
+	view.AfterDraw(screen)
+
+	if out.Len() > 0 {
+		t.Errorf("expected nothing written for an unchanged frame, but got %q", out.String())
+	}
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// The size of the terminal's cells in pixels, or a common size if it doesn't report one
+func CellSize() (int, int) {
+	var size struct {
+		rows, cols, xpixel, ypixel uint16
+	}
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, os.Stdout.Fd(), syscall.TIOCGWINSZ, uintptr(unsafe.Pointer(&size)))
+	if errno != 0 || size.rows == 0 || size.cols == 0 || size.xpixel == 0 || size.ypixel == 0 {
+		return IMAGE_CELL_WIDTH, IMAGE_CELL_HEIGHT
+	}
+
+	return int(size.xpixel / size.cols), int(size.ypixel / size.rows)
+}
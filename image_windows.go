@@ -0,0 +1,9 @@
+//go:build windows
+// +build windows
+
+package main
+
+// Windows' consoles don't report their cells' size in pixels, so a common size is assumed
+func CellSize() (int, int) {
+	return IMAGE_CELL_WIDTH, IMAGE_CELL_HEIGHT
+}
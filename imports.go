@@ -0,0 +1,177 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var pythonImportPattern = regexp.MustCompile(`(?m)^\s*import\s+([\w., \t]+)`)
+var pythonFromPattern = regexp.MustCompile(`(?m)^\s*from\s+(\.*[\w.]*)\s+import\s+([\w., \t()*]+)`)
+var nodeImportPattern = regexp.MustCompile(`(?:require|import)\s*\(\s*['"]([^'"]+)['"]\s*\)|(?:import|export)\s+(?:[^'";]*?\s+from\s+)?['"]([^'"]+)['"]`)
+
+var nodeExtensions = []string{"", ".js", ".mjs", ".cjs", ".ts", ".jsx", ".tsx", ".json", "/index.js", "/index.ts"}
+
+// The language family whose imports can be followed, or "" if imports aren't understood
+func ImportLanguage(lang string) string {
+	program, _ := SplitLanguage(lang)
+
+	switch name := filepath.Base(program); {
+	case strings.HasPrefix(name, "python"), name == "pypy", name == "pypy3":
+		return IMPORTS_PYTHON
+	case name == "node", name == "bun", name == "deno", name == "ts-node", name == "tsx":
+		return IMPORTS_NODE
+	}
+
+	return ""
+}
+
+// List the modules a python source imports, as dotted names; relative imports keep their leading dots
+func PythonImports(source string) []string {
+	modules := []string{}
+
+	for _, match := range pythonImportPattern.FindAllStringSubmatch(source, -1) {
+		for _, name := range strings.Split(match[1], ",") {
+			// drop aliases; "import numpy as np"
+			fields := strings.Fields(name)
+			if len(fields) > 0 {
+				modules = append(modules, fields[0])
+			}
+		}
+	}
+
+	for _, match := range pythonFromPattern.FindAllStringSubmatch(source, -1) {
+		module := match[1]
+		modules = append(modules, module)
+
+		// "from . import sibling" and "from pkg import submodule" may name modules too
+		for _, name := range strings.Split(strings.Trim(match[2], " \t()"), ",") {
+			fields := strings.Fields(name)
+			if len(fields) == 0 || fields[0] == "*" {
+				continue
+			}
+
+			if strings.HasSuffix(module, ".") {
+				modules = append(modules, module+fields[0])
+			} else {
+				modules = append(modules, module+"."+fields[0])
+			}
+		}
+	}
+
+	return modules
+}
+
+// List the relative paths a javascript or typescript source requires or imports; packages are ignored
+func NodeImports(source string) []string {
+	specifiers := []string{}
+
+	for _, match := range nodeImportPattern.FindAllStringSubmatch(source, -1) {
+		specifier := match[1]
+		if len(specifier) == 0 {
+			specifier = match[2]
+		}
+
+		if strings.HasPrefix(specifier, "./") || strings.HasPrefix(specifier, "../") {
+			specifiers = append(specifiers, specifier)
+		}
+	}
+
+	return specifiers
+}
+
+// Is the path an existing regular file?
+func isFile(fpath string) bool {
+	info, err := os.Stat(fpath)
+	return err == nil && !info.IsDir()
+}
+
+// Find the files a python module name refers to, relative to the importing file or the project root
+func resolvePython(module string, importer string, dpath string) []string {
+	bases := []string{filepath.Dir(importer), dpath}
+
+	// relative imports climb one directory per dot beyond the first
+	if strings.HasPrefix(module, ".") {
+		trimmed := strings.TrimLeft(module, ".")
+		base := filepath.Dir(importer)
+
+		for idx := 1; idx < len(module)-len(trimmed); idx++ {
+			base = filepath.Dir(base)
+		}
+
+		bases = []string{base}
+		module = trimmed
+	}
+
+	files := []string{}
+
+	for _, base := range bases {
+		stem := filepath.Join(base, filepath.FromSlash(strings.Replace(module, ".", "/", -1)))
+
+		for _, candidate := range []string{stem + ".py", filepath.Join(stem, "__init__.py")} {
+			if isFile(candidate) {
+				files = append(files, candidate)
+			}
+		}
+	}
+
+	return files
+}
+
+// Find the file a relative node specifier refers to
+func resolveNode(specifier string, importer string) []string {
+	stem := filepath.Join(filepath.Dir(importer), filepath.FromSlash(specifier))
+
+	for _, extension := range nodeExtensions {
+		if isFile(stem + extension) {
+			return []string{stem + extension}
+		}
+	}
+
+	return []string{}
+}
+
+// List the entrypoint and every file beneath the directory it transitively imports
+func ImportClosure(entrypoint string, dpath string, family string) []string {
+	seen := map[string]bool{entrypoint: true}
+	queue := []string{entrypoint}
+	files := []string{}
+
+	for len(queue) > 0 {
+		fpath := queue[0]
+		queue = queue[1:]
+		files = append(files, fpath)
+
+		content, err := ioutil.ReadFile(fpath)
+		if err != nil {
+			continue
+		}
+
+		imported := []string{}
+
+		switch family {
+		case IMPORTS_PYTHON:
+			for _, module := range PythonImports(string(content)) {
+				imported = append(imported, resolvePython(module, fpath, dpath)...)
+			}
+		case IMPORTS_NODE:
+			for _, specifier := range NodeImports(string(content)) {
+				imported = append(imported, resolveNode(specifier, fpath)...)
+			}
+		}
+
+		for _, dep := range imported {
+			rel, err := filepath.Rel(dpath, dep)
+			if err != nil || strings.HasPrefix(rel, "..") || seen[dep] {
+				continue
+			}
+
+			seen[dep] = true
+			queue = append(queue, dep)
+		}
+	}
+
+	return files
+}
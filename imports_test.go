@@ -0,0 +1,83 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestPythonImports(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{"Reads plain imports, dropping aliases", "import os, numpy as np\n", []string{"os", "numpy"}},
+		{"Reads from-imports", "from lib.util import helper\n", []string{"lib.util", "lib.util.helper"}},
+		{"Keeps relative import dots", "from . import sibling\n", []string{".", ".sibling"}},
+		{"Ignores star imports", "from lib import *\n", []string{"lib"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PythonImports(tt.source); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PythonImports() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNodeImports(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{"Reads relative requires", "const a = require('./a')\nconst fs = require('fs')\n", []string{"./a"}},
+		{"Reads import statements", "import { b } from \"../b.js\"\nimport './c'\n", []string{"../b.js", "./c"}},
+		{"Reads dynamic imports", "await import('./d')\n", []string{"./d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NodeImports(tt.source); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NodeImports() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportClosure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replit-imports")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"main.py":         "import lib.util\nimport os\n",
+		"lib/__init__.py": "",
+		"lib/util.py":     "from . import shared\n",
+		"lib/shared.py":   "",
+		"unrelated.py":    "",
+	}
+
+	for name, content := range files {
+		fpath := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(fpath), 0755)
+		ioutil.WriteFile(fpath, []byte(content), 0644)
+	}
+
+	got := []string{}
+	for _, fpath := range ImportClosure(filepath.Join(dir, "main.py"), dir, IMPORTS_PYTHON) {
+		rel, _ := filepath.Rel(dir, fpath)
+		got = append(got, rel)
+	}
+	sort.Strings(got)
+
+	want := []string{"lib/__init__.py", "lib/shared.py", "lib/util.py", "main.py"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ImportClosure() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A test's result, parsed from a test runner's output
+type TestCase struct {
+	Name     string
+	Class    string
+	Status   string
+	Duration time.Duration
+	Output   string
+}
+
+// go test's "=== RUN   TestA" and "--- FAIL: TestA (0.01s)" lines
+var goTestRunPattern = regexp.MustCompile(`^=== (?:RUN|CONT)\s+(\S+)`)
+var goTestResultPattern = regexp.MustCompile(`^\s*--- (PASS|FAIL|SKIP): (\S+) \(([\d.]+)s\)`)
+
+// pytest -v's "test_x.py::test_a PASSED    [ 50%]" lines
+var pytestResultPattern = regexp.MustCompile(`^(\S+?)::(\S+) (PASSED|FAILED|SKIPPED|ERROR|XFAIL|XPASS)\b`)
+
+// Parse the tests a run reported, from go test or pytest -v output. Output a test logged
+// is kept with it
+func ParseTestCases(output []byte) []TestCase {
+	cases := []TestCase{}
+	logs := map[string][]string{}
+	current := ""
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if match := goTestRunPattern.FindStringSubmatch(line); match != nil {
+			current = match[1]
+			continue
+		}
+
+		if match := goTestResultPattern.FindStringSubmatch(line); match != nil {
+			seconds, _ := strconv.ParseFloat(match[3], 64)
+			status := map[string]string{"PASS": TEST_PASSED, "FAIL": TEST_FAILED, "SKIP": TEST_SKIPPED}[match[1]]
+
+			cases = append(cases, TestCase{Name: match[2], Status: status, Duration: time.Duration(seconds * float64(time.Second))})
+
+			// without -v, a test's log follows its result
+			current = match[2]
+			continue
+		}
+
+		if match := pytestResultPattern.FindStringSubmatch(line); match != nil {
+			status := TEST_PASSED
+			switch match[3] {
+			case "FAILED", "ERROR", "XPASS":
+				status = TEST_FAILED
+			case "SKIPPED", "XFAIL":
+				status = TEST_SKIPPED
+			}
+
+			cases = append(cases, TestCase{Name: match[2], Class: match[1], Status: status})
+			continue
+		}
+
+		if len(current) > 0 && strings.HasPrefix(line, "    ") {
+			logs[current] = append(logs[current], strings.TrimPrefix(line, "    "))
+		} else {
+			current = ""
+		}
+	}
+
+	for idx := range cases {
+		cases[idx].Output = strings.Join(logs[cases[idx].Name], "\n")
+	}
+
+	return cases
+}
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Skipped   int         `xml:"skipped,attr"`
+	Time      string      `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr"`
+	Cases     []junitCase `xml:"testcase"`
+	Stdout    string      `xml:"system-out,omitempty"`
+	Stderr    string      `xml:"system-err,omitempty"`
+}
+
+type junitCase struct {
+	Name    string        `xml:"name,attr"`
+	Class   string        `xml:"classname,attr"`
+	Time    string        `xml:"time,attr"`
+	Failure *junitFailure `xml:"failure,omitempty"`
+	Skipped *struct{}     `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// Render a run as a JUnit XML report. Tests the runner reported, or its TAP test points, are listed
+// as test cases; otherwise the run itself is the only test case, failing if the run failed
+func JUnitReport(entry HistoryEntry, name string) ([]byte, error) {
+	output := append(append([]byte{}, entry.Stdout...), entry.Stderr...)
+	cases := ParseTestCases(output)
+
+	if report, ok := ParseTAP(entry.Stdout); ok && len(cases) == 0 {
+		for _, result := range report.Results {
+			status := TEST_PASSED
+			if result.Directive == "SKIP" {
+				status = TEST_SKIPPED
+			} else if result.Failed() {
+				status = TEST_FAILED
+			}
+
+			cases = append(cases, TestCase{Name: result.Description, Status: status, Output: strings.Join(result.Details, "\n")})
+		}
+	}
+
+	if len(cases) == 0 {
+		status := TEST_PASSED
+		if !entry.Result.Succeeded() {
+			status = TEST_FAILED
+		}
+
+		cases = []TestCase{{Name: name, Status: status, Duration: entry.Result.Duration, Output: string(entry.Stderr)}}
+	}
+
+	suite := junitSuite{
+		Name:      name,
+		Time:      fmt.Sprintf("%.3f", entry.Result.Duration.Seconds()),
+		Timestamp: entry.Result.StartedAt.Format("2006-01-02T15:04:05"),
+		Stdout:    string(entry.Stdout),
+		Stderr:    string(entry.Stderr),
+	}
+
+	for _, test := range cases {
+		class := test.Class
+		if len(class) == 0 {
+			class = name
+		}
+
+		junit := junitCase{Name: test.Name, Class: class, Time: fmt.Sprintf("%.3f", test.Duration.Seconds())}
+
+		switch test.Status {
+		case TEST_FAILED:
+			junit.Failure = &junitFailure{Message: test.Name + " failed", Text: test.Output}
+			suite.Failures += 1
+		case TEST_SKIPPED:
+			junit.Skipped = &struct{}{}
+			suite.Skipped += 1
+		}
+
+		suite.Cases = append(suite.Cases, junit)
+	}
+
+	suite.Tests = len(suite.Cases)
+
+	data, err := xml.MarshalIndent(junitSuites{Suites: []junitSuite{suite}}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// Write a run's JUnit report to a path; {run} in the path is replaced by the run's number, to keep
+// a report per run rather than only the latest
+func WriteJUnitReport(template string, entry HistoryEntry, name string) error {
+	data, err := JUnitReport(entry, name)
+	if err != nil {
+		return err
+	}
+
+	fpath := ExpandTemplate(template, map[string]string{"run": strconv.FormatInt(entry.Run, 10)})
+
+	return ioutil.WriteFile(fpath, data, 0644)
+}
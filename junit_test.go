@@ -0,0 +1,83 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTestCases(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []TestCase
+	}{
+		{
+			"Reads go test -v output, keeping each test's log",
+			`=== RUN   TestA
+    a_test.go:3: boom
+--- FAIL: TestA (0.25s)
+=== RUN   TestB
+=== RUN   TestB/sub
+--- PASS: TestB (0.00s)
+    --- SKIP: TestB/sub (0.00s)
+FAIL
+`,
+			[]TestCase{
+				{"TestA", "", TEST_FAILED, 250 * time.Millisecond, "a_test.go:3: boom"},
+				{"TestB", "", TEST_PASSED, 0, ""},
+				{"TestB/sub", "", TEST_SKIPPED, 0, ""},
+			},
+		},
+		{
+			"Reads go test logs that follow the result",
+			"--- FAIL: TestA (0.00s)\n    a_test.go:3: boom\nFAIL\n",
+			[]TestCase{{"TestA", "", TEST_FAILED, 0, "a_test.go:3: boom"}},
+		},
+		{
+			"Reads pytest -v output",
+			"test_x.py::test_a FAILED      [ 50%]\ntest_x.py::test_b PASSED      [100%]\n",
+			[]TestCase{
+				{"test_a", "test_x.py", TEST_FAILED, 0, ""},
+				{"test_b", "test_x.py", TEST_PASSED, 0, ""},
+			},
+		},
+		{
+			"Finds no tests in other output",
+			"hello\n",
+			[]TestCase{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseTestCases([]byte(tt.output)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTestCases() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJUnitReport(t *testing.T) {
+	entry := HistoryEntry{
+		Run:    1,
+		Result: RunResult{ExitCode: 1, Duration: time.Second},
+		Stderr: []byte("Traceback <oops>\n"),
+	}
+
+	data, err := JUnitReport(entry, "main.py")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := string(data)
+	for _, want := range []string{
+		`<testsuite name="main.py" tests="1" failures="1" skipped="0" time="1.000"`,
+		`<testcase name="main.py" classname="main.py" time="1.000">`,
+		`<failure message="main.py failed">Traceback &lt;oops&gt;`,
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("JUnitReport() = %s, missing %s", report, want)
+		}
+	}
+}
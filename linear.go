@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Print runs as plain, labelled text in the order they happen, rather than drawing the interface, so
+// screen readers can follow them. Output is headed by its stream each time the stream changes.
+// Methods of a nil report do nothing
+type LinearReport struct {
+	lock      sync.Mutex
+	out       io.Writer
+	stream    string
+	partial   map[string]*bytes.Buffer
+	lastError string
+}
+
+func NewLinearReport(out io.Writer) *LinearReport {
+	return &LinearReport{out: out, partial: map[string]*bytes.Buffer{}}
+}
+
+// Print a line of text
+func (report *LinearReport) Print(text string) {
+	if report == nil {
+		return
+	}
+
+	report.lock.Lock()
+	defer report.lock.Unlock()
+
+	report.stream = ""
+	fmt.Fprintln(report.out, text)
+}
+
+// Announce a run starting
+func (report *LinearReport) Started(run int64, file string) {
+	report.Print(fmt.Sprintf("Run %d started: %s", run, file))
+}
+
+// Announce how a run finished, and why, if it was stopped
+func (report *LinearReport) Finished(run int64, result RunResult) {
+	if report == nil {
+		return
+	}
+
+	report.EndOutput()
+
+	status := "passed"
+	if !result.Succeeded() {
+		status = fmt.Sprintf("failed with exit code %d", result.ExitCode)
+	}
+
+	text := fmt.Sprintf("Run %d %s in %s.", run, status, FormatDuration(result.Duration))
+	if footer := result.Footer(); len(footer) > 0 {
+		text += " It " + strings.TrimPrefix(footer, "✗ ") + "."
+	}
+
+	report.Print(text)
+}
+
+// Report an error, unless it was the last reported
+func (report *LinearReport) Error(err error) {
+	if report == nil {
+		return
+	}
+
+	report.lock.Lock()
+	repeated := report.lastError == err.Error()
+	report.lastError = err.Error()
+	report.lock.Unlock()
+
+	if !repeated {
+		report.Print("Error: " + err.Error())
+	}
+}
+
+// A writer printing a stream's complete lines, headed by its name
+func (report *LinearReport) Writer(stream string) io.Writer {
+	return &linearWriter{report, stream}
+}
+
+type linearWriter struct {
+	report *LinearReport
+	stream string
+}
+
+func (writer *linearWriter) Write(data []byte) (int, error) {
+	report := writer.report
+
+	report.lock.Lock()
+	defer report.lock.Unlock()
+
+	partial, ok := report.partial[writer.stream]
+	if !ok {
+		partial = &bytes.Buffer{}
+		report.partial[writer.stream] = partial
+	}
+
+	partial.Write(data)
+
+	if idx := bytes.LastIndexByte(partial.Bytes(), '\n'); idx >= 0 {
+		lines := string(partial.Next(idx + 1))
+		report.printLines(writer.stream, lines)
+	}
+
+	return len(data), nil
+}
+
+// Print lines of a stream, heading them with the stream if the last lines printed were another's
+func (report *LinearReport) printLines(stream string, lines string) {
+	if report.stream != stream {
+		fmt.Fprintln(report.out, stream+":")
+		report.stream = stream
+	}
+
+	// screen readers would read escape sequences aloud
+	fmt.Fprint(report.out, terminalEscapes.ReplaceAllString(lines, ""))
+}
+
+// Print output left without a final newline when a run ends
+func (report *LinearReport) EndOutput() {
+	if report == nil {
+		return
+	}
+
+	report.lock.Lock()
+	defer report.lock.Unlock()
+
+	for _, stream := range []string{LINEAR_STDOUT, LINEAR_STDERR} {
+		if partial, ok := report.partial[stream]; ok && partial.Len() > 0 {
+			report.printLines(stream, partial.String()+"\n")
+			partial.Reset()
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLinearReport(t *testing.T) {
+	var out bytes.Buffer
+	report := NewLinearReport(&out)
+
+	report.Started(1, "main.py")
+	stdout := report.Writer(LINEAR_STDOUT)
+	stderr := report.Writer(LINEAR_STDERR)
+
+	stdout.Write([]byte("first\n\x1b[31msec"))
+	stderr.Write([]byte("oops\r\n"))
+	stdout.Write([]byte("ond\nthird"))
+	report.Error(errors.New("could not fetch"))
+	report.Error(errors.New("could not fetch"))
+	report.Finished(1, RunResult{ExitCode: 2, Duration: 1500 * time.Millisecond})
+
+	want := `Run 1 started: main.py
+Output:
+first
+Error output:
+oops
+Output:
+second
+Error: could not fetch
+Output:
+third
+Run 1 failed with exit code 2 in 1.5s.
+`
+	if got := out.String(); got != want {
+		t.Errorf("LinearReport printed %q, want %q", got, want)
+	}
+}
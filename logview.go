@@ -0,0 +1,481 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+	"github.com/rivo/tview"
+	"github.com/rivo/uniseg"
+)
+
+// tview's colour tags, and escaped text that only looks like one
+var (
+	logTagPattern     = regexp.MustCompile(`^\[([a-zA-Z]+|#[0-9a-zA-Z]{6}|\-)?(:([a-zA-Z]+|#[0-9a-zA-Z]{6}|\-)?(:([lbdru]+|\-)?)?)?\]`)
+	logEscapedPattern = regexp.MustCompile(`^\[[a-zA-Z0-9_,;: \-\."#]+\[+\]`)
+	logSanitizer      = strings.NewReplacer("\t", strings.Repeat(" ", tview.TabSize), "\r", "")
+)
+
+// A scrollable view of output that indexes its lines, and draws only the rows on screen. A
+// TextView re-indexes all of its text as output arrives, which stalls on million-line outputs
+type LogView struct {
+	*tview.Box
+	lock      sync.Mutex
+	lines     []string
+	starts    []int
+	open      bool
+	cursor    int
+	rewriting bool
+	dirty     int
+	rows      int
+	width     int
+	offset    int
+	trackEnd  bool
+	pageSize  int
+}
+
+func NewLogView() *LogView {
+	return &LogView{Box: tview.NewBox(), dirty: -1}
+}
+
+// Append output; a line left without a newline is continued by the next write. While rewriting,
+// output replaces the previous text line by line
+func (view *LogView) Write(data []byte) (int, error) {
+	if len(data) == 0 {
+		return 0, nil
+	}
+
+	view.lock.Lock()
+	defer view.lock.Unlock()
+
+	parts := strings.Split(logSanitizer.Replace(string(data)), "\n")
+
+	for idx, part := range parts {
+		last := idx == len(parts)-1
+		if last && len(part) == 0 {
+			view.open = false
+			break
+		}
+
+		if idx == 0 && view.open {
+			view.put(view.cursor-1, view.lines[view.cursor-1]+part)
+		} else {
+			view.put(view.cursor, part)
+			view.cursor += 1
+		}
+
+		view.open = last
+	}
+
+	return len(data), nil
+}
+
+// Add a line, noting the row it starts on
+func (view *LogView) push(line string) {
+	view.lines = append(view.lines, line)
+	view.starts = append(view.starts, view.rows)
+	view.rows += WrappedRowCount(line, view.width)
+}
+
+// Set a line, or add it if it's past the last line. Unchanged lines are left as they are, and the
+// lines after one that wraps onto a different number of rows are recounted before they're next drawn
+func (view *LogView) put(idx int, line string) {
+	if idx == len(view.lines) {
+		view.push(line)
+		return
+	}
+
+	if view.lines[idx] == line {
+		return
+	}
+
+	before := WrappedRowCount(view.lines[idx], view.width)
+	after := WrappedRowCount(line, view.width)
+	view.lines[idx] = line
+
+	if after != before {
+		view.rows += after - before
+
+		if view.dirty < 0 || idx+1 < view.dirty {
+			view.dirty = idx + 1
+		}
+	}
+}
+
+// Recount the rows lines start on, from the first line whose start is out of date
+func (view *LogView) settle() {
+	if view.dirty < 0 {
+		return
+	}
+
+	for idx := view.dirty; idx < len(view.lines); idx++ {
+		view.starts[idx] = view.starts[idx-1] + WrappedRowCount(view.lines[idx-1], view.width)
+	}
+
+	view.dirty = -1
+}
+
+// Recount the rows each line wraps onto, once the width changes
+func (view *LogView) reindex() {
+	view.rows = 0
+	view.dirty = -1
+
+	for idx, line := range view.lines {
+		view.starts[idx] = view.rows
+		view.rows += WrappedRowCount(line, view.width)
+	}
+}
+
+// Replace the text with output written from now on, line by line, so lines that are unchanged
+// aren't redrawn. Lines past the new output are dimmed until EndRewrite removes them
+func (view *LogView) Rewrite() *LogView {
+	view.lock.Lock()
+	defer view.lock.Unlock()
+
+	view.cursor = 0
+	view.open = false
+	view.rewriting = true
+
+	return view
+}
+
+// Remove the lines past the output written since Rewrite
+func (view *LogView) EndRewrite() *LogView {
+	view.lock.Lock()
+	defer view.lock.Unlock()
+
+	view.settle()
+
+	if view.cursor < len(view.lines) {
+		view.rows = view.starts[view.cursor]
+		view.lines = view.lines[:view.cursor]
+		view.starts = view.starts[:view.cursor]
+	}
+
+	view.rewriting = false
+
+	return view
+}
+
+// Replace the text of the view
+func (view *LogView) SetText(text string) *LogView {
+	view.Clear()
+	view.Write([]byte(text))
+
+	return view
+}
+
+// Copy the text of the view, without the lines a rewrite hasn't reached
+func (view *LogView) GetText() string {
+	view.lock.Lock()
+	defer view.lock.Unlock()
+
+	text := strings.Join(view.lines[:view.cursor], "\n")
+	if view.cursor > 0 && !view.open {
+		text += "\n"
+	}
+
+	return text
+}
+
+// Remove all text from the view
+func (view *LogView) Clear() *LogView {
+	view.lock.Lock()
+	defer view.lock.Unlock()
+
+	view.lines = nil
+	view.starts = nil
+	view.open = false
+	view.cursor = 0
+	view.rewriting = false
+	view.dirty = -1
+	view.rows = 0
+	view.offset = 0
+
+	return view
+}
+
+// The number of rows the text wraps onto, at the width it was last drawn
+func (view *LogView) RowCount() int {
+	view.lock.Lock()
+	defer view.lock.Unlock()
+
+	return view.rows
+}
+
+func (view *LogView) GetScrollOffset() (row, column int) {
+	view.lock.Lock()
+	defer view.lock.Unlock()
+
+	return view.offset, 0
+}
+
+// Scroll to a row; the view stops following the end of the output. Wrapped lines aren't
+// scrolled horizontally, so the column is ignored
+func (view *LogView) ScrollTo(row, column int) *LogView {
+	view.lock.Lock()
+	defer view.lock.Unlock()
+
+	view.offset = row
+	view.trackEnd = false
+
+	return view
+}
+
+// Scroll to the row a line starts on, counting lines from zero
+func (view *LogView) ScrollToLine(line int) *LogView {
+	view.lock.Lock()
+	defer view.lock.Unlock()
+
+	view.settle()
+
+	if line >= 0 && line < len(view.starts) {
+		view.offset = view.starts[line]
+		view.trackEnd = false
+	}
+
+	return view
+}
+
+func (view *LogView) Draw(screen tcell.Screen) {
+	view.Box.DrawForSubclass(screen, view)
+
+	x, y, width, height := view.GetInnerRect()
+	if width <= 0 || height <= 0 {
+		return
+	}
+
+	view.lock.Lock()
+	defer view.lock.Unlock()
+
+	if width != view.width {
+		view.width = width
+		view.reindex()
+	}
+
+	view.settle()
+	view.pageSize = height
+
+	// while rewriting, the end of the output is the end of the new output
+	end := view.rows
+	if view.rewriting && view.cursor < len(view.lines) {
+		end = view.starts[view.cursor]
+	}
+
+	// scrolling past the end follows the end of the output, as a TextView does
+	if view.offset+height > end {
+		view.trackEnd = true
+	}
+
+	if view.trackEnd {
+		view.offset = end - height
+	}
+
+	if view.offset < 0 {
+		view.offset = 0
+	}
+
+	if len(view.lines) == 0 {
+		return
+	}
+
+	// find the first line on screen, and how many of its rows are scrolled past
+	line := sort.SearchInts(view.starts, view.offset+1) - 1
+	skip := view.offset - view.starts[line]
+
+	for row := 0; row < height && line < len(view.lines); line++ {
+		wrapped := WrapTaggedLine(view.lines[line], width)
+		stale := view.rewriting && line >= view.cursor
+
+		for _, text := range wrapped[skip:] {
+			if row >= height {
+				break
+			}
+
+			if stale {
+				text = "[::d]" + text
+			}
+
+			tview.Print(screen, text, x, y+row, width, tview.AlignLeft, tview.Styles.PrimaryTextColor)
+			row += 1
+		}
+
+		skip = 0
+	}
+}
+
+// Scroll with the arrow, page, home, and end keys, or g and G to jump to the start or end. The
+// application's own bindings, such as j and k, take precedence
+func (view *LogView) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return view.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		view.lock.Lock()
+		defer view.lock.Unlock()
+
+		switch event.Key() {
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'g':
+				view.trackEnd = false
+				view.offset = 0
+			case 'G':
+				view.trackEnd = true
+			}
+		case tcell.KeyHome:
+			view.trackEnd = false
+			view.offset = 0
+		case tcell.KeyEnd:
+			view.trackEnd = true
+		case tcell.KeyUp:
+			view.trackEnd = false
+			view.offset--
+		case tcell.KeyDown:
+			view.offset++
+		case tcell.KeyPgDn, tcell.KeyCtrlF:
+			view.offset += view.pageSize
+		case tcell.KeyPgUp, tcell.KeyCtrlB:
+			view.trackEnd = false
+			view.offset -= view.pageSize
+		}
+	})
+}
+
+// Focus the view when clicked, and scroll it with the mouse wheel
+func (view *LogView) MouseHandler() func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (bool, tview.Primitive) {
+	return view.WrapMouseHandler(func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (bool, tview.Primitive) {
+		if !view.InRect(event.Position()) {
+			return false, nil
+		}
+
+		view.lock.Lock()
+		defer view.lock.Unlock()
+
+		switch action {
+		case tview.MouseLeftClick:
+			setFocus(view)
+			return true, nil
+		case tview.MouseScrollUp:
+			view.trackEnd = false
+			view.offset--
+			return true, nil
+		case tview.MouseScrollDown:
+			view.offset++
+			return true, nil
+		}
+
+		return false, nil
+	})
+}
+
+// Count the rows a line wraps onto at a width
+func WrappedRowCount(line string, width int) int {
+	// no character is wider than its encoding, so short lines fit without measuring them
+	if width <= 0 || len(line) <= width {
+		return 1
+	}
+
+	rows := 1
+	rowWidth := 0
+
+	walkTaggedLine(line, func(from int, to int, cellWidth int) {
+		if rowWidth > 0 && rowWidth+cellWidth > width {
+			rows += 1
+			rowWidth = 0
+		}
+
+		rowWidth += cellWidth
+	}, func(tag string) {})
+
+	return rows
+}
+
+// Split a line into the rows it wraps onto at a width. The colour tags before each
+// row are repeated at its start, so wrapped text keeps its style
+func WrapTaggedLine(line string, width int) []string {
+	if width <= 0 || len(line) <= width {
+		return []string{line}
+	}
+
+	rows := []string{}
+	prefix := ""
+	tags := ""
+	start := 0
+	rowWidth := 0
+
+	walkTaggedLine(line, func(from int, to int, cellWidth int) {
+		if rowWidth > 0 && rowWidth+cellWidth > width {
+			rows = append(rows, prefix+line[start:from])
+			prefix = tags
+			start = from
+			rowWidth = 0
+		}
+
+		rowWidth += cellWidth
+	}, func(tag string) {
+		tags += tag
+	})
+
+	return append(rows, prefix+line[start:])
+}
+
+// Visit each character of a line with its byte range and width in cells, and each colour tag
+func walkTaggedLine(line string, cell func(from int, to int, cellWidth int), tag func(tag string)) {
+	for idx := 0; idx < len(line); {
+		char := line[idx]
+
+		switch {
+		case char == '[':
+			if text := logTagPattern.FindString(line[idx:]); len(text) > 0 {
+				tag(text)
+				idx += len(text)
+				continue
+			}
+
+			// escaped tags render without one of their brackets
+			if escaped := logEscapedPattern.FindString(line[idx:]); len(escaped) > 0 {
+				cell(idx, idx+len(escaped), len(escaped)-1)
+				idx += len(escaped)
+				continue
+			}
+
+			cell(idx, idx+1, 1)
+			idx += 1
+		case char < utf8.RuneSelf:
+			cellWidth := 1
+			if char < ' ' || char == 0x7f {
+				cellWidth = 0
+			}
+
+			cell(idx, idx+1, cellWidth)
+			idx += 1
+		default:
+			// measure runs of other characters by grapheme cluster, as tview does
+			end := idx
+			for end < len(line) && line[end] >= utf8.RuneSelf {
+				end += 1
+			}
+
+			graphemes := uniseg.NewGraphemes(line[idx:end])
+			for graphemes.Next() {
+				from, to := graphemes.Positions()
+				cell(idx+from, idx+to, GraphemeWidth(graphemes.Runes()))
+			}
+
+			idx = end
+		}
+	}
+}
+
+// The cells a grapheme cluster occupies; the width of its first non-zero-width rune, as tview measures it
+func GraphemeWidth(runes []rune) int {
+	for _, char := range runes {
+		if width := runewidth.RuneWidth(char); width > 0 {
+			return width
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLogViewWrite(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+		rows   int
+	}{
+		{
+			"Splits writes into lines",
+			[]string{"a\nb\n"},
+			"a\nb\n",
+			2,
+		},
+		{
+			"Continues lines left open by an earlier write",
+			[]string{"a", "b\nc", "d"},
+			"ab\ncd",
+			2,
+		},
+		{
+			"Expands tabs",
+			[]string{"\tx\n"},
+			"    x\n",
+			1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			view := NewLogView()
+
+			for _, write := range tt.writes {
+				view.Write([]byte(write))
+			}
+
+			if got := view.GetText(); got != tt.want {
+				t.Errorf("GetText() = %q, want %q", got, tt.want)
+			}
+
+			if got := view.RowCount(); got != tt.rows {
+				t.Errorf("RowCount() = %d, want %d", got, tt.rows)
+			}
+		})
+	}
+}
+
+func TestLogViewRewrite(t *testing.T) {
+	view := NewLogView()
+	view.width = 4
+	view.Write([]byte("a\nb\nc\nd\n"))
+
+	// the second line now wraps onto two rows, and the last is left from the previous text
+	view.Rewrite()
+	view.Write([]byte("a\nbbbbb\nc"))
+
+	if got := view.GetText(); got != "a\nbbbbb\nc" {
+		t.Errorf("GetText() = %q, want the rewritten text", got)
+	}
+
+	if got := view.RowCount(); got != 5 {
+		t.Errorf("RowCount() = %d, want 5 while the last line remains", got)
+	}
+
+	if row, _ := view.ScrollToLine(2).GetScrollOffset(); row != 3 {
+		t.Errorf("the third line starts on row %d, want 3", row)
+	}
+
+	view.EndRewrite()
+
+	if got := view.RowCount(); got != 4 {
+		t.Errorf("RowCount() = %d, want 4 once the rewrite ends", got)
+	}
+
+	view.Write([]byte("\ne\n"))
+
+	if got := view.GetText(); got != "a\nbbbbb\nc\ne\n" {
+		t.Errorf("GetText() = %q, want writes appended after the rewrite", got)
+	}
+}
+
+func TestWrapTaggedLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		width int
+		want  []string
+	}{
+		{
+			"Leaves lines that fit",
+			"abc",
+			3,
+			[]string{"abc"},
+		},
+		{
+			"Wraps by cell",
+			"abcdefg",
+			3,
+			[]string{"abc", "def", "g"},
+		},
+		{
+			"Carries colour tags onto later rows",
+			"[red]abcd[-]ef",
+			3,
+			[]string{"[red]abc", "[red]d[-]ef"},
+		},
+		{
+			"Counts wide characters as two cells",
+			"日本語",
+			4,
+			[]string{"日本", "語"},
+		},
+		{
+			"Counts escaped tags without their extra bracket",
+			"[x[]yz",
+			3,
+			[]string{"[x[]", "yz"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WrapTaggedLine(tt.line, tt.width); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WrapTaggedLine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -7,6 +7,11 @@ import (
 )
 
 func main() {
+	// replit runs commands through itself on windows, under a pseudo console
+	if len(os.Args) > 1 && os.Args[1] == PSEUDO_CONSOLE_COMMAND {
+		os.Exit(RunPseudoConsole(os.Args[2:]))
+	}
+
 	opts, err := docopt.ParseDoc(Usage)
 
 	if err != nil {
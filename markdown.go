@@ -0,0 +1,178 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+var markdownHeading = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+var markdownBullet = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+var markdownRule = regexp.MustCompile(`^\s*([-*_]\s*){3,}$`)
+var markdownInline = regexp.MustCompile("`[^`]+`|\\*\\*[^*]+\\*\\*|\\[[^\\]]+\\]\\([^)]+\\)")
+
+// How a language's code is highlighted: its line comment marker, and its space-separated keywords
+type CodeSyntax struct {
+	Comment    string
+	Keywords   string
+	IgnoreCase bool
+}
+
+// A language's syntax, ready to match against lines of code
+type codeHighlighter struct {
+	syntax   CodeSyntax
+	keywords map[string]bool
+	tokens   *regexp.Regexp
+}
+
+var codeHighlighters = newCodeHighlighters()
+
+func newCodeHighlighters() map[string]*codeHighlighter {
+	highlighters := map[string]*codeHighlighter{}
+
+	for lang, syntax := range CODE_SYNTAXES {
+		keywords := map[string]bool{}
+		for _, keyword := range strings.Fields(syntax.Keywords) {
+			keywords[keyword] = true
+		}
+
+		// comments, strings, and words; comments and strings are matched first, so words within them aren't
+		pattern := regexp.QuoteMeta(syntax.Comment) + `.*|"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'|` + "`[^`]*`" + `|[A-Za-z_][A-Za-z0-9_]*`
+		highlighters[lang] = &codeHighlighter{syntax, keywords, regexp.MustCompile(pattern)}
+	}
+
+	return highlighters
+}
+
+// Render Markdown output with headings, lists, quotes, and code blocks styled for the terminal
+func RenderMarkdown(data []byte) string {
+	lines := strings.Split(string(data), "\n")
+	rendered := make([]string, 0, len(lines))
+	inCode := false
+	lang := ""
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		// code fences toggle verbatim blocks, highlighted if their language is known
+		if strings.HasPrefix(trimmed, "```") {
+			inCode = !inCode
+			lang = strings.TrimPrefix(trimmed, "```")
+
+			if inCode && len(lang) > 0 {
+				rendered = append(rendered, "[gray]  "+tview.Escape(lang)+"[-]")
+			}
+			continue
+		}
+
+		if inCode {
+			rendered = append(rendered, "["+CODE_COLOR+"]  │ "+HighlightCode(line, lang)+"[-]")
+			continue
+		}
+
+		if match := markdownHeading.FindStringSubmatch(line); match != nil {
+			color := "yellow"
+			if len(match[1]) > 1 {
+				color = "orange"
+			}
+
+			rendered = append(rendered, "["+color+"::b]"+tview.Escape(match[2])+"[-::-]")
+			continue
+		}
+
+		if markdownRule.MatchString(line) {
+			rendered = append(rendered, "[gray]"+strings.Repeat("─", 40)+"[-]")
+			continue
+		}
+
+		if match := markdownBullet.FindStringSubmatch(line); match != nil {
+			rendered = append(rendered, match[1]+"  [blue]•[-] "+RenderMarkdownInline(match[2]))
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, ">") {
+			quote := strings.TrimSpace(strings.TrimPrefix(trimmed, ">"))
+			rendered = append(rendered, "[gray]▌ "+RenderMarkdownInline(quote)+"[-]")
+			continue
+		}
+
+		rendered = append(rendered, RenderMarkdownInline(line))
+	}
+
+	return strings.Join(rendered, "\n")
+}
+
+// Colour a line of code's keywords, strings, and comments, for the languages in CODE_SYNTAXES; the fence's
+// language may be followed by other words. Each line is highlighted alone, so strings and comments spanning
+// lines aren't. Other languages' code is left unchanged
+func HighlightCode(line string, lang string) string {
+	fields := strings.Fields(strings.ToLower(lang))
+	if len(fields) == 0 {
+		return tview.Escape(line)
+	}
+
+	name := fields[0]
+	if alias, ok := CODE_ALIASES[name]; ok {
+		name = alias
+	}
+
+	highlighter, ok := codeHighlighters[name]
+	if !ok {
+		return tview.Escape(line)
+	}
+
+	var out strings.Builder
+	last := 0
+
+	// unstyled text is escaped in runs, so brackets around a word can't form a tag
+	for _, loc := range highlighter.tokens.FindAllStringIndex(line, -1) {
+		token := line[loc[0]:loc[1]]
+
+		color := ""
+		switch {
+		case strings.HasPrefix(token, highlighter.syntax.Comment):
+			color = CODE_COMMENT_COLOR
+		case strings.ContainsAny(token[:1], "\"'`"):
+			color = CODE_STRING_COLOR
+		case highlighter.keywords[token] || highlighter.syntax.IgnoreCase && highlighter.keywords[strings.ToLower(token)]:
+			color = CODE_KEYWORD_COLOR
+		default:
+			continue
+		}
+
+		out.WriteString(tview.Escape(line[last:loc[0]]))
+		out.WriteString("[" + color + "]" + tview.Escape(token) + "[" + CODE_COLOR + "]")
+		last = loc[1]
+	}
+
+	out.WriteString(tview.Escape(line[last:]))
+	return out.String()
+}
+
+// Style inline code, bold text, and links within a line
+func RenderMarkdownInline(line string) string {
+	var out strings.Builder
+	last := 0
+
+	for _, loc := range markdownInline.FindAllStringIndex(line, -1) {
+		out.WriteString(tview.Escape(line[last:loc[0]]))
+		token := line[loc[0]:loc[1]]
+
+		switch {
+		case strings.HasPrefix(token, "`"):
+			out.WriteString("[green]" + tview.Escape(strings.Trim(token, "`")) + "[-]")
+		case strings.HasPrefix(token, "**"):
+			out.WriteString("[::b]" + tview.Escape(strings.Trim(token, "*")) + "[::-]")
+		default:
+			split := strings.Index(token, "](")
+			text, url := token[1:split], token[split+2:len(token)-1]
+			out.WriteString("[::u]" + tview.Escape(text) + "[::-] [gray](" + tview.Escape(url) + ")[-]")
+		}
+
+		last = loc[1]
+	}
+
+	out.WriteString(tview.Escape(line[last:]))
+	return out.String()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			"Styles headings and lists",
+			"# Title\n- item with `code`",
+			"[yellow::b]Title[-::-]\n  [blue]•[-] item with [green]code[-]",
+		},
+		{
+			"Keeps code blocks verbatim",
+			"```go\nx := [a]\n```",
+			"[gray]  go[-]\n[green]  │ x := [a[][-]",
+		},
+		{
+			"Highlights code in known languages",
+			"```python\nif x: print(\"[a]\")  # done\n```",
+			"[gray]  python[-]\n[green]  │ [fuchsia]if[green] x: print([yellow]\"[a[]\"[green])  [gray]# done[green][-]",
+		},
+		{
+			"Shows link targets",
+			"see [docs](https://example.com)",
+			"see [::u]docs[::-] [gray](https://example.com)[-]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderMarkdown([]byte(tt.data)); got != tt.want {
+				t.Errorf("RenderMarkdown() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlightCode(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		lang string
+		want string
+	}{
+		{"Colours keywords", "func main() {", "go", "[fuchsia]func[green] main() {"},
+		{"Leaves keywords within words", "iffy := format", "go", "iffy := format"},
+		{"Doesn't colour words in strings or comments", `x = "if" // for`, "js", `x = [yellow]"if"[green] [gray]// for[green]`},
+		{"Knows languages by other names", "fi", "bash extra", "[fuchsia]fi[green]"},
+		{"Ignores case where the language does", "SELECT 1", "sql", "[fuchsia]SELECT[green] 1"},
+		{"Keeps brackets around plain words escaped", "x[i] = [a]", "go", "x[i[] = [a[]"},
+		{"Leaves unknown languages unchanged", "if [a]", "cobol", "if [a[]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HighlightCode(tt.line, tt.lang); got != tt.want {
+				t.Errorf("HighlightCode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Quote a word for a POSIX shell
+func ShellQuote(word string) string {
+	if len(word) > 0 && strings.Trim(word, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:@%+,") == "" {
+		return word
+	}
+
+	return "'" + strings.Replace(word, "'", `'"'"'`, -1) + "'"
+}
+
+// Does the nix target name a flake, rather than a shell.nix file or a list of packages?
+func isFlake(target string) bool {
+	if strings.Contains(target, "#") {
+		return true
+	}
+
+	_, err := os.Stat(filepath.Join(target, "flake.nix"))
+	return err == nil
+}
+
+// Check the nix tooling that a target needs is installed
+func ValidateNix(target string) error {
+	program := "nix-shell"
+	if isFlake(target) {
+		program = "nix"
+	}
+
+	if !CommandExists(program) {
+		return errors.New(program + " is not in PATH; is nix installed?")
+	}
+
+	return nil
+}
+
+// Run a command line inside a nix environment; a flake's dev shell, a shell.nix file, or a
+// shell with the listed packages
+func NixCommand(target string, argv []string) []string {
+	if len(target) == 0 {
+		return argv
+	}
+
+	if isFlake(target) {
+		return append([]string{"nix", "develop", target, "--command"}, argv...)
+	}
+
+	quoted := []string{}
+	for _, word := range argv {
+		quoted = append(quoted, ShellQuote(word))
+	}
+
+	script := strings.Join(quoted, " ")
+
+	if strings.HasSuffix(target, ".nix") {
+		return []string{"nix-shell", target, "--run", script}
+	}
+
+	return append(append([]string{"nix-shell", "-p"}, strings.Fields(target)...), "--run", script)
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNixCommand(t *testing.T) {
+	argv := []string{"python3", "/tmp/it's.py"}
+
+	tests := []struct {
+		name   string
+		target string
+		want   []string
+	}{
+		{"Runs unwrapped without a target", "", argv},
+		{"Runs inside a flake's dev shell", ".#dev", []string{"nix", "develop", ".#dev", "--command", "python3", "/tmp/it's.py"}},
+		{"Runs inside a shell.nix file", "shell.nix", []string{"nix-shell", "shell.nix", "--run", `python3 '/tmp/it'"'"'s.py'`}},
+		{"Runs with listed packages", "python3 jq", []string{"nix-shell", "-p", "python3", "jq", "--run", `python3 '/tmp/it'"'"'s.py'`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NixCommand(tt.target, argv); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NixCommand() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
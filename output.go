@@ -0,0 +1,598 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/rivo/tview"
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/transform"
+)
+
+// Decode output from a charset into UTF-8 before it reaches tview, replacing invalid byte
+// sequences. Close the writer once the stream ends, to flush any incomplete sequence
+func NewCharsetWriter(dst io.Writer, charset string) (io.WriteCloser, error) {
+	encoding, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, err
+	}
+
+	return transform.NewWriter(dst, encoding.NewDecoder()), nil
+}
+
+// Decode complete output from a charset into UTF-8, replacing invalid byte sequences. Unknown
+// charsets leave the output unchanged
+func DecodeCharset(data []byte, charset string) []byte {
+	encoding, err := htmlindex.Get(charset)
+	if err != nil {
+		return data
+	}
+
+	decoded, _, err := transform.Bytes(encoding.NewDecoder(), data)
+	if err != nil {
+		return data
+	}
+
+	return decoded
+}
+
+// Accumulate a stream's output while writing it through to a view. The raw output
+// is kept, so the view can be re-rendered in other forms once a run finishes. Output
+// reaches the view through a queue that may drop some, so the complete output is
+// recorded separately, for history and the parsers reading it. Output is only decoded
+// from its charset where it's shown as text
+type OutputBuffer struct {
+	lock        sync.Mutex
+	data        bytes.Buffer
+	recorded    bytes.Buffer
+	view        *LogView
+	charset     string
+	decodeLock  sync.Mutex
+	decoder     io.WriteCloser
+	transform   func(data []byte) string
+	binary      bool
+	interpretCR bool
+	keepScroll  bool
+	scroll      ScrollMark
+	footer      string
+	batched     bool
+	pending     bytes.Buffer
+	stale       bool
+}
+
+// A scroll position to restore as output arrives
+type ScrollMark struct {
+	Row    int
+	Column int
+	Active bool
+}
+
+func NewOutputBuffer(view *LogView) *OutputBuffer {
+	return &OutputBuffer{view: view, interpretCR: true, charset: "utf-8"}
+}
+
+// Set the charset output is decoded from when it's shown; the recorded output is left as written
+func (buffer *OutputBuffer) SetCharset(charset string) {
+	buffer.lock.Lock()
+	buffer.charset = charset
+	buffer.lock.Unlock()
+
+	buffer.resetDecoder()
+}
+
+// Drop any incomplete sequence held by the decoder, so the next output is decoded afresh
+func (buffer *OutputBuffer) resetDecoder() {
+	buffer.decodeLock.Lock()
+	defer buffer.decodeLock.Unlock()
+
+	buffer.decoder = nil
+}
+
+// Write output to the view, decoded from its charset. Sequences split across writes are
+// held until the rest arrives
+func (buffer *OutputBuffer) display(data []byte) (int, error) {
+	buffer.decodeLock.Lock()
+	defer buffer.decodeLock.Unlock()
+
+	if buffer.decoder == nil {
+		buffer.lock.Lock()
+		charset := buffer.charset
+		buffer.lock.Unlock()
+
+		decoder, err := NewCharsetWriter(buffer.view, charset)
+		if err != nil {
+			return buffer.view.Write(data)
+		}
+		buffer.decoder = decoder
+	}
+
+	return buffer.decoder.Write(data)
+}
+
+func (buffer *OutputBuffer) Write(data []byte) (int, error) {
+	buffer.lock.Lock()
+	buffer.data.Write(data)
+	redraw := buffer.interpretCR && bytes.IndexByte(data, '\r') >= 0
+
+	// batched output reaches the view when it's next flushed, so bursts cost one write
+	if buffer.batched {
+		buffer.pending.Write(data)
+		buffer.stale = buffer.stale || redraw
+		buffer.lock.Unlock()
+
+		return len(data), nil
+	}
+	buffer.lock.Unlock()
+
+	// a carriage return rewrites an earlier line, so the view is re-rendered rather than appended to
+	if redraw {
+		buffer.Refresh()
+		return len(data), nil
+	}
+
+	count, err := buffer.display(data)
+	buffer.restoreScroll()
+
+	return count, err
+}
+
+// A writer recording the complete output, without writing it to the view
+func (buffer *OutputBuffer) Recorder() io.Writer {
+	return outputRecorder{buffer}
+}
+
+type outputRecorder struct {
+	buffer *OutputBuffer
+}
+
+func (recorder outputRecorder) Write(data []byte) (int, error) {
+	recorder.buffer.lock.Lock()
+	defer recorder.buffer.lock.Unlock()
+
+	return recorder.buffer.recorded.Write(data)
+}
+
+// Hold output until Flush is called, rather than writing each chunk to the view as it arrives
+func (buffer *OutputBuffer) SetBatched(batched bool) {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+
+	buffer.batched = batched
+}
+
+// Write held output to the view; re-rendering it, if a carriage return rewrote an earlier line
+func (buffer *OutputBuffer) Flush() {
+	buffer.lock.Lock()
+	stale := buffer.stale
+	pending := append([]byte{}, buffer.pending.Bytes()...)
+	buffer.pending.Reset()
+	buffer.stale = false
+	buffer.lock.Unlock()
+
+	if stale {
+		buffer.Refresh()
+		return
+	}
+
+	if len(pending) == 0 {
+		return
+	}
+
+	buffer.display(pending)
+	buffer.restoreScroll()
+}
+
+// Keep the scroll position across reruns, unless the view was following the end of the output
+func (buffer *OutputBuffer) SetKeepScroll(keep bool) {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+
+	buffer.keepScroll = keep
+}
+
+// Remember where the view is scrolled to, if the user scrolled away from the end of the output
+func (buffer *OutputBuffer) holdScroll() {
+	if !buffer.keepScroll {
+		return
+	}
+
+	row, column := buffer.view.GetScrollOffset()
+	_, _, _, height := buffer.view.GetInnerRect()
+	following := row+height >= buffer.view.RowCount()
+
+	buffer.scroll = ScrollMark{row, column, !following}
+}
+
+// Scroll back to the held position. tview follows the end of the output while there
+// is too little to fill the view, so this repeats until the output is long enough
+func (buffer *OutputBuffer) restoreScroll() {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+
+	if !buffer.scroll.Active {
+		return
+	}
+
+	buffer.view.ScrollTo(buffer.scroll.Row, buffer.scroll.Column)
+
+	_, _, _, height := buffer.view.GetInnerRect()
+	if buffer.view.RowCount() >= buffer.scroll.Row+height {
+		buffer.scroll.Active = false
+	}
+}
+
+// Keep carriage returns as raw output, rather than treating them as in-place line updates
+func (buffer *OutputBuffer) SetInterpretCR(interpret bool) {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+
+	buffer.interpretCR = interpret
+}
+
+// Render the raw bytes, ignoring carriage returns; for binary views such as hexdumps
+func (buffer *OutputBuffer) SetBinary(binary bool) {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+
+	buffer.binary = binary
+}
+
+// Copy the complete, recorded output
+func (buffer *OutputBuffer) Bytes() []byte {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+
+	return append([]byte{}, buffer.recorded.Bytes()...)
+}
+
+// Remove all output from the buffer, and rewrite its view with the output that follows, so
+// lines the next output leaves unchanged aren't redrawn
+func (buffer *OutputBuffer) Clear() {
+	buffer.lock.Lock()
+	buffer.holdScroll()
+	buffer.data.Reset()
+	buffer.recorded.Reset()
+	buffer.pending.Reset()
+	buffer.stale = false
+	buffer.footer = ""
+	buffer.lock.Unlock()
+
+	buffer.resetDecoder()
+	buffer.view.Rewrite()
+}
+
+// Set lines rendered after the output, until it's cleared; such as why a run was killed. The
+// lines may include colour tags
+func (buffer *OutputBuffer) SetFooter(footer string) {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+
+	buffer.footer = footer
+}
+
+// Set how the raw output is rendered by Refresh; nil renders it unchanged
+func (buffer *OutputBuffer) SetTransform(transform func(data []byte) string) {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+
+	buffer.transform = transform
+}
+
+// Re-render the view from the raw output, including any held output. Only the lines
+// that changed are redrawn. Binary views render the bytes as written; others decode them
+// from the output's charset first
+func (buffer *OutputBuffer) Refresh() {
+	buffer.lock.Lock()
+	buffer.pending.Reset()
+	buffer.stale = false
+
+	data := buffer.data.Bytes()
+	if !buffer.binary {
+		data = DecodeCharset(data, buffer.charset)
+
+		if buffer.interpretCR {
+			data = ApplyCarriageReturns(data)
+		}
+	}
+
+	text := string(data)
+	if buffer.transform != nil {
+		text = buffer.transform(data)
+	}
+
+	if len(buffer.footer) > 0 {
+		if len(text) > 0 && !strings.HasSuffix(text, "\n") {
+			text += "\n"
+		}
+
+		text += buffer.footer + "\n"
+	}
+	buffer.lock.Unlock()
+
+	buffer.view.Rewrite()
+	buffer.view.Write([]byte(text))
+	buffer.view.EndRewrite()
+	buffer.restoreScroll()
+}
+
+// Treat carriage returns as in-place line updates, as a terminal would; progress bars
+// redraw their line this way, and would otherwise fill the view with stale copies
+func ApplyCarriageReturns(data []byte) []byte {
+	if bytes.IndexByte(data, '\r') < 0 {
+		return data
+	}
+
+	lines := bytes.Split(data, []byte("\n"))
+
+	for idx, line := range lines {
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		segments := bytes.Split(line, []byte("\r"))
+
+		// later segments overwrite the start of the line, leaving any longer tail
+		current := string(segments[0])
+		for _, segment := range segments[1:] {
+			current = OverwriteCells(current, string(segment))
+		}
+
+		lines[idx] = []byte(current)
+	}
+
+	return bytes.Join(lines, []byte("\n"))
+}
+
+// Write text over the start of a line, as a terminal would; by cells, so wide characters such as
+// CJK text cover two. A wide character left half-covered is replaced by a space
+func OverwriteCells(line string, text string) string {
+	width := CellWidth(text)
+
+	cells := 0
+	graphemes := uniseg.NewGraphemes(line)
+	for graphemes.Next() {
+		if cells >= width {
+			from, _ := graphemes.Positions()
+			return text + line[from:]
+		}
+
+		cells += GraphemeWidth(graphemes.Runes())
+		if cells > width {
+			_, to := graphemes.Positions()
+			return text + strings.Repeat(" ", cells-width) + line[to:]
+		}
+	}
+
+	return text
+}
+
+// Cut text to fit a number of cells, ending it with an ellipsis if it was cut. Wide characters
+// aren't split, so the text may fall a cell short
+func TruncateCells(text string, width int) string {
+	if CellWidth(text) <= width {
+		return text
+	}
+
+	cells := 0
+	graphemes := uniseg.NewGraphemes(text)
+	for graphemes.Next() {
+		cells += GraphemeWidth(graphemes.Runes())
+		if cells > width-1 {
+			from, _ := graphemes.Positions()
+			return text[:from] + "…"
+		}
+	}
+
+	return text
+}
+
+// The cells text occupies, measuring each grapheme cluster as tview does
+func CellWidth(text string) int {
+	cells := 0
+
+	graphemes := uniseg.NewGraphemes(text)
+	for graphemes.Next() {
+		cells += GraphemeWidth(graphemes.Runes())
+	}
+
+	return cells
+}
+
+// Fold each long traceback or error block in output, so only its first and last lines show. A block is
+// a line followed by indented lines, as python, node, java, and rust print stack traces; go's alternate
+// function lines with indented file lines, so a line starting another block straight after one continues it
+func FoldTraces(data []byte, head int, tail int) string {
+	text := strings.TrimSuffix(string(data), "\n")
+	lines := strings.Split(text, "\n")
+
+	indented := func(idx int) bool {
+		return idx < len(lines) && len(lines[idx]) > 0 && (lines[idx][0] == ' ' || lines[idx][0] == '\t')
+	}
+
+	starts := func(idx int) bool {
+		return idx < len(lines) && len(lines[idx]) > 0 && !indented(idx) && indented(idx+1)
+	}
+
+	kept := []string{}
+	folds := false
+
+	for idx := 0; idx < len(lines); {
+		if !starts(idx) {
+			kept = append(kept, lines[idx])
+			idx++
+			continue
+		}
+
+		end := idx + 1
+		for indented(end) || starts(end) {
+			end++
+		}
+
+		block := lines[idx:end]
+		idx = end
+
+		if len(block) <= head+tail+1 {
+			kept = append(kept, block...)
+			continue
+		}
+
+		folded := len(block) - head - tail
+		marker := fmt.Sprintf("[yellow]··· %d lines folded; press Enter to expand ···[reset]", folded)
+
+		kept = append(append(append(kept, block[:head]...), marker), block[len(block)-tail:]...)
+		folds = true
+	}
+
+	if !folds {
+		return string(data)
+	}
+
+	return strings.Join(kept, "\n") + "\n"
+}
+
+// Render binary output as a hexdump
+func HexDump(data []byte) string {
+	return tview.Escape(hex.Dump(data))
+}
+
+// Pretty-print and colourise JSON output, or leave it unchanged with a notice if it isn't JSON
+func PrettyJSON(data []byte) string {
+	var indented bytes.Buffer
+
+	if err := json.Indent(&indented, bytes.TrimSpace(data), "", "  "); err != nil {
+		return "[yellow]stdout is not valid JSON: " + tview.Escape(err.Error()) + "[reset]\n" + string(data)
+	}
+
+	return ColorizeJSON(indented.String()) + "\n"
+}
+
+// Colour the keys, strings, numbers, and literals of indented JSON
+func ColorizeJSON(text string) string {
+	var out strings.Builder
+
+	paint := func(color string, token string) {
+		out.WriteString("[" + color + "]" + tview.Escape(token) + "[-]")
+	}
+
+	for idx := 0; idx < len(text); {
+		char := text[idx]
+
+		switch {
+		case char == '"':
+			end := idx + 1
+			for end < len(text) && text[end] != '"' {
+				if text[end] == '\\' {
+					end += 1
+				}
+				end += 1
+			}
+
+			// include the closing quote, unless the string is unterminated
+			closing := end + 1
+			if closing > len(text) {
+				closing = len(text)
+			}
+
+			token := text[idx:closing]
+
+			// keys are followed by a colon
+			if strings.HasPrefix(text[closing:], ":") {
+				paint(JSON_KEY_COLOR, token)
+			} else {
+				paint(JSON_STRING_COLOR, token)
+			}
+
+			idx += len(token)
+		case char == '-' || (char >= '0' && char <= '9'):
+			end := idx + 1
+			for end < len(text) && strings.IndexByte("0123456789.eE+-", text[end]) >= 0 {
+				end += 1
+			}
+
+			paint(JSON_NUMBER_COLOR, text[idx:end])
+			idx = end
+		case strings.HasPrefix(text[idx:], "true"), strings.HasPrefix(text[idx:], "null"):
+			paint(JSON_LITERAL_COLOR, text[idx:idx+4])
+			idx += 4
+		case strings.HasPrefix(text[idx:], "false"):
+			paint(JSON_LITERAL_COLOR, text[idx:idx+5])
+			idx += 5
+		default:
+			out.WriteByte(char)
+			idx += 1
+		}
+	}
+
+	return out.String()
+}
+
+// Interleave several output streams into one view in arrival order, marking each line with its stream
+type CombinedView struct {
+	lock     sync.Mutex
+	view     *LogView
+	last     string
+	lineOpen bool
+}
+
+// Write one stream's output to the combined view
+type StreamWriter struct {
+	combined *CombinedView
+	prefix   string
+}
+
+func NewCombinedView(view *LogView) *CombinedView {
+	return &CombinedView{view: view}
+}
+
+// Construct a writer for a stream, whose lines are marked with the prefix
+func (combined *CombinedView) Stream(prefix string) *StreamWriter {
+	return &StreamWriter{combined, prefix}
+}
+
+// Forget any partially written line
+func (combined *CombinedView) Reset() {
+	combined.lock.Lock()
+	defer combined.lock.Unlock()
+
+	combined.last = ""
+	combined.lineOpen = false
+}
+
+func (writer *StreamWriter) Write(data []byte) (int, error) {
+	combined := writer.combined
+
+	combined.lock.Lock()
+	defer combined.lock.Unlock()
+
+	var out bytes.Buffer
+
+	// another stream left a line unfinished; start a fresh line rather than mixing them
+	if combined.lineOpen && combined.last != writer.prefix {
+		out.WriteString("\n")
+		combined.lineOpen = false
+	}
+
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+
+		if !combined.lineOpen {
+			out.WriteString(writer.prefix)
+		}
+
+		out.Write(line)
+		combined.lineOpen = line[len(line)-1] != '\n'
+	}
+
+	combined.last = writer.prefix
+
+	if _, err := combined.view.Write(out.Bytes()); err != nil {
+		return 0, err
+	}
+
+	return len(data), nil
+}
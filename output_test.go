@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCombinedView(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []struct{ prefix, text string }
+		want   string
+	}{
+		{
+			"Prefixes every line",
+			[]struct{ prefix, text string }{{"out| ", "a\nb\n"}},
+			"out| a\nout| b\n",
+		},
+		{
+			"Breaks lines left unfinished by another stream",
+			[]struct{ prefix, text string }{{"out| ", "a"}, {"err| ", "b\n"}, {"out| ", "c\n"}},
+			"out| a\nerr| b\nout| c\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			view := NewLogView()
+			combined := NewCombinedView(view)
+
+			for _, write := range tt.writes {
+				combined.Stream(write.prefix).Write([]byte(write.text))
+			}
+
+			if got := view.GetText(); got != tt.want {
+				t.Errorf("CombinedView = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFoldTraces(t *testing.T) {
+	marker := func(count int) string {
+		return fmt.Sprintf("[yellow]··· %d lines folded; press Enter to expand ···[reset]", count)
+	}
+
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			"Short output is unchanged",
+			"a\nb\nc\n",
+			"a\nb\nc\n",
+		},
+		{
+			"Long output without traces is unchanged",
+			"a\nb\nc\nd\ne\nf\n",
+			"a\nb\nc\nd\ne\nf\n",
+		},
+		{
+			"Folds a python traceback, keeping the exception",
+			"Traceback (most recent call last):\n  File \"a.py\", line 1\n    f()\n  File \"a.py\", line 2\n    g()\nValueError: bad\n",
+			"Traceback (most recent call last):\n" + marker(3) + "\n    g()\nValueError: bad\n",
+		},
+		{
+			"Folds each trace separately",
+			"Error: one\n    at a\n    at b\n    at c\nbetween\nError: two\n    at d\n    at e\n    at f\n",
+			"Error: one\n" + marker(2) + "\n    at c\nbetween\nError: two\n" + marker(2) + "\n    at f\n",
+		},
+		{
+			"Folds a go trace as one block",
+			"panic: boom\n\ngoroutine 1 [running]:\nmain.f()\n\t/a.go:3\nmain.main()\n\t/a.go:7\nexit status 2\n",
+			"panic: boom\n\ngoroutine 1 [running]:\nmain.f()\n" + marker(2) + "\n\t/a.go:7\nexit status 2\n",
+		},
+		{
+			"Leaves short traces unfolded",
+			"Error: one\n    at a\n    at b\n",
+			"Error: one\n    at a\n    at b\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FoldTraces([]byte(tt.data), 1, 1); got != tt.want {
+				t.Errorf("FoldTraces() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrettyJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			"Indents and colours JSON",
+			`{"a":[1,true]}`,
+			"{\n  [blue]\"a\"[-]: [\n    [yellow]1[-],\n    [purple]true[-]\n  ]\n}\n",
+		},
+		{
+			"Leaves invalid JSON unchanged",
+			"plain",
+			"[yellow]stdout is not valid JSON: invalid character 'p' looking for beginning of value[reset]\nplain",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PrettyJSON([]byte(tt.data)); got != tt.want {
+				t.Errorf("PrettyJSON() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCharsetWriter(t *testing.T) {
+	tests := []struct {
+		name    string
+		charset string
+		data    []string
+		want    string
+	}{
+		{
+			"Replaces invalid UTF-8",
+			"utf-8",
+			[]string{"ok \xff"},
+			"ok �",
+		},
+		{
+			"Keeps sequences split across writes",
+			"utf-8",
+			[]string{"caf\xc3", "\xa9"},
+			"café",
+		},
+		{
+			"Decodes latin-1",
+			"latin1",
+			[]string{"caf\xe9"},
+			"café",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+
+			writer, err := NewCharsetWriter(&out, tt.charset)
+			if err != nil {
+				t.Fatalf("NewCharsetWriter() error = %v", err)
+			}
+
+			for _, data := range tt.data {
+				writer.Write([]byte(data))
+			}
+			writer.Close()
+
+			if got := out.String(); got != tt.want {
+				t.Errorf("CharsetWriter = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyCarriageReturns(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			"Keeps CRLF line endings as newlines",
+			"a\r\nb\r\n",
+			"a\nb\n",
+		},
+		{
+			"Redraws a progress bar in place",
+			"10%\r50%\r100%\ndone\n",
+			"100%\ndone\n",
+		},
+		{
+			"Shorter updates overwrite the start of the line",
+			"abcdef\rXY",
+			"XYcdef",
+		},
+		{
+			"Wide characters are overwritten by cell",
+			"進捗 10%\r進捗 100%\r完了",
+			"完了 100%",
+		},
+		{
+			"Half-covered wide characters become spaces",
+			"漢字ab\rXYZ",
+			"XYZ ab",
+		},
+		{
+			"Emoji are overwritten by cell",
+			"⏳ waiting\r✔ done",
+			"✔ doneting",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(ApplyCarriageReturns([]byte(tt.data))); got != tt.want {
+				t.Errorf("ApplyCarriageReturns() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutputBufferBatching(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{
+			"Appends held output once flushed",
+			[]string{"a\n", "b\n"},
+			"a\nb\n",
+		},
+		{
+			"Re-renders when a carriage return rewrote a line",
+			[]string{"10%", "\r50%", "\r100%\n"},
+			"100%\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			view := NewLogView()
+			buffer := NewOutputBuffer(view)
+			buffer.SetBatched(true)
+
+			for _, write := range tt.writes {
+				buffer.Write([]byte(write))
+			}
+
+			if got := view.GetText(); strings.TrimSpace(got) != "" {
+				t.Errorf("view = %q before flushing, want it empty", got)
+			}
+
+			buffer.Flush()
+
+			if got := strings.TrimRight(view.GetText(), "\n"); got != strings.TrimRight(tt.want, "\n") {
+				t.Errorf("view = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutputBufferRecording(t *testing.T) {
+	view := NewLogView()
+	buffer := NewOutputBuffer(view)
+
+	// the view may be sent less than the program wrote, when its queue overflows
+	pipe := NewOutputPipe(buffer, 4)
+	writer := io.MultiWriter(buffer.Recorder(), pipe)
+
+	writer.Write([]byte("first\n"))
+	writer.Write([]byte("second\n"))
+	pipe.Close()
+
+	if got := string(buffer.Bytes()); got != "first\nsecond\n" {
+		t.Errorf("Bytes() = %q, want the complete output", got)
+	}
+
+	if got := view.GetText(); strings.Contains(got, "first") {
+		t.Errorf("view = %q, want output beyond the queue's limit dropped", got)
+	}
+
+	buffer.Clear()
+	if got := buffer.Bytes(); len(got) > 0 {
+		t.Errorf("Bytes() = %q after clearing, want nothing", got)
+	}
+}
+
+func TestOutputBufferCharset(t *testing.T) {
+	view := NewLogView()
+	buffer := NewOutputBuffer(view)
+	buffer.SetCharset("latin1")
+
+	raw := []byte("caf\xe9 \xff\n")
+	buffer.Recorder().Write(raw)
+	buffer.Write(raw)
+
+	if got := view.GetText(); !strings.Contains(got, "café ÿ") {
+		t.Errorf("view = %q, want the output decoded", got)
+	}
+
+	if got := buffer.Bytes(); !bytes.Equal(got, raw) {
+		t.Errorf("Bytes() = %q, want the output as written", got)
+	}
+
+	// the hexdump shows the bytes the program wrote, rather than their decoding
+	buffer.SetBinary(true)
+	buffer.SetTransform(StdoutTransform(STDOUT_HEX))
+	buffer.Refresh()
+
+	if got := view.GetText(); !strings.Contains(got, "63 61 66 e9 20 ff 0a") {
+		t.Errorf("hexdump = %q, want the original bytes", got)
+	}
+
+	buffer.SetBinary(false)
+	buffer.SetTransform(nil)
+	buffer.Refresh()
+
+	if got := view.GetText(); !strings.Contains(got, "café ÿ") {
+		t.Errorf("view = %q after leaving the hexdump, want the output decoded", got)
+	}
+}
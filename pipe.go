@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Pass a program's output to a writer through a bounded queue, written from its own goroutine. Writes
+// never wait for the destination, so a slow view never blocks the program reading its pipe. Output
+// that arrives while the queue is full is dropped, and a marker noting how much was dropped takes its place
+type OutputPipe struct {
+	lock     sync.Mutex
+	ready    *sync.Cond
+	queue    bytes.Buffer
+	limit    int
+	dropped  int64
+	dropping bool
+	closed   bool
+	dst      io.Writer
+	done     chan struct{}
+}
+
+func NewOutputPipe(dst io.Writer, limit int) *OutputPipe {
+	pipe := &OutputPipe{limit: limit, dst: dst, done: make(chan struct{})}
+	pipe.ready = sync.NewCond(&pipe.lock)
+
+	go pipe.drain()
+
+	return pipe
+}
+
+// Queue output, or drop it if the queue is full. Dropping continues until the queue has half
+// emptied, so the destination isn't sent fragments between markers
+func (pipe *OutputPipe) Write(data []byte) (int, error) {
+	pipe.lock.Lock()
+	defer pipe.lock.Unlock()
+
+	if (pipe.dropping && pipe.queue.Len() > pipe.limit/2) || pipe.queue.Len()+len(data) > pipe.limit {
+		pipe.dropping = true
+		pipe.dropped += int64(len(data))
+
+		return len(data), nil
+	}
+
+	pipe.endDropping()
+	pipe.queue.Write(data)
+	pipe.ready.Signal()
+
+	return len(data), nil
+}
+
+// Queue the marker for output dropped since the queue filled
+func (pipe *OutputPipe) endDropping() {
+	if !pipe.dropping {
+		return
+	}
+
+	pipe.queue.WriteString(fmt.Sprintf(OUTPUT_DROPPED_MARKER, FormatBytes(pipe.dropped)))
+	pipe.dropping = false
+	pipe.dropped = 0
+}
+
+// Write queued output to the destination until the pipe is closed and emptied
+func (pipe *OutputPipe) drain() {
+	defer close(pipe.done)
+
+	for {
+		pipe.lock.Lock()
+		for pipe.queue.Len() == 0 && !pipe.closed {
+			pipe.ready.Wait()
+		}
+
+		if pipe.queue.Len() == 0 {
+			pipe.lock.Unlock()
+			return
+		}
+
+		data := append([]byte{}, pipe.queue.Bytes()...)
+		pipe.queue.Reset()
+		pipe.lock.Unlock()
+
+		pipe.dst.Write(data)
+	}
+}
+
+// Note any dropped output, and wait for the queue to be written
+func (pipe *OutputPipe) Close() error {
+	pipe.lock.Lock()
+	pipe.endDropping()
+	pipe.closed = true
+	pipe.ready.Signal()
+	pipe.lock.Unlock()
+
+	<-pipe.done
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// A writer that waits to be released before accepting output, noting when a write begins
+type gatedWriter struct {
+	lock    sync.Mutex
+	entered chan struct{}
+	gate    chan struct{}
+	data    bytes.Buffer
+}
+
+func (writer *gatedWriter) Write(data []byte) (int, error) {
+	writer.entered <- struct{}{}
+	<-writer.gate
+
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	return writer.data.Write(data)
+}
+
+func TestOutputPipe(t *testing.T) {
+	dst := &gatedWriter{entered: make(chan struct{}, 10), gate: make(chan struct{})}
+	pipe := NewOutputPipe(dst, 10)
+
+	// the destination blocks on the first write, so the second is queued and the third overflows the queue
+	pipe.Write([]byte("first\n"))
+	<-dst.entered
+
+	pipe.Write([]byte("second\n"))
+	pipe.Write([]byte("dropped\n"))
+
+	close(dst.gate)
+	pipe.Close()
+
+	want := "first\nsecond\n" + fmt.Sprintf(OUTPUT_DROPPED_MARKER, FormatBytes(8))
+	if got := dst.data.String(); got != want {
+		t.Errorf("OutputPipe wrote %q, want %q", got, want)
+	}
+}
+
+func TestOutputPipeOrder(t *testing.T) {
+	var dst bytes.Buffer
+	pipe := NewOutputPipe(&dst, OUTPUT_QUEUE_BYTES)
+
+	want := ""
+	for idx := 0; idx < 1000; idx++ {
+		line := fmt.Sprintf("line %d\n", idx)
+		want += line
+		pipe.Write([]byte(line))
+	}
+	pipe.Close()
+
+	if dst.String() != want {
+		t.Errorf("OutputPipe reordered or lost output")
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// A configured pipeline step; a shell command with {file}, {files}, and {lang} placeholders, and
+// optionally where to write the problems it reports as SARIF
+type PipelineStep struct {
+	Name  string `json:"name"`
+	Run   string `json:"run"`
+	Sarif string `json:"sarif"`
+}
+
+// A command of a pipeline, the name of the step it runs, variables it adds to the environment,
+// and where to write its problems as SARIF, if anywhere. Commands run by an interpreter started
+// ahead of the run are already started
+type PipelineCommand struct {
+	Name      string
+	Cmd       *exec.Cmd
+	Env       []string
+	Sarif     string
+	OnSuccess func() error
+	Warm      *WarmProcess
+}
+
+// Expand a step into shell scripts; once per file if it uses {file}, or once for all files
+func StepScripts(step PipelineStep, lang string, files []string) []string {
+	quoted := []string{}
+	for _, fpath := range files {
+		quoted = append(quoted, ShellQuote(fpath))
+	}
+
+	values := map[string]string{
+		"files": strings.Join(quoted, " "),
+		"lang":  lang,
+	}
+
+	if !strings.Contains(step.Run, "{file}") {
+		return []string{ExpandTemplate(step.Run, values)}
+	}
+
+	scripts := []string{}
+	for _, fpath := range quoted {
+		values["file"] = fpath
+		scripts = append(scripts, ExpandTemplate(step.Run, values))
+	}
+
+	return scripts
+}
+
+// The commands to run against files, in order; the configured pipeline, or else the language
+// against each file, through the build server if there is one, building it first if the language is compiled,
+// or with an interpreter started ahead of the run if one is ready
+func Pipeline(args *ReplitArgs, files []string) []PipelineCommand {
+	commands := []PipelineCommand{}
+
+	if len(args.Config.Pipeline) == 0 {
+		for _, fpath := range files {
+			if args.BuildServer != nil {
+				cmd := WrapCommand(args, fpath, []string{"sh", "-c", args.BuildServer.Script(args.BuildServer.Run, args, fpath)})
+				cmd.Dir = args.Dpath
+
+				commands = append(commands, PipelineCommand{Name: args.Lang, Cmd: cmd})
+			} else if len(CompiledLanguage(args.Lang)) > 0 {
+				commands = append(commands, CompiledPipeline(args, fpath)...)
+			} else if warm := args.WarmPool.Take(fpath); warm != nil {
+				commands = append(commands, PipelineCommand{Name: args.Lang, Cmd: warm.Cmd, Warm: warm})
+			} else {
+				commands = append(commands, PipelineCommand{Name: args.Lang, Cmd: InstrumentedCommand(args, fpath)})
+			}
+		}
+
+		return commands
+	}
+
+	// sandboxes expose the file being edited; other files are visible through the watched directory
+	fpath := ""
+	if len(files) > 0 {
+		fpath = files[0]
+	}
+
+	for _, step := range args.Config.Pipeline {
+		for _, script := range StepScripts(step, args.Lang, files) {
+			commands = append(commands, PipelineCommand{Name: step.Name, Cmd: WrapCommand(args, fpath, []string{"sh", "-c", script}), Sarif: step.Sarif})
+		}
+	}
+
+	return commands
+}
+
+// List the files staged for commit, that were added, copied, or modified
+func StagedFiles(dpath string) ([]string, error) {
+	var stderr bytes.Buffer
+
+	cmd := exec.Command("git", "diff", "--cached", "--name-only", "--diff-filter=ACM", "-z")
+	cmd.Dir = dpath
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not list staged files: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	files := []string{}
+	for _, name := range strings.Split(string(out), "\x00") {
+		if len(name) > 0 {
+			files = append(files, name)
+		}
+	}
+
+	return files, nil
+}
+
+// Run the pipeline once against the staged files, as a git pre-commit hook
+func RunHook(args *ReplitArgs) int {
+	files, err := StagedFiles(args.Dpath)
+	if err != nil {
+		PrintCliError(err.Error(), "run --hook from within a git repository")
+		return EXIT_BAD_ARGS
+	}
+
+	if len(files) == 0 {
+		return 0
+	}
+
+	return RunHeadless(args, files)
+}
+
+// The problems a command's output reports, to stderr or found by the config's matchers
+func StepProblems(args *ReplitArgs, stdout []byte, stderr []byte) []Problem {
+	problems := []Problem{}
+	if parse := StderrParser(args.Lang); parse != nil {
+		problems = append(problems, parse(stderr)...)
+	}
+
+	return append(problems, MatchProblems(args.Config.Matchers, stdout, stderr)...)
+}
+
+// Run the pipeline once against files without the TUI, streaming its output to the terminal. Stops
+// at the first failing step, and returns its exit code. In GitHub Actions, the problems found are
+// printed as annotations
+func RunHeadless(args *ReplitArgs, files []string) int {
+	problems := []Problem{}
+	exitCode := 0
+
+	var allStdout, allStderr bytes.Buffer
+	started := time.Now()
+
+	for _, command := range Pipeline(args, files) {
+		var stdout, stderr bytes.Buffer
+
+		cmd := command.Cmd
+		cmd.Dir = args.Dpath
+		cmd.Stdout = io.MultiWriter(os.Stdout, &stdout)
+		cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+		cmd.Env = append(ChildEnv(args), command.Env...)
+		ConfigureProcess(cmd, args)
+
+		err := StartProcess(cmd)
+		if err == nil {
+			if err := PrioritizeProcess(cmd, args); err != nil {
+				println("replit: could not lower the priority of " + command.Name + ": " + err.Error())
+			}
+
+			err = cmd.Wait()
+		}
+
+		problems = append(problems, StepProblems(args, stdout.Bytes(), stderr.Bytes())...)
+
+		if len(command.Sarif) > 0 {
+			if err := WriteStepSARIF(args, command, stdout.Bytes(), stderr.Bytes()); err != nil {
+				println("replit: could not write " + command.Name + "'s SARIF: " + err.Error())
+			}
+		}
+
+		allStdout.Write(stdout.Bytes())
+		allStderr.Write(stderr.Bytes())
+
+		if err == nil && command.OnSuccess != nil {
+			if err := command.OnSuccess(); err != nil {
+				println("replit: could not finish " + command.Name + ": " + err.Error())
+			}
+		}
+
+		if err != nil {
+			exitCode = EXIT_HOOK_FAILED
+			if cmd.ProcessState != nil && cmd.ProcessState.ExitCode() > 0 {
+				exitCode = cmd.ProcessState.ExitCode()
+			}
+
+			println("replit: " + command.Name + " failed: " + err.Error())
+			break
+		}
+	}
+
+	problems = append(problems, CollectDiagnostics()...)
+
+	if len(args.JUnitPath) > 0 {
+		entry := HistoryEntry{
+			Run:    1,
+			Result: RunResult{StartedAt: started, Duration: time.Since(started), ExitCode: exitCode},
+			Stdout: allStdout.Bytes(),
+			Stderr: allStderr.Bytes(),
+		}
+
+		if err := WriteJUnitReport(args.JUnitPath, entry, filepath.Base(files[0])); err != nil {
+			println("replit: could not write JUnit report: " + err.Error())
+		}
+	}
+
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		root := os.Getenv("GITHUB_WORKSPACE")
+		if len(root) == 0 {
+			root = args.Dpath
+		}
+
+		for _, problem := range problems {
+			fmt.Println(GithubAnnotation(problem, root))
+		}
+	}
+
+	return exitCode
+}
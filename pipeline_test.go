@@ -0,0 +1,29 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStepScripts(t *testing.T) {
+	files := []string{"a.py", "b c.py"}
+
+	tests := []struct {
+		name string
+		run  string
+		want []string
+	}{
+		{"Runs once per file with {file}", "{lang} {file}", []string{"python3 a.py", "python3 'b c.py'"}},
+		{"Runs once with {files}", "ruff check {files}", []string{"ruff check a.py 'b c.py'"}},
+		{"Runs once without placeholders", "make test", []string{"make test"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := StepScripts(PipelineStep{Name: "step", Run: tt.run}, "python3", files)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("StepScripts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,431 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// A diagnostic from a compiler, runtime, or checker, and where it points. Problems parsed from stderr
+// note the line they were reported on, from one; others have no RawLine
+type Problem struct {
+	Source  string
+	File    string
+	Line    int
+	Column  int
+	Message string
+	RawLine int
+}
+
+// How severe a problem is; warnings are reported as warnings, and others as errors
+func ProblemLevel(problem Problem) string {
+	if strings.HasPrefix(strings.ToLower(problem.Message), "warning") {
+		return "warning"
+	}
+
+	return "error"
+}
+
+// A problem's file relative to root, with forward slashes, if it's within root
+func RelativeProblemFile(fpath string, root string) string {
+	if filepath.IsAbs(fpath) && len(root) > 0 {
+		if rel, err := filepath.Rel(root, fpath); err == nil && !strings.HasPrefix(rel, "..") {
+			fpath = rel
+		}
+	}
+
+	return filepath.ToSlash(fpath)
+}
+
+// "#1 0x4c3f2e in main /tmp/x.c:6:12" frames of sanitizer reports
+var sanitizerFramePattern = regexp.MustCompile(`^\s*#\d+ 0x[0-9a-f]+ in \S+ (\S+?):(\d+)(?::(\d+))?$`)
+
+// "==1234==ERROR: AddressSanitizer: heap-buffer-overflow on address ..." headlines
+var sanitizerErrorPattern = regexp.MustCompile(`^==\d+==ERROR: (\w+Sanitizer: .*?)(?: on address.*)?$`)
+
+// "/tmp/x.c:5:7: runtime error: signed integer overflow" reports from UBSan
+var ubsanPattern = regexp.MustCompile(`^(\S+?):(\d+):(\d+): runtime error: (.*)$`)
+
+// "==123== Invalid read of size 4" headlines and "==123==    at 0x109162: main (x.c:6)" frames from valgrind
+var valgrindLinePattern = regexp.MustCompile(`^==\d+== (\s*)(.*)$`)
+var valgrindFramePattern = regexp.MustCompile(`^(?:at|by) 0x[0-9A-F]+: .* \((\S+?):(\d+)\)$`)
+
+// Parse a line and column, treating missing or invalid numbers as zero
+func atoiOrZero(value string) int {
+	number, _ := strconv.Atoi(value)
+	return number
+}
+
+// Is a stack frame inside the sanitizer runtime, rather than the program?
+func isRuntimeFrame(fpath string) bool {
+	return strings.Contains(fpath, "sanitizer") || strings.Contains(fpath, "compiler-rt")
+}
+
+// Parse AddressSanitizer, LeakSanitizer, and UBSan reports; each points at its first frame in the program
+func ParseSanitizer(data []byte) []Problem {
+	problems := []Problem{}
+	pending := -1
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if match := ubsanPattern.FindStringSubmatch(line); match != nil {
+			problems = append(problems, Problem{Source: "ubsan", File: match[1], Line: atoiOrZero(match[2]), Column: atoiOrZero(match[3]), Message: match[4]})
+			continue
+		}
+
+		if match := sanitizerErrorPattern.FindStringSubmatch(line); match != nil {
+			problems = append(problems, Problem{Source: "asan", Message: match[1]})
+			pending = len(problems) - 1
+			continue
+		}
+
+		if match := sanitizerFramePattern.FindStringSubmatch(line); match != nil && pending >= 0 && !isRuntimeFrame(match[1]) {
+			problems[pending].File = match[1]
+			problems[pending].Line = atoiOrZero(match[2])
+			problems[pending].Column = atoiOrZero(match[3])
+			pending = -1
+		}
+	}
+
+	return problems
+}
+
+// Parse valgrind's error reports; each points at its first frame with a source location
+func ParseValgrind(data []byte) []Problem {
+	problems := []Problem{}
+	pending := -1
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		match := valgrindLinePattern.FindStringSubmatch(scanner.Text())
+		if match == nil || len(match[2]) == 0 {
+			continue
+		}
+
+		// unindented lines start a new error; indented lines are its stack
+		if len(match[1]) == 0 {
+			problems = append(problems, Problem{Source: "valgrind", Message: match[2]})
+			pending = len(problems) - 1
+			continue
+		}
+
+		if frame := valgrindFramePattern.FindStringSubmatch(match[2]); frame != nil && pending >= 0 {
+			problems[pending].File = frame[1]
+			problems[pending].Line = atoiOrZero(frame[2])
+			pending = -1
+		}
+	}
+
+	return problems
+}
+
+// Describe a problem in one line of the problems pane
+func ProblemLabel(problem Problem, dpath string) string {
+	location := problem.File
+	if rel, err := filepath.Rel(dpath, problem.File); err == nil && !strings.HasPrefix(rel, "..") {
+		location = rel
+	}
+
+	if problem.Line > 0 {
+		location += ":" + fmt.Sprint(problem.Line)
+	}
+
+	if problem.Column > 0 {
+		location += ":" + fmt.Sprint(problem.Column)
+	}
+
+	return fmt.Sprintf("[red]%s[reset] %s  %s", problem.Source, tview.Escape(location), tview.Escape(problem.Message))
+}
+
+// "  File "/tmp/x.py", line 3, in <module>" frames, and "ValueError: message" exceptions of python tracebacks
+var pythonFramePattern = regexp.MustCompile(`^\s+File "(.+)", line (\d+)`)
+var pythonErrorPattern = regexp.MustCompile(`^([A-Za-z_][\w.]*)(?:: (.*))?$`)
+
+// "./main.go:5:2: undefined: x" build errors, "panic: message" panics, and "\t/tmp/main.go:12 +0x1d" frames from go
+var goErrorPattern = regexp.MustCompile(`^(\S+\.go):(\d+)(?::(\d+))?: (.*)$`)
+var goPanicPattern = regexp.MustCompile(`^panic: (.*)$`)
+var goFramePattern = regexp.MustCompile(`^\t(\S+\.go):(\d+)`)
+
+// "error[E0425]: message" headlines and " --> src/main.rs:2:5" locations from rustc, and panics with either
+// "thread 'main' panicked at src/main.rs:2:5:" followed by the message, or "panicked at 'message', src/main.rs:2:5"
+var rustErrorPattern = regexp.MustCompile(`^(error|warning)(?:\[\w+\])?: (.*)$`)
+var rustLocationPattern = regexp.MustCompile(`^\s*--> (\S+?):(\d+):(\d+)$`)
+var rustPanicPattern = regexp.MustCompile(`^thread '.*' panicked at (\S+?):(\d+):(\d+):$`)
+var rustOldPanicPattern = regexp.MustCompile(`^thread '.*' panicked at '(.*)', (\S+?):(\d+):(\d+)$`)
+var rustSummaryPattern = regexp.MustCompile(`^(aborting due to|could not compile|\d+ warnings? emitted)`)
+
+// "/tmp/x.js:3" throw sites, "TypeError: message" errors, and "    at f (/tmp/x.js:3:9)" frames from node
+var nodeLocationPattern = regexp.MustCompile(`^(/.+?|[A-Za-z]:\\.+?):(\d+)$`)
+var nodeErrorPattern = regexp.MustCompile(`^([A-Z]\w*(?:Error|Exception)|Error)(?: \[\w+\])?: (.*)$`)
+var nodeFramePattern = regexp.MustCompile(`^\s+at (?:.*? \()?(.+?):(\d+):(\d+)\)?$`)
+
+// The built-in stderr parser for a language's program, or nil if there isn't one
+func StderrParser(lang string) func(data []byte) []Problem {
+	program, _ := SplitLanguage(lang)
+	program = filepath.Base(program)
+
+	switch {
+	case strings.HasPrefix(program, "python"):
+		return ParsePython
+	case program == "go":
+		return ParseGo
+	case program == "rustc" || program == "cargo":
+		return ParseRust
+	case program == "node" || program == "deno" || program == "bun" || program == "ts-node":
+		return ParseNode
+	}
+
+	return nil
+}
+
+// Parse python tracebacks and syntax errors; each points at the innermost frame. Problems note
+// the line of stderr they were reported on, counting from one
+func ParsePython(data []byte) []Problem {
+	problems := []Problem{}
+	var frame *Problem
+
+	for idx, line := range strings.Split(string(data), "\n") {
+		if match := pythonFramePattern.FindStringSubmatch(line); match != nil {
+			frame = &Problem{Source: "python", File: match[1], Line: atoiOrZero(match[2])}
+			continue
+		}
+
+		// the exception follows its frames, unindented
+		if frame == nil || strings.HasPrefix(line, " ") || strings.HasPrefix(line, "Traceback") {
+			continue
+		}
+
+		if match := pythonErrorPattern.FindStringSubmatch(line); match != nil {
+			frame.Message = strings.TrimSuffix(match[1]+": "+match[2], ": ")
+			frame.RawLine = idx + 1
+			problems = append(problems, *frame)
+			frame = nil
+		}
+	}
+
+	return problems
+}
+
+// Parse go build errors and panics; panics point at their first frame outside the runtime
+func ParseGo(data []byte) []Problem {
+	problems := []Problem{}
+	pending := -1
+
+	for idx, line := range strings.Split(string(data), "\n") {
+		if match := goErrorPattern.FindStringSubmatch(line); match != nil {
+			problems = append(problems, Problem{"go", match[1], atoiOrZero(match[2]), atoiOrZero(match[3]), match[4], idx + 1})
+			continue
+		}
+
+		if match := goPanicPattern.FindStringSubmatch(line); match != nil {
+			problems = append(problems, Problem{Source: "go", Message: "panic: " + match[1], RawLine: idx + 1})
+			pending = len(problems) - 1
+			continue
+		}
+
+		if match := goFramePattern.FindStringSubmatch(line); match != nil && pending >= 0 && !strings.Contains(match[1], "/runtime/") {
+			problems[pending].File = match[1]
+			problems[pending].Line = atoiOrZero(match[2])
+			pending = -1
+		}
+	}
+
+	return problems
+}
+
+// Parse rustc errors and warnings, and panics
+func ParseRust(data []byte) []Problem {
+	problems := []Problem{}
+	pending := -1
+	panicked := -1
+
+	for idx, line := range strings.Split(string(data), "\n") {
+		// the message follows the location of newer panics
+		if panicked >= 0 {
+			problems[panicked].Message = "panicked: " + line
+			panicked = -1
+			continue
+		}
+
+		if match := rustErrorPattern.FindStringSubmatch(line); match != nil && !rustSummaryPattern.MatchString(match[2]) {
+			problems = append(problems, Problem{Source: "rustc", Message: match[1] + ": " + match[2], RawLine: idx + 1})
+			pending = len(problems) - 1
+			continue
+		}
+
+		if match := rustLocationPattern.FindStringSubmatch(line); match != nil && pending >= 0 {
+			problems[pending].File = match[1]
+			problems[pending].Line = atoiOrZero(match[2])
+			problems[pending].Column = atoiOrZero(match[3])
+			pending = -1
+			continue
+		}
+
+		if match := rustPanicPattern.FindStringSubmatch(line); match != nil {
+			problems = append(problems, Problem{"rust", match[1], atoiOrZero(match[2]), atoiOrZero(match[3]), "panicked", idx + 1})
+			panicked = len(problems) - 1
+			continue
+		}
+
+		if match := rustOldPanicPattern.FindStringSubmatch(line); match != nil {
+			problems = append(problems, Problem{"rust", match[2], atoiOrZero(match[3]), atoiOrZero(match[4]), "panicked: " + match[1], idx + 1})
+		}
+	}
+
+	return problems
+}
+
+// Parse uncaught node errors; each points at its throw site, or else its first frame outside node's internals
+func ParseNode(data []byte) []Problem {
+	problems := []Problem{}
+	var location *Problem
+	pending := -1
+
+	for idx, line := range strings.Split(string(data), "\n") {
+		if match := nodeLocationPattern.FindStringSubmatch(line); match != nil {
+			location = &Problem{File: match[1], Line: atoiOrZero(match[2])}
+			continue
+		}
+
+		if match := nodeErrorPattern.FindStringSubmatch(line); match != nil {
+			problem := Problem{Source: "node", Message: match[1] + ": " + match[2], RawLine: idx + 1}
+
+			if location != nil {
+				problem.File, problem.Line = location.File, location.Line
+				location = nil
+				pending = -1
+			} else {
+				pending = len(problems)
+			}
+
+			problems = append(problems, problem)
+			continue
+		}
+
+		if match := nodeFramePattern.FindStringSubmatch(line); match != nil && pending >= 0 && !strings.HasPrefix(match[1], "node:") {
+			problems[pending].File = match[1]
+			problems[pending].Line = atoiOrZero(match[2])
+			problems[pending].Column = atoiOrZero(match[3])
+			pending = -1
+		}
+	}
+
+	return problems
+}
+
+// A project's own problem matcher, in the style of VSCode's; a regexp, and the groups holding each part of a problem
+type ProblemMatcher struct {
+	Owner   string         `json:"owner"`
+	Stream  string         `json:"stream"`
+	Pattern ProblemPattern `json:"pattern"`
+	pattern *regexp.Regexp
+}
+
+type ProblemPattern struct {
+	Regexp  string `json:"regexp"`
+	File    int    `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message int    `json:"message"`
+}
+
+// Compile configured problem matchers, checking their groups exist
+func CompileMatchers(matchers []ProblemMatcher) error {
+	for idx := range matchers {
+		matcher := &matchers[idx]
+
+		if len(matcher.Owner) == 0 {
+			matcher.Owner = "matcher"
+		}
+
+		if len(matcher.Stream) == 0 {
+			matcher.Stream = MATCH_STDERR
+		}
+
+		if matcher.Stream != MATCH_STDERR && matcher.Stream != MATCH_STDOUT && matcher.Stream != MATCH_BOTH {
+			return fmt.Errorf("problem matcher %s: unknown stream '%s'", matcher.Owner, matcher.Stream)
+		}
+
+		pattern, err := regexp.Compile(matcher.Pattern.Regexp)
+		if err != nil {
+			return fmt.Errorf("problem matcher %s: %v", matcher.Owner, err)
+		}
+
+		groups := pattern.NumSubexp()
+		for _, group := range []int{matcher.Pattern.File, matcher.Pattern.Line, matcher.Pattern.Column, matcher.Pattern.Message} {
+			if group < 0 || group > groups {
+				return fmt.Errorf("problem matcher %s: its regexp has no group %d", matcher.Owner, group)
+			}
+		}
+
+		if matcher.Pattern.Message == 0 {
+			return fmt.Errorf("problem matcher %s: set the group holding the message", matcher.Owner)
+		}
+
+		matcher.pattern = pattern
+	}
+
+	return nil
+}
+
+// The text of a group, or "" for group zero, which marks a part as absent
+func matchGroup(match []string, group int) string {
+	if group == 0 {
+		return ""
+	}
+
+	return match[group]
+}
+
+// Match a run's output line by line against compiled problem matchers. Only problems in stderr note their RawLine
+func MatchProblems(matchers []ProblemMatcher, stdout []byte, stderr []byte) []Problem {
+	problems := []Problem{}
+
+	for _, matcher := range matchers {
+		streams := map[string][]byte{}
+		if matcher.Stream != MATCH_STDERR {
+			streams[MATCH_STDOUT] = stdout
+		}
+		if matcher.Stream != MATCH_STDOUT {
+			streams[MATCH_STDERR] = stderr
+		}
+
+		for _, stream := range []string{MATCH_STDOUT, MATCH_STDERR} {
+			data, ok := streams[stream]
+			if !ok {
+				continue
+			}
+
+			for idx, line := range strings.Split(string(data), "\n") {
+				match := matcher.pattern.FindStringSubmatch(line)
+				if match == nil {
+					continue
+				}
+
+				problem := Problem{
+					Source:  matcher.Owner,
+					File:    matchGroup(match, matcher.Pattern.File),
+					Line:    atoiOrZero(matchGroup(match, matcher.Pattern.Line)),
+					Column:  atoiOrZero(matchGroup(match, matcher.Pattern.Column)),
+					Message: matchGroup(match, matcher.Pattern.Message),
+				}
+
+				if stream == MATCH_STDERR {
+					problem.RawLine = idx + 1
+				}
+
+				problems = append(problems, problem)
+			}
+		}
+	}
+
+	return problems
+}
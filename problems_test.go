@@ -0,0 +1,196 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSanitizer(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []Problem
+	}{
+		{
+			"Points AddressSanitizer reports at the first program frame",
+			`==25090==ERROR: AddressSanitizer: heap-buffer-overflow on address 0x602000000024 at pc 0x55d07206d277
+WRITE of size 4 at 0x602000000024 thread T0
+    #0 0x55d07206d276 in main /tmp/asan.c:2
+    #1 0x7fe0ac245249  (/lib/x86_64-linux-gnu/libc.so.6+0x27249)
+`,
+			[]Problem{{"asan", "/tmp/asan.c", 2, 0, "AddressSanitizer: heap-buffer-overflow", 0}},
+		},
+		{
+			"Skips sanitizer runtime frames",
+			`==1==ERROR: LeakSanitizer: detected memory leaks
+    #0 0x7fe0accb89cf in __interceptor_malloc ../../../../src/libsanitizer/asan/asan_malloc_linux.cpp:69
+    #1 0x55d07206d1eb in main /tmp/leak.c:4:13
+`,
+			[]Problem{{"asan", "/tmp/leak.c", 4, 13, "LeakSanitizer: detected memory leaks", 0}},
+		},
+		{
+			"Reads UBSan runtime errors",
+			"/tmp/ub.c:5:7: runtime error: signed integer overflow: 2147483647 + 1 cannot be represented in type 'int'\n",
+			[]Problem{{"ubsan", "/tmp/ub.c", 5, 7, "signed integer overflow: 2147483647 + 1 cannot be represented in type 'int'", 0}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseSanitizer([]byte(tt.data)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSanitizer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseValgrind(t *testing.T) {
+	data := `==123== Invalid read of size 4
+==123==    at 0x109162: main (x.c:6)
+==123==  Address 0x4a4f054 is 4 bytes after a block of size 16 alloc'd
+==123==    at 0x483B7F3: malloc (in /usr/lib/x86_64-linux-gnu/valgrind/vgpreload_memcheck-amd64-linux.so)
+==123==
+==123== Conditional jump or move depends on uninitialised value(s)
+==123==    by 0x109170: helper (x.c:12)
+`
+
+	want := []Problem{
+		{"valgrind", "x.c", 6, 0, "Invalid read of size 4", 0},
+		{"valgrind", "x.c", 12, 0, "Conditional jump or move depends on uninitialised value(s)", 0},
+	}
+
+	if got := ParseValgrind([]byte(data)); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseValgrind() = %v, want %v", got, want)
+	}
+}
+
+func TestStderrParsers(t *testing.T) {
+	tests := []struct {
+		name string
+		lang string
+		data string
+		want []Problem
+	}{
+		{
+			"Points python tracebacks at the innermost frame",
+			"python3",
+			`Traceback (most recent call last):
+  File "/tmp/pe.py", line 3, in <module>
+    f()
+  File "/tmp/pe.py", line 2, in f
+    raise ValueError("bad")
+ValueError: bad
+`,
+			[]Problem{{"python", "/tmp/pe.py", 2, 0, "ValueError: bad", 6}},
+		},
+		{
+			"Reads python syntax errors",
+			"python3 -u",
+			`  File "/tmp/ps.py", line 1
+    x = (
+        ^
+SyntaxError: '(' was never closed
+`,
+			[]Problem{{"python", "/tmp/ps.py", 1, 0, "SyntaxError: '(' was never closed", 4}},
+		},
+		{
+			"Reads go build errors",
+			"go run",
+			"# command-line-arguments\n./main.go:2:14: undefined: x\n",
+			[]Problem{{"go", "./main.go", 2, 14, "undefined: x", 2}},
+		},
+		{
+			"Points go panics at the first program frame",
+			"go run",
+			`panic: runtime error: index out of range [3] with length 0
+
+goroutine 1 [running]:
+runtime.panicIndex()
+	/usr/local/go/src/runtime/panic.go:115 +0x1d
+main.main()
+	/tmp/gp/main.go:2 +0x9
+exit status 2
+`,
+			[]Problem{{"go", "/tmp/gp/main.go", 2, 0, "panic: runtime error: index out of range [3] with length 0", 1}},
+		},
+		{
+			"Reads rustc errors, skipping their summary",
+			"rustc",
+			"error[E0425]: cannot find value `y` in this scope\n --> re.rs:1:12\n  |\n\nerror: aborting due to 1 previous error\n",
+			[]Problem{{"rustc", "re.rs", 1, 12, "error: cannot find value `y` in this scope", 1}},
+		},
+		{
+			"Reads rust panics",
+			"rustc",
+			"\nthread 'main' panicked at rp.rs:1:54:\nindex out of bounds: the len is 0 but the index is 3\n",
+			[]Problem{{"rust", "rp.rs", 1, 54, "panicked: index out of bounds: the len is 0 but the index is 3", 2}},
+		},
+		{
+			"Points node errors at their throw site",
+			"node",
+			`/tmp/ne.js:1
+function f(){ null.x }
+                   ^
+
+TypeError: Cannot read properties of null (reading 'x')
+    at f (/tmp/ne.js:1:20)
+    at Module._compile (node:internal/modules/cjs/loader:1521:14)
+`,
+			[]Problem{{"node", "/tmp/ne.js", 1, 0, "TypeError: Cannot read properties of null (reading 'x')", 5}},
+		},
+		{
+			"Points node errors without a throw site at their first frame",
+			"node",
+			"Error: failed\n    at Module._compile (node:internal/modules/cjs/loader:1521:14)\n    at main (/tmp/ne.js:4:9)\n",
+			[]Problem{{"node", "/tmp/ne.js", 4, 9, "Error: failed", 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StderrParser(tt.lang)([]byte(tt.data)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchProblems(t *testing.T) {
+	matchers := []ProblemMatcher{
+		{Owner: "lint", Pattern: ProblemPattern{Regexp: `^(\S+):(\d+): (.*)$`, File: 1, Line: 2, Message: 3}},
+		{Owner: "todo", Stream: MATCH_STDOUT, Pattern: ProblemPattern{Regexp: `^TODO (.*)$`, Message: 1}},
+	}
+
+	if err := CompileMatchers(matchers); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := "main.py:1: ignored on stdout\nTODO tidy up\n"
+	stderr := "checking\nmain.py:3: unused import\n"
+
+	want := []Problem{
+		{"lint", "main.py", 3, 0, "unused import", 2},
+		{"todo", "", 0, 0, "tidy up", 0},
+	}
+
+	if got := MatchProblems(matchers, []byte(stdout), []byte(stderr)); !reflect.DeepEqual(got, want) {
+		t.Errorf("MatchProblems() = %v, want %v", got, want)
+	}
+}
+
+func TestCompileMatchers(t *testing.T) {
+	tests := []struct {
+		name    string
+		matcher ProblemMatcher
+	}{
+		{"Rejects invalid regexps", ProblemMatcher{Pattern: ProblemPattern{Regexp: `(`, Message: 1}}},
+		{"Rejects missing groups", ProblemMatcher{Pattern: ProblemPattern{Regexp: `(.*)`, Message: 2}}},
+		{"Requires a message", ProblemMatcher{Pattern: ProblemPattern{Regexp: `(.*)`, File: 1}}},
+		{"Rejects unknown streams", ProblemMatcher{Stream: "stdin", Pattern: ProblemPattern{Regexp: `(.*)`, Message: 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CompileMatchers([]ProblemMatcher{tt.matcher}); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
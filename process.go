@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// The name of the signal that killed a process, or "" if it exited by itself
+func ProcessSignal(state *os.ProcessState) string {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return ""
+	}
+
+	names := map[syscall.Signal]string{
+		syscall.SIGKILL: "SIGKILL",
+		syscall.SIGTERM: "SIGTERM",
+		syscall.SIGINT:  "SIGINT",
+		syscall.SIGHUP:  "SIGHUP",
+		syscall.SIGSEGV: "SIGSEGV",
+		syscall.SIGABRT: "SIGABRT",
+		syscall.SIGBUS:  "SIGBUS",
+		syscall.SIGFPE:  "SIGFPE",
+		syscall.SIGPIPE: "SIGPIPE",
+	}
+
+	if name, ok := names[status.Signal()]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("signal %d", status.Signal())
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// Can the program be run without network access on this platform?
+func CheckNetworkIsolation() error {
+	return nil
+}
+
+// ioprio_set's target and class values, from linux/ioprio.h
+const ioprioWhoPgrp = 2
+const ioprioClassShift = 13
+const ioprioClassBestEffort = 2
+const ioprioClassIdle = 3
+
+// The lowest priority within the best-effort class
+const ioprioLowestLevel = 7
+
+// Check a disk priority class
+func ValidateIOPriority(class string) error {
+	if class != IONICE_IDLE && class != IONICE_BEST_EFFORT {
+		return fmt.Errorf("unknown class '%s'", class)
+	}
+
+	return nil
+}
+
+// Set the disk priority class of a process group
+func SetIOPriority(pgid int, class string) error {
+	prio := ioprioClassIdle << ioprioClassShift
+	if class == IONICE_BEST_EFFORT {
+		prio = ioprioClassBestEffort<<ioprioClassShift | ioprioLowestLevel
+	}
+
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoPgrp, uintptr(pgid), uintptr(prio)); errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+// Run the program in a fresh network namespace, with only a downed loopback device. Unprivileged
+// users need a user namespace to create one, so map the current user onto itself
+func IsolateNetwork(attr *syscall.SysProcAttr) {
+	attr.Cloneflags |= syscall.CLONE_NEWNET
+
+	if os.Geteuid() == 0 {
+		return
+	}
+
+	attr.Cloneflags |= syscall.CLONE_NEWUSER
+	attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Geteuid(), HostID: os.Geteuid(), Size: 1}}
+	attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getegid(), HostID: os.Getegid(), Size: 1}}
+}
@@ -0,0 +1,27 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import (
+	"errors"
+	"syscall"
+)
+
+// Can the program be run without network access on this platform?
+func CheckNetworkIsolation() error {
+	return errors.New("network isolation requires linux namespaces")
+}
+
+// Network namespaces are linux-only; arguments are checked before this is reached
+func IsolateNetwork(attr *syscall.SysProcAttr) {}
+
+// Check a disk priority class
+func ValidateIOPriority(class string) error {
+	return errors.New("disk priorities require linux's ioprio_set")
+}
+
+// Disk priorities are linux-only; arguments are checked before this is reached
+func SetIOPriority(pgid int, class string) error {
+	return nil
+}
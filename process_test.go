@@ -0,0 +1,65 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"os/exec"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestPrioritizeProcess(t *testing.T) {
+	cmd := exec.Command("sleep", "1")
+	ConfigureProcess(cmd, &ReplitArgs{})
+
+	if err := cmd.Start(); err != nil {
+		t.Skip("sleep is unavailable")
+	}
+	defer KillProcess(cmd)
+
+	if err := PrioritizeProcess(cmd, &ReplitArgs{Nice: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	// linux's getpriority returns 20 - nice, to avoid negative return values
+	prio, err := syscall.Getpriority(syscall.PRIO_PROCESS, cmd.Process.Pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if nice := 20 - prio; nice != 10 {
+		t.Errorf("expected niceness 10, but got %d", nice)
+	}
+}
+
+func TestUserCredentialForCurrentUser(t *testing.T) {
+	account, err := user.Current()
+	if err != nil {
+		t.Skip("the current user can't be looked up")
+	}
+
+	credential, err := UserCredential(account.Username)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if credential != nil {
+		t.Errorf("expected no credential to run as the current user, but got %+v", credential)
+	}
+
+	cmd := exec.Command("id", "-u")
+	ConfigureProcess(cmd, &ReplitArgs{Credential: credential})
+
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected to run as the current user, but got %v", err)
+	}
+
+	if uid := strings.TrimSpace(string(out)); uid != strconv.Itoa(syscall.Geteuid()) {
+		t.Errorf("expected to run as uid %d, but got %s", syscall.Geteuid(), uid)
+	}
+}
@@ -0,0 +1,122 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"runtime"
+	"strconv"
+	"syscall"
+)
+
+// The user and groups to run the program as
+type Credential = syscall.Credential
+
+// Start the command in its own process group, so it can be killed along with any children,
+// and as another user or without network access if requested
+func ConfigureProcess(cmd *exec.Cmd, args *ReplitArgs) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid:    true,
+		Credential: args.Credential,
+	}
+
+	if args.NoNetwork {
+		IsolateNetwork(cmd.SysProcAttr)
+	}
+}
+
+// Lower a started command's CPU and disk priority, if requested. The command leads its own
+// process group, so the whole group is reprioritised, including anything it already forked
+func PrioritizeProcess(cmd *exec.Cmd, args *ReplitArgs) error {
+	if cmd.Process == nil {
+		return nil
+	}
+
+	if args.Nice != 0 {
+		if err := syscall.Setpriority(syscall.PRIO_PGRP, cmd.Process.Pid, args.Nice); err != nil {
+			return err
+		}
+	}
+
+	if len(args.IONice) > 0 {
+		return SetIOPriority(cmd.Process.Pid, args.IONice)
+	}
+
+	return nil
+}
+
+// Look up the credentials to run the program as a named user
+func UserCredential(name string) (*Credential, error) {
+	account, err := user.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	uid, err := strconv.ParseUint(account.Uid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("user %s has a non-numeric uid %s", name, account.Uid)
+	}
+
+	gid, err := strconv.ParseUint(account.Gid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("user %s has a non-numeric gid %s", name, account.Gid)
+	}
+
+	// running as the current user needs no switch, and setting its groups would need privileges
+	if uint64(os.Geteuid()) == uid {
+		return nil, nil
+	}
+
+	// switching user requires privileges
+	if os.Geteuid() != 0 {
+		return nil, fmt.Errorf("running as %s requires replit to run as root", name)
+	}
+
+	groups := []uint32{}
+	if ids, err := account.GroupIds(); err == nil {
+		for _, id := range ids {
+			if group, err := strconv.ParseUint(id, 10, 32); err == nil {
+				groups = append(groups, uint32(group))
+			}
+		}
+	}
+
+	return &Credential{Uid: uint32(uid), Gid: uint32(gid), Groups: groups}, nil
+}
+
+// Kill a started command and every process in its group
+func KillProcess(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+
+	if err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != nil {
+		return cmd.Process.Kill()
+	}
+
+	return nil
+}
+
+// Start a command; ConfigureProcess already placed it in its own process group
+func StartProcess(cmd *exec.Cmd) error {
+	return cmd.Start()
+}
+
+// The peak resident memory of an exited process, in bytes
+func PeakMemory(state *os.ProcessState) int64 {
+	rusage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return 0
+	}
+
+	// darwin reports bytes, while linux and the BSDs report kilobytes
+	if runtime.GOOS == "darwin" {
+		return int64(rusage.Maxrss)
+	}
+
+	return int64(rusage.Maxrss) * 1024
+}
@@ -0,0 +1,246 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// The user and groups to run the program as; switching user isn't supported on windows
+type Credential struct {
+	Uid    uint32
+	Gid    uint32
+	Groups []uint32
+}
+
+var (
+	kernel32               = syscall.NewLazyDLL("kernel32.dll")
+	procCreateJobObject    = kernel32.NewProc("CreateJobObjectW")
+	procAssignProcessToJob = kernel32.NewProc("AssignProcessToJobObject")
+	procTerminateJobObject = kernel32.NewProc("TerminateJobObject")
+	procSetPriorityClass   = kernel32.NewProc("SetPriorityClass")
+	procThread32First      = kernel32.NewProc("Thread32First")
+	procThread32Next       = kernel32.NewProc("Thread32Next")
+	procOpenThread         = kernel32.NewProc("OpenThread")
+	procResumeThread       = kernel32.NewProc("ResumeThread")
+)
+
+// Process and thread access rights, creation flags, and SetPriorityClass's classes, from winnt.h
+// and winbase.h
+const (
+	processSetQuota          = 0x0100
+	threadSuspendResume      = 0x0002
+	createSuspended          = 0x0004
+	processSetInformation    = 0x0200
+	idlePriorityClass        = 0x0040
+	belowNormalPriorityClass = 0x4000
+	aboveNormalPriorityClass = 0x8000
+	highPriorityClass        = 0x0080
+)
+
+// The job object each running command was placed in, by pid
+var processJobs = struct {
+	sync.Mutex
+	jobs map[int]syscall.Handle
+}{jobs: map[int]syscall.Handle{}}
+
+// Start the command in its own process group, so console interrupts meant for replit don't reach it,
+// and under a pseudo console where windows has them, so it writes as it would to a terminal. Options
+// supported only elsewhere, such as --user and --no-network, are rejected before this is reached
+func ConfigureProcess(cmd *exec.Cmd, args *ReplitArgs) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+
+	// commands that weren't found are left to fail to start
+	self, err := os.Executable()
+	if err != nil || !filepath.IsAbs(cmd.Path) || !PseudoConsolesSupported() {
+		return
+	}
+
+	cmd.Args = append([]string{self, PSEUDO_CONSOLE_COMMAND, cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = self
+}
+
+// Start a command suspended, place it in a job object so it can be killed along with any children
+// it starts, then let it run. Windows has no process groups to do this, and a command started
+// before it's in its job could start children outside it
+func StartProcess(cmd *exec.Cmd) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= createSuspended
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	if err := containProcess(cmd.Process.Pid); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return fmt.Errorf("could not place the command in a job object: %v", err)
+	}
+
+	if err := resumeProcess(cmd.Process.Pid); err != nil {
+		KillProcess(cmd)
+		cmd.Wait()
+		return fmt.Errorf("could not resume the command: %v", err)
+	}
+
+	return nil
+}
+
+// Place a process in a new job object, released once the process exits
+func containProcess(pid int) error {
+	process, err := syscall.OpenProcess(processSetQuota|syscall.PROCESS_TERMINATE|syscall.SYNCHRONIZE, false, uint32(pid))
+	if err != nil {
+		return err
+	}
+
+	job, _, err := procCreateJobObject.Call(0, 0)
+	if job == 0 {
+		syscall.CloseHandle(process)
+		return err
+	}
+
+	if ok, _, err := procAssignProcessToJob.Call(job, uintptr(process)); ok == 0 {
+		syscall.CloseHandle(syscall.Handle(job))
+		syscall.CloseHandle(process)
+		return err
+	}
+
+	processJobs.Lock()
+	processJobs.jobs[pid] = syscall.Handle(job)
+	processJobs.Unlock()
+
+	go func() {
+		syscall.WaitForSingleObject(process, syscall.INFINITE)
+		syscall.CloseHandle(process)
+
+		processJobs.Lock()
+		delete(processJobs.jobs, pid)
+		syscall.CloseHandle(syscall.Handle(job))
+		processJobs.Unlock()
+	}()
+
+	return nil
+}
+
+// A thread's entry in a toolhelp snapshot, from tlhelp32.h
+type threadEntry32 struct {
+	Size           uint32
+	Usage          uint32
+	ThreadID       uint32
+	OwnerProcessID uint32
+	BasePriority   int32
+	DeltaPriority  int32
+	Flags          uint32
+}
+
+// Resume a process started suspended. Windows has no call to resume a process, so each of its
+// threads is resumed; a suspended process has only its main thread
+func resumeProcess(pid int) error {
+	snapshot, err := syscall.CreateToolhelp32Snapshot(syscall.TH32CS_SNAPTHREAD, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.CloseHandle(snapshot)
+
+	entry := threadEntry32{}
+	entry.Size = uint32(unsafe.Sizeof(entry))
+
+	resumed := false
+	ok, _, err := procThread32First.Call(uintptr(snapshot), uintptr(unsafe.Pointer(&entry)))
+	for ok != 0 {
+		if entry.OwnerProcessID == uint32(pid) {
+			thread, _, err := procOpenThread.Call(threadSuspendResume, 0, uintptr(entry.ThreadID))
+			if thread == 0 {
+				return err
+			}
+
+			count, _, err := procResumeThread.Call(thread)
+			syscall.CloseHandle(syscall.Handle(thread))
+			if int32(count) == -1 {
+				return err
+			}
+
+			resumed = true
+		}
+
+		ok, _, err = procThread32Next.Call(uintptr(snapshot), uintptr(unsafe.Pointer(&entry)))
+	}
+
+	if !resumed {
+		return fmt.Errorf("no threads found for process %d: %v", pid, err)
+	}
+
+	return nil
+}
+
+// The priority class nearest a niceness; windows has a few classes, rather than a range
+func PriorityClass(nice int) uintptr {
+	switch {
+	case nice >= 10:
+		return idlePriorityClass
+	case nice > 0:
+		return belowNormalPriorityClass
+	case nice <= -10:
+		return highPriorityClass
+	default:
+		return aboveNormalPriorityClass
+	}
+}
+
+// Set a started command's priority class from --nice. Disk priorities are linux-only, and
+// rejected before this is reached
+func PrioritizeProcess(cmd *exec.Cmd, args *ReplitArgs) error {
+	if cmd.Process == nil || args.Nice == 0 {
+		return nil
+	}
+
+	process, err := syscall.OpenProcess(processSetInformation, false, uint32(cmd.Process.Pid))
+	if err != nil {
+		return err
+	}
+	defer syscall.CloseHandle(process)
+
+	if ok, _, err := procSetPriorityClass.Call(uintptr(process), PriorityClass(args.Nice)); ok == 0 {
+		return err
+	}
+
+	return nil
+}
+
+// Running as another user isn't supported on windows
+func UserCredential(name string) (*Credential, error) {
+	return nil, errors.New("running as another user isn't supported on windows")
+}
+
+// Kill a started command and every process in its job
+func KillProcess(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+
+	processJobs.Lock()
+	defer processJobs.Unlock()
+
+	if job, ok := processJobs.jobs[cmd.Process.Pid]; ok {
+		if done, _, _ := procTerminateJobObject.Call(uintptr(job), 1); done != 0 {
+			return nil
+		}
+	}
+
+	return cmd.Process.Kill()
+}
+
+// Windows' process state has no peak memory
+func PeakMemory(state *os.ProcessState) int64 {
+	return 0
+}
@@ -0,0 +1,24 @@
+//go:build windows
+// +build windows
+
+package main
+
+import "testing"
+
+func TestPriorityClass(t *testing.T) {
+	tests := []struct {
+		nice int
+		want uintptr
+	}{
+		{19, idlePriorityClass},
+		{10, idlePriorityClass},
+		{5, belowNormalPriorityClass},
+		{-5, aboveNormalPriorityClass},
+		{-20, highPriorityClass},
+	}
+	for _, tt := range tests {
+		if got := PriorityClass(tt.nice); got != tt.want {
+			t.Errorf("PriorityClass(%d) = %#x, want %#x", tt.nice, got, tt.want)
+		}
+	}
+}
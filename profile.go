@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A function, and the share of samples spent in it
+type ProfileEntry struct {
+	Function string
+	Percent  float64
+}
+
+// perf report's "  12.34%  python3  libc.so  [.] memcpy" rows
+var perfReportPattern = regexp.MustCompile(`^\s*([\d.]+)%.*\[[.k]\]\s+(.+?)\s*$`)
+
+// Check a profiler is supported and installed
+func ValidateProfiler(profiler string) error {
+	switch profiler {
+	case PROFILER_PY_SPY, PROFILER_PERF:
+	default:
+		return fmt.Errorf("unknown profiler '%s'; expected %s or %s", profiler, PROFILER_PY_SPY, PROFILER_PERF)
+	}
+
+	if !CommandExists(profiler) {
+		return errors.New(profiler + " is not in PATH")
+	}
+
+	return nil
+}
+
+// Wrap a command line in a profiler that records samples to a file
+func ProfileCommand(profiler string, output string, argv []string) []string {
+	switch profiler {
+	case PROFILER_PY_SPY:
+		return append([]string{"py-spy", "record", "--format", "raw", "--output", output, "--"}, argv...)
+	case PROFILER_PERF:
+		return append([]string{"perf", "record", "--quiet", "-g", "-o", output, "--"}, argv...)
+	}
+
+	return argv
+}
+
+// Rank functions by the samples spent in them, from py-spy's collapsed stacks
+func ParseCollapsedStacks(data []byte) []ProfileEntry {
+	samples := map[string]int{}
+	total := 0
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		sep := strings.LastIndex(line, " ")
+		if sep < 0 {
+			continue
+		}
+
+		count, err := strconv.Atoi(line[sep+1:])
+		if err != nil {
+			continue
+		}
+
+		// time is attributed to the innermost frame
+		frames := strings.Split(line[:sep], ";")
+		samples[frames[len(frames)-1]] += count
+		total += count
+	}
+
+	entries := []ProfileEntry{}
+	for function, count := range samples {
+		entries = append(entries, ProfileEntry{function, 100 * float64(count) / float64(total)})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Percent == entries[j].Percent {
+			return entries[i].Function < entries[j].Function
+		}
+
+		return entries[i].Percent > entries[j].Percent
+	})
+
+	return entries
+}
+
+// Rank functions by the samples spent in them, from perf report's output
+func ParsePerfReport(data []byte) []ProfileEntry {
+	entries := []ProfileEntry{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		match := perfReportPattern.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+
+		if percent, err := strconv.ParseFloat(match[1], 64); err == nil {
+			entries = append(entries, ProfileEntry{match[2], percent})
+		}
+	}
+
+	return entries
+}
+
+// Summarise the samples a profiler recorded, busiest functions first
+func ProfileSummary(profiler string, output string) ([]ProfileEntry, error) {
+	switch profiler {
+	case PROFILER_PY_SPY:
+		data, err := ioutil.ReadFile(output)
+		if err != nil {
+			return nil, err
+		}
+
+		return ParseCollapsedStacks(data), nil
+	case PROFILER_PERF:
+		data, err := exec.Command("perf", "report", "--stdio", "--no-children", "--sort", "symbol", "-i", output).Output()
+		if err != nil {
+			return nil, fmt.Errorf("perf report failed: %v", err)
+		}
+
+		return ParsePerfReport(data), nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCollapsedStacks(t *testing.T) {
+	data := "main;work;inner 6\nmain;work 2\nmain;other;inner 2\n"
+
+	want := []ProfileEntry{{"inner", 80}, {"work", 20}}
+	if got := ParseCollapsedStacks([]byte(data)); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseCollapsedStacks() = %v, want %v", got, want)
+	}
+}
+
+func TestParsePerfReport(t *testing.T) {
+	data := "# Samples: 1K\n#\n    42.50%  python3  libc.so.6  [.] memcpy\n     7.25%  python3  [kernel]   [k] clear_page\n            |\n"
+
+	want := []ProfileEntry{{"memcpy", 42.5}, {"clear_page", 7.25}}
+	if got := ParsePerfReport([]byte(data)); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParsePerfReport() = %v, want %v", got, want)
+	}
+}
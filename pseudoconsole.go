@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// States of a pseudo console filter, part way through an escape sequence
+const (
+	consoleText = iota
+	consoleEscape
+	consoleCharset
+	consoleCSI
+	consoleOSC
+	consoleOSCEscape
+)
+
+// A writer passing on what a pseudo console draws as plain output. Windows' pseudo consoles draw
+// programs' output as a terminal would, moving the cursor, setting the title and skipping over
+// blank cells; colours are kept, cursor moves forward become spaces, other sequences are dropped,
+// and its CRLF line endings become newlines. Sequences may be split between writes.
+//
+// The console repaints its screen by moving the cursor back over cells already passed on and drawing
+// them again, so only cells beyond those drawn so far are passed on; moves down to new lines become
+// newlines, and erasing is ignored. A program that moves the cursor up to rewrite its output, rather
+// than using carriage returns, keeps the first version of those lines. Once the output fills the
+// console's PSEUDO_CONSOLE_ROWS, it scrolls, and new lines are drawn on its last row
+type PseudoConsoleFilter struct {
+	out      io.Writer
+	state    int
+	params   []byte
+	carriage bool
+	// the cursor's position, the last row drawn on, and how many of its columns were passed on
+	row     int
+	column  int
+	bottom  int
+	written int
+	passing bool
+}
+
+func NewPseudoConsoleFilter(out io.Writer) *PseudoConsoleFilter {
+	return &PseudoConsoleFilter{out: out}
+}
+
+// Move the cursor to the start of the next row. Leaving the last row drawn on starts a new line,
+// scrolling once the console is full
+func (filter *PseudoConsoleFilter) newline(text *bytes.Buffer) {
+	filter.column = 0
+
+	if filter.row < filter.bottom {
+		filter.row++
+		return
+	}
+
+	text.WriteByte('\n')
+	filter.written = 0
+
+	if filter.bottom < PSEUDO_CONSOLE_ROWS-1 {
+		filter.bottom++
+	}
+	filter.row = filter.bottom
+}
+
+// Pass on blank cells up to a column, if the cursor moved beyond those passed on
+func (filter *PseudoConsoleFilter) pad(column int, text *bytes.Buffer) {
+	if filter.row == filter.bottom && column > filter.written {
+		text.Write(bytes.Repeat([]byte{' '}, column-filter.written))
+		filter.written = column
+	}
+
+	filter.column = column
+}
+
+// Move the cursor to a row and column, counted from one
+func (filter *PseudoConsoleFilter) moveTo(row int, column int, text *bytes.Buffer) {
+	if row > PSEUDO_CONSOLE_ROWS {
+		row = PSEUDO_CONSOLE_ROWS
+	}
+
+	if row-1 < filter.bottom {
+		filter.row = row - 1
+	} else {
+		filter.row = filter.bottom
+		for filter.row < row-1 {
+			filter.newline(text)
+		}
+	}
+
+	filter.column = 0
+	filter.pad(column-1, text)
+}
+
+// The numeric parameters of a control sequence, defaulting to one
+func sequenceParams(params []byte, count int) []int {
+	values := make([]int, count)
+	parts := strings.Split(string(params), ";")
+
+	for idx := range values {
+		values[idx] = 1
+		if idx < len(parts) {
+			if value, err := strconv.Atoi(parts[idx]); err == nil && value > 0 {
+				values[idx] = value
+			}
+		}
+	}
+
+	return values
+}
+
+func (filter *PseudoConsoleFilter) Write(data []byte) (int, error) {
+	var text bytes.Buffer
+
+	for _, char := range data {
+		// a lone carriage return on the last row is passed on, and the line is drawn again from its start
+		if filter.carriage && char != '\n' {
+			if filter.row == filter.bottom {
+				text.WriteByte('\r')
+				filter.written = 0
+			}
+			filter.column = 0
+		}
+		filter.carriage = false
+
+		switch filter.state {
+		case consoleText:
+			if char == '\r' {
+				filter.carriage = true
+			} else if char == '\n' {
+				filter.newline(&text)
+			} else if char == '\x1b' {
+				filter.state = consoleEscape
+			} else if char&0xc0 == 0x80 {
+				// continuation bytes follow the start of their character
+				if filter.passing {
+					text.WriteByte(char)
+				}
+			} else {
+				filter.passing = filter.row == filter.bottom && filter.column >= filter.written
+				if filter.passing {
+					text.WriteByte(char)
+					filter.written = filter.column + 1
+				}
+				filter.column++
+			}
+		case consoleEscape:
+			switch char {
+			case '[':
+				filter.state = consoleCSI
+				filter.params = filter.params[:0]
+			case ']':
+				filter.state = consoleOSC
+			case '(', ')':
+				filter.state = consoleCharset
+			default:
+				filter.state = consoleText
+			}
+		case consoleCharset:
+			filter.state = consoleText
+		case consoleCSI:
+			if char >= 0x20 && char <= 0x3f {
+				filter.params = append(filter.params, char)
+				continue
+			}
+
+			switch char {
+			case 'm':
+				text.WriteString("\x1b[")
+				text.Write(filter.params)
+				text.WriteByte('m')
+			case 'C':
+				filter.pad(filter.column+sequenceParams(filter.params, 1)[0], &text)
+			case 'H':
+				position := sequenceParams(filter.params, 2)
+				filter.moveTo(position[0], position[1], &text)
+			}
+			filter.state = consoleText
+		case consoleOSC:
+			if char == '\a' {
+				filter.state = consoleText
+			} else if char == '\x1b' {
+				filter.state = consoleOSCEscape
+			}
+		case consoleOSCEscape:
+			if char == '\\' {
+				filter.state = consoleText
+			} else {
+				filter.state = consoleOSC
+			}
+		}
+	}
+
+	if _, err := filter.out.Write(text.Bytes()); err != nil {
+		return 0, err
+	}
+
+	return len(data), nil
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// Pseudo consoles are windows-only; elsewhere, commands run without replit in between
+func RunPseudoConsole(argv []string) int {
+	fmt.Fprintln(os.Stderr, "replit: pseudo consoles are only used on windows")
+	return EXIT_BAD_ARGS
+}
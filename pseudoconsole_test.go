@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestPseudoConsoleFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{"Passes plain text", []string{"hello\n"}, "hello\n"},
+		{"Turns CRLF into newlines", []string{"a\r\nb\r\n"}, "a\nb\n"},
+		{"Keeps lone carriage returns", []string{"10%\r20%\r\n"}, "10%\r20%\n"},
+		{"Turns CRLF split between writes into newlines", []string{"a\r", "\nb"}, "a\nb"},
+		{"Keeps colours", []string{"\x1b[31mred\x1b[0m"}, "\x1b[31mred\x1b[0m"},
+		{"Turns cursor moves forward into spaces", []string{"a\x1b[3Cb\x1b[Cc"}, "a   b c"},
+		{"Drops cursor and mode sequences", []string{"\x1b[?25l\x1b[2J\x1b[H\x1b[1;1Htext\x1b[K\x1b[?25h"}, "text"},
+		{"Drops titles", []string{"\x1b]0;C:\\cmd.exe\atext\x1b]0;x\x1b\\"}, "text"},
+		{"Drops character set sequences", []string{"\x1b(Btext\x1b7"}, "text"},
+		{"Handles sequences split between writes", []string{"a\x1b", "[3", "1mb\x1b]0", ";title\x07c"}, "a\x1b[31mbc"},
+		{"Turns cursor moves down into newlines", []string{"a\x1b[3;3Hb"}, "a\n\n  b"},
+		{"Pads cursor moves along the line", []string{"ab\x1b[1;5Hc"}, "ab  c"},
+		{"Drops repaints of lines already passed on", []string{"one\r\ntwo\r\nth", "\x1b[2J\x1b[Hone\x1b[K\r\ntwo\r\nthree\r\n"}, "one\ntwo\nthree\n"},
+		{"Drops repaints after moving back to the last line", []string{"one\r\ntwo", "\x1b[1;1Hone\x1b[2;4H!\r\n"}, "one\ntwo!\n"},
+		{"Passes carriage returns on the last line", []string{"\x1b[H10%\r20%"}, "10%\r20%"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			filter := NewPseudoConsoleFilter(&out)
+
+			for _, write := range tt.writes {
+				if n, err := filter.Write([]byte(write)); err != nil || n != len(write) {
+					t.Fatalf("Write() = %d, %v", n, err)
+				}
+			}
+
+			if got := out.String(); got != tt.want {
+				t.Errorf("PseudoConsoleFilter wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPseudoConsoleFilterScrolling(t *testing.T) {
+	var out, want bytes.Buffer
+	filter := NewPseudoConsoleFilter(&out)
+
+	// once the console is full, new lines are drawn on its last row; repaints of its screen are dropped
+	for line := 0; line < PSEUDO_CONSOLE_ROWS*2; line++ {
+		fmt.Fprintf(filter, "line %d\r\n", line)
+		fmt.Fprintf(&want, "line %d\n", line)
+	}
+
+	filter.Write([]byte("\x1b[H"))
+	for line := PSEUDO_CONSOLE_ROWS + 1; line < PSEUDO_CONSOLE_ROWS*2; line++ {
+		fmt.Fprintf(filter, "line %d\x1b[K\r\n", line)
+	}
+	filter.Write([]byte("last\r\n"))
+	want.WriteString("last\n")
+
+	if got := out.String(); got != want.String() {
+		t.Errorf("PseudoConsoleFilter wrote %q, want %q", got, want.String())
+	}
+}
@@ -0,0 +1,182 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	procCreatePseudoConsole               = kernel32.NewProc("CreatePseudoConsole")
+	procClosePseudoConsole                = kernel32.NewProc("ClosePseudoConsole")
+	procInitializeProcThreadAttributeList = kernel32.NewProc("InitializeProcThreadAttributeList")
+	procUpdateProcThreadAttribute         = kernel32.NewProc("UpdateProcThreadAttribute")
+	procDeleteProcThreadAttributeList     = kernel32.NewProc("DeleteProcThreadAttributeList")
+)
+
+// The pseudo console and inherited handle process attributes, and extended startup flag, from winbase.h
+const (
+	procThreadAttributePseudoConsole = 0x00020016
+	procThreadAttributeHandleList    = 0x00020002
+	extendedStartupInfoPresent       = 0x00080000
+)
+
+// Startup information with an attribute list, from winbase.h
+type startupInfoEx struct {
+	syscall.StartupInfo
+	attributeList *byte
+}
+
+// Whether windows can run commands under pseudo consoles; they were added in windows 10 1809
+func PseudoConsolesSupported() bool {
+	return procCreatePseudoConsole.Find() == nil
+}
+
+// Run a command under a pseudo console, so it writes to it as it would to a terminal, copying what it
+// draws to standard output and standard input to it. Replit runs commands through itself this way,
+// since windows has no other way to make a command see a terminal. The command's standard error is a
+// pipe of its own, copied to standard error, so only its standard output is drawn to the console. Exits
+// as the command did
+func RunPseudoConsole(argv []string) int {
+	if len(argv) == 0 {
+		fmt.Fprintln(os.Stderr, "replit: no command to run under a pseudo console")
+		return EXIT_BAD_ARGS
+	}
+
+	code, err := runPseudoConsole(argv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "replit: could not run %s under a pseudo console: %v\n", argv[0], err)
+		return EXIT_BAD_ARGS
+	}
+
+	return int(code)
+}
+
+func runPseudoConsole(argv []string) (uint32, error) {
+	var inputRead, inputWrite, outputRead, outputWrite syscall.Handle
+
+	if err := syscall.CreatePipe(&inputRead, &inputWrite, nil, 0); err != nil {
+		return 0, err
+	}
+	if err := syscall.CreatePipe(&outputRead, &outputWrite, nil, 0); err != nil {
+		return 0, err
+	}
+
+	// the command inherits the write end of its standard error pipe, and nothing else
+	var errorRead, errorWrite syscall.Handle
+	inherit := syscall.SecurityAttributes{InheritHandle: 1}
+	inherit.Length = uint32(unsafe.Sizeof(inherit))
+
+	if err := syscall.CreatePipe(&errorRead, &errorWrite, &inherit, 0); err != nil {
+		return 0, err
+	}
+	if err := syscall.SetHandleInformation(errorRead, syscall.HANDLE_FLAG_INHERIT, 0); err != nil {
+		return 0, err
+	}
+
+	// a console's size is passed as a COORD by value, packed into one word
+	var console syscall.Handle
+	size := uintptr(PSEUDO_CONSOLE_COLUMNS) | uintptr(PSEUDO_CONSOLE_ROWS)<<16
+
+	if result, _, _ := procCreatePseudoConsole.Call(size, uintptr(inputRead), uintptr(outputWrite), 0, uintptr(unsafe.Pointer(&console))); result != 0 {
+		return 0, syscall.Errno(result)
+	}
+
+	// the console holds its own copies of its ends of the pipes
+	syscall.CloseHandle(inputRead)
+	syscall.CloseHandle(outputWrite)
+
+	output := os.NewFile(uintptr(outputRead), "pseudo console output")
+	errorOutput := os.NewFile(uintptr(errorRead), "pseudo console errors")
+	input := os.NewFile(uintptr(inputWrite), "pseudo console input")
+	defer input.Close()
+
+	copied := make(chan struct{})
+	go func() {
+		io.Copy(NewPseudoConsoleFilter(os.Stdout), output)
+		output.Close()
+		close(copied)
+	}()
+
+	copiedErrors := make(chan struct{})
+	go func() {
+		io.Copy(os.Stderr, errorOutput)
+		errorOutput.Close()
+		close(copiedErrors)
+	}()
+	go io.Copy(input, os.Stdin)
+
+	code, err := startPseudoConsoleProcess(console, errorWrite, argv)
+	syscall.CloseHandle(errorWrite)
+
+	// closing the console draws what's left of the command's output, then ends it
+	procClosePseudoConsole.Call(uintptr(console))
+	<-copied
+	<-copiedErrors
+
+	return code, err
+}
+
+// Start a process attached to a pseudo console, writing its standard error to a handle, and wait for
+// its exit code
+func startPseudoConsoleProcess(console syscall.Handle, stderr syscall.Handle, argv []string) (uint32, error) {
+	var listSize uintptr
+	procInitializeProcThreadAttributeList.Call(0, 2, 0, uintptr(unsafe.Pointer(&listSize)))
+
+	list := make([]byte, listSize)
+	if ok, _, err := procInitializeProcThreadAttributeList.Call(uintptr(unsafe.Pointer(&list[0])), 2, 0, uintptr(unsafe.Pointer(&listSize))); ok == 0 {
+		return 0, err
+	}
+	defer procDeleteProcThreadAttributeList.Call(uintptr(unsafe.Pointer(&list[0])))
+
+	if ok, _, err := procUpdateProcThreadAttribute.Call(uintptr(unsafe.Pointer(&list[0])), 0, procThreadAttributePseudoConsole, uintptr(console), unsafe.Sizeof(console), 0, 0); ok == 0 {
+		return 0, err
+	}
+
+	// only the standard error handle is inherited, however many inheritable handles replit holds
+	inherited := []syscall.Handle{stderr}
+	if ok, _, err := procUpdateProcThreadAttribute.Call(uintptr(unsafe.Pointer(&list[0])), 0, procThreadAttributeHandleList, uintptr(unsafe.Pointer(&inherited[0])), unsafe.Sizeof(stderr), 0, 0); ok == 0 {
+		return 0, err
+	}
+
+	quoted := make([]string, len(argv))
+	for idx, arg := range argv {
+		quoted[idx] = syscall.EscapeArg(arg)
+	}
+
+	commandLine, err := syscall.UTF16PtrFromString(strings.Join(quoted, " "))
+	if err != nil {
+		return 0, err
+	}
+
+	// standard handles left empty are given the pseudo console's, so only standard error is redirected
+	info := startupInfoEx{attributeList: &list[0]}
+	info.Cb = uint32(unsafe.Sizeof(info))
+	info.Flags = syscall.STARTF_USESTDHANDLES
+	info.StdErr = stderr
+
+	var process syscall.ProcessInformation
+	err = syscall.CreateProcess(nil, commandLine, nil, nil, true, extendedStartupInfoPresent|syscall.CREATE_UNICODE_ENVIRONMENT, nil, nil, &info.StartupInfo, &process)
+	if err != nil {
+		return 0, err
+	}
+	defer syscall.CloseHandle(process.Process)
+	defer syscall.CloseHandle(process.Thread)
+
+	if _, err := syscall.WaitForSingleObject(process.Process, syscall.INFINITE); err != nil {
+		return 0, err
+	}
+
+	var code uint32
+	if err := syscall.GetExitCodeProcess(process.Process, &code); err != nil {
+		return 0, err
+	}
+
+	return code, nil
+}
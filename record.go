@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Record a session's output and key presses as an asciicast v2 file, for asciinema to replay
+type CastRecorder struct {
+	lock  sync.Mutex
+	file  *os.File
+	start time.Time
+}
+
+// Write output to the recording, in a colour; "" leaves it uncoloured
+type CastWriter struct {
+	recorder *CastRecorder
+	color    string
+}
+
+// The size recordings are played back at; the terminal's, if the shell exported it
+func CastSize() (int, int) {
+	width, err := strconv.Atoi(os.Getenv("COLUMNS"))
+	if err != nil || width <= 0 {
+		width = CAST_DEFAULT_WIDTH
+	}
+
+	height, err := strconv.Atoi(os.Getenv("LINES"))
+	if err != nil || height <= 0 {
+		height = CAST_DEFAULT_HEIGHT
+	}
+
+	return width, height
+}
+
+// Create a recording, and write its header
+func NewCastRecorder(fpath string, title string) (*CastRecorder, error) {
+	file, err := os.Create(fpath)
+	if err != nil {
+		return nil, err
+	}
+
+	width, height := CastSize()
+	start := time.Now()
+
+	header, _ := json.Marshal(map[string]interface{}{
+		"version":   2,
+		"width":     width,
+		"height":    height,
+		"timestamp": start.Unix(),
+		"title":     title,
+	})
+
+	if _, err := file.Write(append(header, '\n')); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &CastRecorder{file: file, start: start}, nil
+}
+
+// Record an event; "o" for output, or "i" for input, at the time since the recording started
+func (recorder *CastRecorder) Event(kind string, data string) {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+
+	event, _ := json.Marshal([]interface{}{time.Since(recorder.start).Seconds(), kind, data})
+	recorder.file.Write(append(event, '\n'))
+}
+
+// Construct a writer that records output in a colour
+func (recorder *CastRecorder) Writer(color string) *CastWriter {
+	return &CastWriter{recorder, color}
+}
+
+// Mark the start of a run in the recording
+func (recorder *CastRecorder) StartRun(run int64) {
+	recorder.Event("o", CAST_YELLOW+"── run "+strconv.FormatInt(run, 10)+" ──"+CAST_RESET+"\r\n")
+}
+
+func (recorder *CastRecorder) Close() error {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+
+	return recorder.file.Close()
+}
+
+// Record output; terminals need carriage returns to start new lines at the left edge
+func (writer *CastWriter) Write(data []byte) (int, error) {
+	text := strings.Replace(string(data), "\n", "\r\n", -1)
+
+	if len(writer.color) > 0 {
+		text = writer.color + text + CAST_RESET
+	}
+
+	writer.recorder.Event("o", text)
+	return len(data), nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCastRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replit-cast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fpath := filepath.Join(dir, "session.cast")
+	recorder, err := NewCastRecorder(fpath, "replit python3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder.Writer("").Write([]byte("hello\n"))
+	recorder.Writer(CAST_RED).Write([]byte("oops\n"))
+	recorder.Event("i", "k")
+	recorder.Close()
+
+	content, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected a header and three events, but got %d lines", len(lines))
+	}
+
+	var header map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &header); err != nil || header["version"] != float64(2) {
+		t.Errorf("expected an asciicast v2 header, but got %s", lines[0])
+	}
+
+	want := [][2]string{{"o", "hello\r\n"}, {"o", CAST_RED + "oops\r\n" + CAST_RESET}, {"i", "k"}}
+	for idx, line := range lines[1:] {
+		var event []interface{}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatal(err)
+		}
+
+		if event[1] != want[idx][0] || event[2] != want[idx][1] {
+			t.Errorf("expected event %v, but got %v", want[idx], event)
+		}
+	}
+}
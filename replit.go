@@ -2,13 +2,17 @@ package main
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -19,27 +23,161 @@ import (
 
 type EditorFile struct {
 	IsTempFile bool
-	File       *os.File
+	// replit created the file, rather than opening an existing one
+	Created bool
+	File    *os.File
+}
+
+// Close the file, and remove it if replit created it; for sessions that never start
+func (file *EditorFile) Discard() {
+	if file.File == nil {
+		return
+	}
+
+	file.File.Close()
+	if file.Created {
+		os.Remove(file.File.Name())
+	}
+}
+
+// Reopen the file by path. Editors that save by renaming a temporary file over the
+// original leave the old descriptor pointing at the replaced file
+func (file *EditorFile) Reopen() error {
+	if !AwaitPath(file.File.Name(), RENAME_TIMEOUT) {
+		return fmt.Errorf("%s no longer exists", file.File.Name())
+	}
+
+	conn, err := os.Open(file.File.Name())
+	if err != nil {
+		return err
+	}
+
+	file.File.Close()
+	file.File = conn
+
+	return nil
 }
 
 type ReplitArgs struct {
-	EditorFile *EditorFile
-	Dpath      string
-	Lang       string
+	EditorFile    *EditorFile
+	Dpath         string
+	Lang          string
+	OnChange      string
+	Combined      bool
+	Charset       string
+	RawCR         bool
+	KeepScroll    bool
+	RuntimeNotes  bool
+	Linear        bool
+	Timings       *StartupTimings
+	NoMouse       bool
+	Batch         string
+	Jobs          int
+	MaxFiles      int
+	WatchAll      bool
+	Imports       bool
+	Credential    *Credential
+	NoNetwork     bool
+	Sandbox       string
+	Nix           string
+	Direnv        map[string]*string
+	Dotenv        map[string]string
+	DotenvPath    string
+	SnapshotEvery time.Duration
+	SnapshotDir   string
+	JUnitPath     string
+	Session       *Session
+	Retries       int
+	RetryDelay    time.Duration
+	Every         time.Duration
+	Schedules     []*CronSchedule
+	Git           bool
+	Hook          bool
+	CI            bool
+	ProfileRun    string
+	ProfileOutput string
+	TraceOutput   string
+	Sanitize      []string
+	Valgrind      bool
+	NoBuildCache  bool
+	BuildServer   *BuildServer
+	Warm          int
+	WarmPool      *WarmPool
+	Timeout       time.Duration
+	DetachOnExit  bool
+	DetachDir     string
+	Share         string
+	Focus         *TestFocus
+	Stdin         *StdinSource
+	Recorder      *CastRecorder
+	Tracer        *Tracer
+	FPS           int
+	Events        *EventLogger
+	SQL           *SQLClient
+	Nice          int
+	IONice        string
+	Env           map[string]string
+	Config        *Config
+	Profile       string
+	Options       []string
+}
+
+// Which of a directory's files are watched
+type WatchFilter struct {
+	Limit int
+	All   bool
+	Keep  string
+}
+
+// Skip binaries, archives, and oversized files, which don't affect a run and are often written by one. The
+// kept file, the one being edited, is always watched
+func (filter WatchFilter) Skip(fpath string, info os.FileInfo) bool {
+	if filter.All || fpath == filter.Keep {
+		return false
+	}
+
+	if info.Size() > WATCH_MAX_FILE_SIZE || IGNORED_EXTENSIONS[strings.ToLower(filepath.Ext(fpath))] {
+		return true
+	}
+
+	// compiled programs usually have no extension; only executables are read, to keep listing cheap
+	if len(filepath.Ext(fpath)) == 0 && info.Mode()&0111 != 0 {
+		return IsBinaryFile(fpath)
+	}
+
+	return false
+}
+
+// Does a file's start contain a NUL byte, as binaries do and text doesn't?
+func IsBinaryFile(fpath string) bool {
+	conn, err := os.Open(fpath)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+
+	head := make([]byte, BINARY_SNIFF_BYTES)
+	count, _ := io.ReadFull(conn, head)
+
+	return bytes.IndexByte(head[:count], 0) >= 0
 }
 
-// List all files in directory
-func ListDirectory(dir string) (*[]string, error) {
+// List the files in a directory that the filter doesn't skip, or its .gitignore and .replitignore files
+// ignore, stopping at the filter's limit if it's positive. Reports whether the limit cut the listing short
+func ListDirectory(dir string, filter WatchFilter) (*[]string, bool, error) {
 	dirInfo, err := os.Stat(dir)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	if !dirInfo.IsDir() {
-		return nil, errors.New(dir + " was not a directory.")
+		return nil, false, errors.New(dir + " was not a directory.")
 	}
 
 	files := []string{}
+	capped := false
+	kept := false
+	rules := IgnoreRules{}
 
 	// walk through directory and append files to a slice.
 	filepath.Walk(dir, func(fpath string, info os.FileInfo, err error) error {
@@ -47,50 +185,219 @@ func ListDirectory(dir string) (*[]string, error) {
 			return err
 		}
 
-		if !info.IsDir() {
-			files = append(files, fpath)
+		rel, _ := filepath.Rel(dir, fpath)
+		rel = filepath.ToSlash(rel)
+
+		// nothing beneath an ignored directory is listed, as with git; each directory's ignore files apply beneath it
+		if info.IsDir() {
+			if rel != "." && rules.Ignored(rel, true) {
+				return filepath.SkipDir
+			}
+
+			if rel == "." {
+				rel = ""
+			}
+
+			rules = append(rules, LoadIgnoreRules(fpath, rel)...)
+			return nil
+		}
+
+		if fpath == filter.Keep {
+			kept = true
+		} else if filter.Skip(fpath, info) || rules.Ignored(rel, false) {
+			return nil
+		}
+
+		if filter.Limit > 0 && len(files) >= filter.Limit {
+			capped = true
+			return errFileLimit
 		}
 
+		files = append(files, fpath)
 		return nil
 	})
 
-	return &files, nil
+	// the edited file is watched even if it's in an ignored directory
+	if len(filter.Keep) > 0 && !kept {
+		if _, err := os.Stat(filter.Keep); err == nil {
+			files = append(files, filter.Keep)
+		}
+	}
+
+	return &files, capped, nil
+}
+
+// Wait briefly for a path to exist; saves via rename remove the original file for a moment
+func AwaitPath(fpath string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		if _, err := os.Stat(fpath); err == nil {
+			return true
+		}
+
+		if time.Now().After(deadline) {
+			return false
+		}
+
+		time.Sleep(RENAME_POLL_INTERVAL)
+	}
+}
+
+// Split a language such as "node --enable-source-maps" into a program and its flags
+func SplitLanguage(language string) (string, []string) {
+	fields := strings.Fields(language)
+	if len(fields) == 0 {
+		return "", []string{}
+	}
+
+	return fields[0], fields[1:]
+}
+
+// Build the command that runs the language against a file
+func LanguageCommand(args *ReplitArgs, fpath string) *exec.Cmd {
+	return WrapCommand(args, fpath, LanguageArgv(args, fpath))
+}
+
+// The language's program and flags, filtered to the focused tests if there are any, and the file. The
+// sql language runs the file with the --dsn database's client
+func LanguageArgv(args *ReplitArgs, fpath string) []string {
+	if args.SQL != nil {
+		return args.SQL.Command(fpath)
+	}
+
+	program, flags := SplitLanguage(args.Lang)
+	argv := append([]string{program}, flags...)
+
+	if args.Focus != nil {
+		argv = append(argv, args.Focus.Flags()...)
+	}
+
+	return append(argv, fpath)
+}
+
+// Build the command that runs the language against a file, under a profiler or syscall tracer
+// if one was requested
+func InstrumentedCommand(args *ReplitArgs, fpath string) *exec.Cmd {
+	argv := ProfileCommand(args.ProfileRun, args.ProfileOutput, LanguageArgv(args, fpath))
+
+	if len(args.TraceOutput) > 0 {
+		argv = TraceCommand(args.TraceOutput, argv)
+	}
+
+	return WrapCommand(args, fpath, argv)
+}
+
+// Build a command, inside nix and a sandbox if they were requested
+func WrapCommand(args *ReplitArgs, fpath string, argv []string) *exec.Cmd {
+	argv = NixCommand(args.Nix, argv)
+	argv = SandboxCommand(args.Sandbox, args.Config.Sandbox, args.Dpath, fpath, argv)
+
+	return exec.Command(argv[0], argv[1:]...)
 }
 
 // Check the requested language
 func ValidateLanguage(language string) error {
-	if !CommandExists(language) {
-		return fmt.Errorf("language %s is not in PATH", language)
+	program, _ := SplitLanguage(language)
+
+	if len(program) == 0 {
+		return errors.New("no language was provided")
+	}
+
+	if !CommandExists(program) {
+		return fmt.Errorf("language %s is not in PATH", program)
 	}
 
 	return nil
 }
 
-// Create and open a temporary file
+// Write a shebang line for the language to a new file
+func WriteShebang(tgt *os.File, lang string) error {
+	// compilers and database clients don't accept shebangs
+	if len(CompiledLanguage(lang)) > 0 || lang == SQL_LANGUAGE {
+		return nil
+	}
+
+	// env only splits interpreter flags from the program with -S
+	if _, flags := SplitLanguage(lang); len(flags) > 0 {
+		_, err := tgt.WriteString("#!/usr/bin/env -S " + lang + "\n")
+		return err
+	}
+
+	_, err := tgt.WriteString("#!/usr/bin/env " + lang + "\n")
+	return err
+}
+
+// Create and open a temporary file, or open the named file; creating it if it doesn't exist yet. URLs
+// are downloaded into a temporary file
 func TargetFile(file string, lang string) (*EditorFile, error) {
+	if IsURL(file) {
+		content, err := FetchURL(file)
+		if err != nil {
+			return nil, err
+		}
+
+		ext := URLExtension(RawURL(file))
+		if len(ext) == 0 {
+			ext = ScratchExtension(lang)
+		}
+
+		tgt, err := ioutil.TempFile("", "replit*"+ext)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := tgt.Write(content); err != nil {
+			tgt.Close()
+			os.Remove(tgt.Name())
+			return nil, err
+		}
+
+		return &EditorFile{IsTempFile: true, Created: true, File: tgt}, nil
+	}
+
 	if len(file) == 0 {
-		tgt, err := ioutil.TempFile("/tmp", "replit")
+		tgt, err := ioutil.TempFile("", "replit*"+ScratchExtension(lang))
 		if err != nil {
 			return nil, err
 		}
 
-		tgt.WriteString("#!/usr/bin/env " + lang + "\n")
+		WriteShebang(tgt, lang)
 
-		return &EditorFile{
-			true,
-			tgt,
-		}, nil
+		return &EditorFile{IsTempFile: true, Created: true, File: tgt}, nil
 	}
 
+	created := false
 	conn, err := os.Open(file)
-	if err != nil {
+	if os.IsNotExist(err) {
+		// start a new, named scratch file
+		conn, err = os.Create(file)
+		if err != nil {
+			return nil, err
+		}
+
+		created = true
+
+		if err := WriteShebang(conn, lang); err != nil {
+			conn.Close()
+			os.Remove(file)
+			return nil, err
+		}
+	} else if err != nil {
 		return nil, err
 	}
 
-	return &EditorFile{
-		false,
-		conn,
-	}, nil
+	return &EditorFile{IsTempFile: false, Created: created, File: conn}, nil
+}
+
+// Check the requested overlap policy
+func ValidateOnChange(policy string) error {
+	switch policy {
+	case ON_CHANGE_QUEUE, ON_CHANGE_SKIP, ON_CHANGE_RESTART:
+		return nil
+	}
+
+	return fmt.Errorf("unknown --on-change policy '%s'; expected %s, %s, or %s", policy, ON_CHANGE_QUEUE, ON_CHANGE_SKIP, ON_CHANGE_RESTART)
 }
 
 // Check whether a command exists
@@ -118,7 +425,8 @@ func GetEditor() (string, error) {
 }
 
 // Launch the user's visual-editor, falling back to VSCode as a default.
-func LaunchEditor(editorChan chan<- *exec.Cmd, file *EditorFile) {
+func LaunchEditor(editorChan chan<- *exec.Cmd, file *EditorFile, tui *TUI) {
+	began := time.Now()
 	editor, _ := GetEditor()
 	var cmd *exec.Cmd
 
@@ -129,15 +437,30 @@ func LaunchEditor(editorChan chan<- *exec.Cmd, file *EditorFile) {
 		cmd = exec.Command(editor, file.File.Name())
 	}
 
-	cmd.Start()
+	if err := cmd.Start(); err != nil {
+		tui.ReportError(fmt.Errorf("failed to launch editor '%s': %v", editor, err))
+	}
+	tui.timings.Record("editor", began)
+
 	editorChan <- cmd
 }
 
 type FileWatcher struct {
-	Done  bool
-	Files *[]string
+	Done       bool
+	Files      *[]string
+	Dpath      string
+	Entrypoint string
+	Imports    string
+	Extra      []string
+	Filter     WatchFilter
+	Capped     bool
+	warned     bool
+	hashes     map[string][sha256.Size]byte
 }
 
+// Stops a directory walk once enough files are listed
+var errFileLimit = errors.New("file limit reached")
+
 func (watch *FileWatcher) Stop() {
 	watch.Done = true
 }
@@ -148,246 +471,1744 @@ func (watch *FileWatcher) Stdin() *bytes.Buffer {
 	return bytes.NewBuffer(byteStr)
 }
 
-func (watch *FileWatcher) Start(tui *TUI) {
-	go func() {
-		for {
-			if watch.Done {
-				return
-			}
-
-			cmd := exec.Command("entr", "-zps", "echo 0")
-			cmd.Stdin = watch.Stdin()
-			cmd.Run()
-
-			tui.actions.fileChange.Broadcast()
-		}
-	}()
-}
-
-// Observe file-changes
-func ObserveFileChanges(args *ReplitArgs, tui *TUI) (FileWatcher, error) {
-	targetFile := args.EditorFile
-	dpath := args.Dpath
-
-	var files *[]string
-
-	if targetFile.IsTempFile {
-		files = &[]string{targetFile.File.Name()}
-	} else {
-		var err error
-		files, err = ListDirectory(dpath)
+// List watched files that no longer exist
+func (watch *FileWatcher) Missing() []string {
+	missing := []string{}
 
-		if err != nil {
-			return FileWatcher{}, err
+	for _, fpath := range *watch.Files {
+		if _, err := os.Stat(fpath); os.IsNotExist(err) {
+			missing = append(missing, fpath)
 		}
 	}
 
-	watch := FileWatcher{false, files}
-
-	return watch, nil
+	return missing
 }
 
-// Read docopt arguments and return parsed, provided parameters
-func ReadArgs(opts docopt.Opts) (ReplitArgs, int) {
-	dir, _ := opts.String("--directory")
-	if len(dir) == 0 {
-		dir, _ = os.Getwd()
-	}
-
-	dpath, err := filepath.Abs(dir)
-	if err != nil {
-		println("replit: failed to resolve directory path")
-		return ReplitArgs{}, 1
+// Re-list the watched directory, so files created or renamed into place are watched
+func (watch *FileWatcher) Refresh() error {
+	if len(watch.Dpath) == 0 {
+		return nil
 	}
 
-	lang, err := opts.String("<lang>")
-	if err != nil {
-		println("replit: could not read language")
-		return ReplitArgs{}, 1
+	// only watch the entrypoint and the files it imports
+	if len(watch.Imports) > 0 {
+		files := WithExtraFiles(ImportClosure(watch.Entrypoint, watch.Dpath, watch.Imports), watch.Extra)
+		watch.Files = &files
+		return nil
 	}
 
-	// check the editor is present; ignore the value for the moment
-	_, err = GetEditor()
-
+	files, capped, err := ListDirectory(watch.Dpath, watch.Filter)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	langErr := ValidateLanguage(lang)
-	if langErr != nil {
-		panic(langErr)
-	}
+	watch.Capped = capped
+	merged := WithExtraFiles(*files, watch.Extra)
+	watch.Files = &merged
+	return nil
+}
 
-	file, _ := opts.String("<file>")
-	targetFile, err := TargetFile(file, lang)
+// Add files to a watch list, skipping any already present
+func WithExtraFiles(files []string, extra []string) []string {
+	seen := map[string]bool{}
+	merged := []string{}
 
-	if err != nil {
-		panic(err)
+	for _, fpath := range append(append([]string{}, files...), extra...) {
+		if !seen[fpath] {
+			seen[fpath] = true
+			merged = append(merged, fpath)
+		}
 	}
 
-	return ReplitArgs{
-		targetFile,
-		dpath,
-		lang,
-	}, -1
-}
-
-type LanguageState struct {
-	Time time.Time
-	Lock sync.Mutex
-	Cmd  *exec.Cmd
+	return merged
 }
 
-func RunLanguage(args *ReplitArgs, tui *TUI, state *LanguageState) {
-	var cmd *exec.Cmd
+// Stamp each watched file; missing files are left out, so removing one is a change
+func (watch *FileWatcher) Stamps() map[string]FileStamp {
+	stamps := map[string]FileStamp{}
 
-	// kill the running process
-	killProcess := func() {
-		if cmd != nil {
-			cmd.Process.Kill()
-			cmd = nil
+	for _, fpath := range *watch.Files {
+		if info, err := os.Stat(fpath); err == nil {
+			stamps[fpath] = FileStamp{info.Size(), info.ModTime()}
 		}
 	}
 
-	// update stdout
-	onFileChange := func() {
-		now := time.Now()
+	return stamps
+}
 
-		threshold := time.Second * 2
-		if state.Time.Sub(now) > threshold {
-			// too slow; even though it is running just kill the process and release the lock
-			// so the new content can be run.
+// Notice changes by comparing the watched files' stamps periodically, for when entr can't watch them
+func (watch *FileWatcher) Poll(tui *TUI) {
+	previous := watch.Stamps()
 
-			if cmd != nil {
-				cmd.Process.Kill()
-				state.Lock.Unlock()
-			}
+	for !watch.Done {
+		time.Sleep(WATCH_POLL_INTERVAL)
+
+		if err := watch.Refresh(); err != nil {
+			tui.ReportError(fmt.Errorf("could not list watched directory: %v", err))
+			continue
 		}
 
-		state.Lock.Lock()
+		if len(TouchedFiles(previous, watch.Stamps())) == 0 {
+			continue
+		}
 
-		// clear stdout
-		stdoutViewer := tui.stdoutViewer
-		stderrViewer := tui.stderrViewer
+		changed := watch.ContentChanged(watch.Settle(previous))
+		previous = watch.Stamps()
 
-		stdoutViewer.Lock()
-		stdoutViewer.Clear()
-		stdoutViewer.Unlock()
+		if len(changed) > 0 {
+			tui.NoteChangedFiles(len(changed))
+			tui.actions.fileChange.Broadcast()
+		}
+	}
+}
 
-		stderrViewer.Lock()
-		stderrViewer.Clear()
-		stderrViewer.Unlock()
+// Wait for a burst of changes, such as a branch switch or a formatter run over a tree, to end; until no
+// watched file changes for the debounce window, or the burst runs too long. Lists the files touched since before
+func (watch *FileWatcher) Settle(before map[string]FileStamp) []string {
+	deadline := time.Now().Add(DEBOUNCE_MAX_WAIT)
+	current := watch.Stamps()
 
-		state.Time = now
-		// call the language against a file
-		cmd = exec.Command(args.Lang, args.EditorFile.File.Name())
-		cmd.Stdout = stdoutViewer
-		cmd.Stderr = stderrViewer
+	for time.Now().Before(deadline) {
+		time.Sleep(DEBOUNCE_WINDOW)
 
-		startCommandTime := time.Now()
-		done := false
+		// re-list the directory, so files the burst created are counted
+		if err := watch.Refresh(); err != nil {
+			break
+		}
 
-		go func() {
-			for {
-				if done {
-					break
-				}
+		next := watch.Stamps()
+		if len(TouchedFiles(current, next)) == 0 {
+			break
+		}
 
-				time.Sleep(time.Millisecond * 25)
+		current = next
+	}
 
-				tui.UpdateRunTime(time.Since(startCommandTime))
-				tui.app.Draw()
-			}
-		}()
+	return TouchedFiles(before, current)
+}
 
-		cmd.Run()
-		tui.UpdateRunCount()
+// Hash each watched file's content, so saves that don't change it can be told apart
+func (watch *FileWatcher) RecordHashes() {
+	watch.hashes = map[string][sha256.Size]byte{}
 
-		done = true
-		tui.app.Draw()
-		state.Lock.Unlock()
+	for _, fpath := range *watch.Files {
+		if content, err := ioutil.ReadFile(fpath); err == nil {
+			watch.hashes[fpath] = sha256.Sum256(content)
+		}
 	}
-
-	// run on process-kill
-	attachListener(tui.actions.killProcess, killProcess)
-
-	// run on file-change
-	attachListener(tui.actions.fileChange, onFileChange)
 }
 
-// Core application
-func ReplIt(opts docopt.Opts) int {
-	// read and validate arguments
-	args, exitCode := ReadArgs(opts)
-	if exitCode >= 0 {
-		return exitCode
+// Filter touched files to those whose content changed since it was last hashed; editors that rewrite
+// identical content, and touch, change a file's modification time alone. Created and removed files changed
+func (watch *FileWatcher) ContentChanged(touched []string) []string {
+	changed := []string{}
+	if watch.hashes == nil {
+		watch.hashes = map[string][sha256.Size]byte{}
 	}
 
-	tui := NewUI(&args)
+	for _, fpath := range touched {
+		content, err := ioutil.ReadFile(fpath)
+		if err != nil {
+			delete(watch.hashes, fpath)
+			changed = append(changed, fpath)
+			continue
+		}
 
-	tui.SetTheme()
+		hash := sha256.Sum256(content)
+		if previous, ok := watch.hashes[fpath]; !ok || previous != hash {
+			changed = append(changed, fpath)
+		}
 
-	go func(tui *TUI) {
-		tui.Start()
-	}(tui)
+		watch.hashes[fpath] = hash
+	}
 
-	editorChan := make(chan *exec.Cmd)
+	return changed
+}
 
-	// launch an editor asyncronously
-	go LaunchEditor(editorChan, args.EditorFile)
+// Explain how to fix entr running out of inotify watches or file descriptors, from its error output; nil for other errors
+func WatchLimitError(stderr string, count int) error {
+	switch {
+	case strings.Contains(stderr, "No space left on device"):
+		limit := "fs.inotify.max_user_watches"
+		if content, err := ioutil.ReadFile(INOTIFY_WATCHES_PATH); err == nil {
+			limit += " (" + strings.TrimSpace(string(content)) + ")"
+		}
 
-	// start entr; read the file (and optionally a directory) and live-reload
-	state := LanguageState{
-		time.Now(),
-		sync.Mutex{},
-		nil,
+		return fmt.Errorf("the watcher ran out of inotify watches for %d files; polling for changes instead. Raise %s, e.g. sudo sysctl fs.inotify.max_user_watches=524288, or watch fewer files with a .replitignore", count, limit)
+	case strings.Contains(stderr, "Too many files listed"), strings.Contains(stderr, "Too many open files"):
+		return fmt.Errorf("the watcher can't open %d files at once; polling for changes instead. Raise the limit with ulimit -n, or watch fewer files with a .replitignore", count)
 	}
 
-	fileWatcher, err := ObserveFileChanges(&args, tui)
-	if err != nil {
-		panic(err)
+	return nil
+}
+
+// Did the watched files reappear after entr lost track of them?
+func (watch *FileWatcher) Renamed() bool {
+	missing := watch.Missing()
+	if len(missing) == 0 {
+		return false
 	}
 
-	go fileWatcher.Start(tui)
+	for _, fpath := range missing {
+		if !AwaitPath(fpath, RENAME_TIMEOUT) {
+			return false
+		}
+	}
 
-	go RunLanguage(&args, tui, &state)
+	return true
+}
 
-	// Terminate program when an exit signal is received, and tidy up termporary files and processes
+func (watch *FileWatcher) Start(tui *TUI) {
+	go func() {
+		watch.RecordHashes()
 
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+		for {
+			if watch.Done {
+				return
+			}
 
-	<-sigs
-	close(sigs)
+			if !CommandExists("entr") {
+				tui.ReportError(errors.New("entr is not in PATH; install entr so file-changes can be watched"))
+				time.Sleep(WATCH_RETRY_INTERVAL)
+				continue
+			}
 
-	var doneGroup sync.WaitGroup
-	doneGroup.Add(2)
+			if err := watch.Refresh(); err != nil {
+				tui.ReportError(fmt.Errorf("could not list watched directory: %v", err))
+				time.Sleep(WATCH_RETRY_INTERVAL)
+				continue
+			}
 
-	// close each channel
+			// warn once; the cap is kept, so changes to the unlisted files go unnoticed
+			if watch.Capped && !watch.warned {
+				tui.ReportError(fmt.Errorf("%s has more than %d files; only the first %d are watched. Raise --max-files, or watch fewer files with a .replitignore, --imports, or a smaller --directory", watch.Dpath, watch.Filter.Limit, watch.Filter.Limit))
+				watch.warned = true
+			}
 
-	// kill editor
-	go func() {
-		defer doneGroup.Done()
+			before := watch.Stamps()
 
-		editor := <-editorChan
-		editor.Process.Kill()
-		close(editorChan)
-	}()
+			// in directory mode, also exit when files are added to the directory. Import-aware
+			// watches re-resolve imports after each change instead
+			flags := "-zps"
+			if len(watch.Dpath) > 0 && len(watch.Imports) == 0 {
+				flags = "-dzps"
+			}
 
-	// remove temporary file
-	go func() {
-		defer doneGroup.Done()
+			var stderr bytes.Buffer
 
-		targetFile := args.EditorFile
+			cmd := exec.Command("entr", flags, "echo 0")
+			cmd.Stdin = watch.Stdin()
+			cmd.Stderr = &stderr
+			err := cmd.Run()
+
+			// retrying won't help once the system's watch limit is reached; poll for the rest of the session
+			if err != nil {
+				if limitErr := WatchLimitError(stderr.String(), len(*watch.Files)); limitErr != nil {
+					tui.ReportError(limitErr)
+					watch.Poll(tui)
+					return
+				}
+			}
+
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) && exitErr.ExitCode() == ENTR_DIRECTORY_ALTERED {
+				err = nil
+			}
+
+			// a file was saved by renaming over the original; treat it as a change
+			if err != nil && watch.Renamed() {
+				err = nil
+			}
+
+			// entr failed rather than observing a change; report it and retry rather than rerunning
+			if err != nil {
+				if missing := watch.Missing(); len(missing) > 0 {
+					tui.ReportError(fmt.Errorf("watched file %s no longer exists", missing[0]))
+				} else {
+					tui.ReportError(fmt.Errorf("file-watcher stopped unexpectedly: %v", err))
+				}
+
+				time.Sleep(WATCH_RETRY_INTERVAL)
+				continue
+			}
+
+			// collapse the rest of the burst into this rerun, unless no file's content changed
+			changed := watch.ContentChanged(watch.Settle(before))
+			if len(changed) == 0 {
+				continue
+			}
+
+			tui.NoteChangedFiles(len(changed))
+			tui.actions.fileChange.Broadcast()
+		}
+	}()
+}
+
+// Observe file-changes
+func ObserveFileChanges(args *ReplitArgs, tui *TUI) (FileWatcher, error) {
+	targetFile := args.EditorFile
+	dpath := args.Dpath
+
+	var files *[]string
+	capped := false
+
+	keep, err := filepath.Abs(targetFile.File.Name())
+	if err != nil {
+		return FileWatcher{}, err
+	}
+
+	filter := WatchFilter{Limit: args.MaxFiles, All: args.WatchAll, Keep: keep}
+
+	if targetFile.IsTempFile {
+		files = &[]string{targetFile.File.Name()}
+		dpath = ""
+	} else {
+		files, capped, err = ListDirectory(dpath, filter)
+
+		if err != nil {
+			return FileWatcher{}, err
+		}
+	}
+
+	watch := FileWatcher{Files: files, Dpath: dpath, Filter: filter, Capped: capped}
+
+	if args.Git {
+		// without a repository, keep watching files as usual
+		gitFiles, err := GitFiles(args.Dpath)
+		if err != nil {
+			tui.ReportError(err)
+		}
+
+		watch.Extra = gitFiles
+		merged := WithExtraFiles(*files, gitFiles)
+		watch.Files = &merged
+	}
+
+	if args.Imports && len(dpath) > 0 {
+		watch.Entrypoint = keep
+		watch.Imports = ImportLanguage(args.Lang)
+
+		if len(watch.Imports) == 0 {
+			tui.ReportError(fmt.Errorf("cannot follow imports for %s; watching every file in %s", args.Lang, dpath))
+		}
+	}
+
+	return watch, nil
+}
+
+// Print a concise CLI error, with a suggestion for fixing it
+func PrintCliError(message string, suggestion string) {
+	println("replit: " + message)
+	println("  " + suggestion)
+}
+
+// Read docopt arguments and return parsed, provided parameters
+func ReadArgs(opts docopt.Opts) (ReplitArgs, int) {
+	dir, _ := opts.String("--directory")
+	if len(dir) == 0 {
+		dir, _ = os.Getwd()
+	}
+
+	dpath, err := filepath.Abs(dir)
+	if err != nil {
+		println("replit: failed to resolve directory path")
+		return ReplitArgs{}, EXIT_BAD_ARGS
+	}
+
+	if info, err := os.Stat(dpath); err != nil || !info.IsDir() {
+		PrintCliError("the directory "+dpath+" does not exist", "check the --directory path, or omit it to watch the current directory")
+		return ReplitArgs{}, EXIT_BAD_ARGS
+	}
+
+	configPath, _ := opts.String("--config")
+	profile, _ := opts.String("--profile")
+
+	config, err := LoadConfig(ConfigPath(configPath, dpath), profile)
+	if err != nil {
+		PrintCliError(err.Error(), "fix the config file, or select another with --config")
+		return ReplitArgs{}, EXIT_BAD_ARGS
+	}
+
+	if err := CompileMatchers(config.Matchers); err != nil {
+		PrintCliError(err.Error(), "fix the config's problem_matchers")
+		return ReplitArgs{}, EXIT_BAD_ARGS
+	}
+
+	for _, hook := range config.Webhooks {
+		if err := hook.Validate(); err != nil {
+			PrintCliError(err.Error(), "fix the config's webhooks")
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+	}
+
+	lang, err := opts.String("<lang>")
+	if err != nil {
+		println("replit: could not read language")
+		return ReplitArgs{}, EXIT_BAD_ARGS
+	}
+
+	// check the editor is present; ignore the value for the moment. Hooks and CI runs don't edit
+	hook, _ := opts.Bool("--hook")
+	ci, _ := opts.Bool("--ci")
+	_, err = GetEditor()
+
+	if err != nil && !hook && !ci {
+		PrintCliError(err.Error(), "install it, or set $VISUAL to an installed editor (e.g. VISUAL=vim)")
+		return ReplitArgs{}, EXIT_MISSING_EDITOR
+	}
+
+	// the sql language runs files with the database's client, rather than a program named sql
+	var sqlClient *SQLClient
+	dsn, _ := opts.String("--dsn")
+	if (lang == SQL_LANGUAGE) != (len(dsn) > 0) {
+		PrintCliError("--dsn and the sql language are used together", "e.g. replit --dsn postgres://localhost/db sql")
+		return ReplitArgs{}, EXIT_BAD_ARGS
+	}
+
+	if len(dsn) > 0 {
+		sqlClient, err = NewSQLClient(dsn)
+		if err != nil {
+			PrintCliError(err.Error(), "pass a postgres://, mysql://, or sqlite:// DSN")
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+	}
+
+	// the language need only exist within the nix environment
+	nix, _ := opts.String("--nix")
+	if len(nix) > 0 {
+		if err := ValidateNix(nix); err != nil {
+			PrintCliError(err.Error(), "install nix, or omit --nix")
+			return ReplitArgs{}, EXIT_MISSING_LANGUAGE
+		}
+	} else if sqlClient != nil {
+		if err := sqlClient.Validate(); err != nil {
+			PrintCliError(err.Error(), "install "+sqlClient.Program+", or provide it with --nix")
+			return ReplitArgs{}, EXIT_MISSING_LANGUAGE
+		}
+	} else if langErr := ValidateLanguage(lang); langErr != nil {
+		program, _ := SplitLanguage(lang)
+		PrintCliError(langErr.Error(), "install "+program+", check your PATH, or provide it with --nix")
+		return ReplitArgs{}, EXIT_MISSING_LANGUAGE
+	}
+
+	onChange, _ := opts.String("--on-change")
+	if restart, _ := opts.Bool("--restart-on-change"); restart {
+		onChange = ON_CHANGE_RESTART
+	}
+
+	if err := ValidateOnChange(onChange); err != nil {
+		println("replit: " + err.Error())
+		return ReplitArgs{}, EXIT_BAD_ARGS
+	}
+
+	// hooks run against staged files, rather than a file being edited
+	file, _ := opts.String("<file>")
+	if ci && len(file) == 0 {
+		PrintCliError("--ci needs a file to run", "pass the file to run after the language")
+		return ReplitArgs{}, EXIT_BAD_ARGS
+	}
+
+	combined, _ := opts.Bool("--combined")
+	rawCR, _ := opts.Bool("--raw-cr")
+	keepScroll, _ := opts.Bool("--keep-scroll")
+	runtimeNotes, _ := opts.Bool("--runtime-notes")
+	linear, _ := opts.Bool("--linear")
+	noTUI, _ := opts.Bool("--no-tui")
+	noMouse, _ := opts.Bool("--no-mouse")
+	noMouse = noMouse || config.NoMouse
+
+	noNetwork, _ := opts.Bool("--no-network")
+	if noNetwork {
+		if err := CheckNetworkIsolation(); err != nil {
+			PrintCliError("--no-network is unsupported: "+err.Error(), "omit --no-network on this platform")
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+	}
+
+	sandbox, _ := opts.String("--sandbox")
+	if len(sandbox) > 0 {
+		if err := ValidateSandbox(sandbox, config.Sandbox); err != nil {
+			PrintCliError("invalid --sandbox: "+err.Error(), "install bwrap or firejail, and check the config's sandbox profile")
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+	}
+
+	var credential *Credential
+	if name, _ := opts.String("--user"); len(name) > 0 {
+		credential, err = UserCredential(name)
+		if err != nil {
+			PrintCliError("cannot run as user '"+name+"': "+err.Error(), "run replit with sudo, or omit --user")
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+	}
+	imports, _ := opts.Bool("--imports")
+
+	batch, _ := opts.String("--batch")
+	if _, err := filepath.Match(batch, ""); err != nil {
+		PrintCliError("invalid --batch pattern '"+batch+"'", "pass a glob relative to the directory, e.g. 'examples/*.py'")
+		return ReplitArgs{}, EXIT_BAD_ARGS
+	}
+
+	// limit concurrent batch processes to the config's max_jobs, or the number of CPUs
+	jobs := runtime.NumCPU()
+	if config.MaxJobs > 0 {
+		jobs = config.MaxJobs
+	}
+
+	if value, _ := opts.String("--jobs"); len(value) > 0 {
+		jobs, err = strconv.Atoi(value)
+		if err != nil || jobs <= 0 {
+			PrintCliError("invalid --jobs '"+value+"'", "pass how many batch files may run at once, e.g. 4")
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+	}
+
+	// cap the files watched in the directory at the config's max_watched_files; zero watches every file
+	maxFiles := DEFAULT_MAX_WATCHED_FILES
+	if config.MaxFiles != nil {
+		maxFiles = *config.MaxFiles
+	}
+
+	if value, _ := opts.String("--max-files"); len(value) > 0 {
+		maxFiles, err = strconv.Atoi(value)
+		if err != nil || maxFiles < 0 {
+			PrintCliError("invalid --max-files '"+value+"'", "pass how many files of the directory may be watched, e.g. 50000, or 0 for no limit")
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+	}
+
+	watchAll, _ := opts.Bool("--watch-all")
+
+	pairs, _ := opts["--env"].([]string)
+	env, err := ParseEnvPairs(pairs)
+	if err != nil {
+		PrintCliError("invalid --env: "+err.Error(), "pass variables as --env KEY=VALUE")
+		return ReplitArgs{}, EXIT_BAD_ARGS
+	}
+
+	var direnv map[string]*string
+	if useDirenv, _ := opts.Bool("--direnv"); useDirenv {
+		direnv, err = DirenvDiff(dpath)
+		if err != nil {
+			PrintCliError("could not load .envrc: "+err.Error(), "run 'direnv allow' in "+dpath+", or omit --direnv")
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+	}
+
+	// load the directory's .env by default, if there is one
+	var dotenv map[string]string
+	dotenvPath, _ := opts.String("--dotenv")
+	noDotenv, _ := opts.Bool("--no-dotenv")
+
+	if len(dotenvPath) == 0 {
+		dotenvPath = filepath.Join(dpath, ".env")
+
+		if _, err := os.Stat(dotenvPath); err != nil {
+			noDotenv = true
+		}
+	}
+
+	if !noDotenv {
+		dotenv, err = LoadDotenv(dotenvPath)
+		if err != nil {
+			PrintCliError("could not load "+dotenvPath+": "+err.Error(), "fix the file, or pass --no-dotenv")
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+	} else {
+		dotenvPath = ""
+	}
+
+	var snapshotEvery time.Duration
+	if every, _ := opts.String("--snapshot-every"); len(every) > 0 {
+		snapshotEvery, err = time.ParseDuration(every)
+		if err != nil || snapshotEvery <= 0 {
+			PrintCliError("invalid --snapshot-every '"+every+"'", "pass a positive duration, e.g. 30s or 5m")
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+	}
+
+	snapshotDir, _ := opts.String("--snapshot-dir")
+	junitPath, _ := opts.String("--junit")
+
+	retries := 0
+	if value, _ := opts.String("--retries"); len(value) > 0 {
+		retries, err = strconv.Atoi(value)
+		if err != nil || retries < 0 {
+			PrintCliError("invalid --retries '"+value+"'", "pass how many times to retry a failed run, e.g. 3")
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+	}
+
+	delay, _ := opts.String("--retry-delay")
+	retryDelay, err := time.ParseDuration(delay)
+	if err != nil || retryDelay < 0 {
+		PrintCliError("invalid --retry-delay '"+delay+"'", "pass a duration, e.g. 2s")
+		return ReplitArgs{}, EXIT_BAD_ARGS
+	}
+
+	var timeout time.Duration
+	if value, _ := opts.String("--timeout"); len(value) > 0 {
+		timeout, err = time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			PrintCliError("invalid --timeout '"+value+"'", "pass a positive duration, e.g. 10s")
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+	}
+
+	detachOnExit, _ := opts.Bool("--detach-on-exit")
+	share, _ := opts.String("--share")
+	if len(share) > 0 {
+		share = ShareAddress(share)
+	}
+
+	var focus *TestFocus
+	if pattern, _ := opts.String("--focus"); len(pattern) > 0 {
+		focus, err = NewTestFocus(lang, pattern)
+		if err != nil {
+			PrintCliError("invalid --focus: "+err.Error(), "use go test, pytest, jest, vitest, bun test, deno test, or mocha as the language")
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+	}
+
+	var stdin *StdinSource
+	if stdinURL, _ := opts.String("--stdin-url"); len(stdinURL) > 0 {
+		if !IsURL(stdinURL) {
+			PrintCliError("invalid --stdin-url '"+stdinURL+"'", "pass an http or https URL")
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+
+		if len(share) > 0 {
+			PrintCliError("--stdin-url and --share both provide the program's stdin", "omit one of them")
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+
+		refetch, _ := opts.Bool("--refetch")
+		stdin = &StdinSource{URL: stdinURL, Refetch: refetch}
+	}
+
+	nice := 0
+	if value, _ := opts.String("--nice"); len(value) > 0 {
+		nice, err = strconv.Atoi(value)
+		if err != nil || nice < -20 || nice > 19 {
+			PrintCliError("invalid --nice '"+value+"'", "pass a niceness from -20 to 19; higher values run at a lower priority")
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+	}
+
+	ionice, _ := opts.String("--ionice")
+	if len(ionice) > 0 {
+		if err := ValidateIOPriority(ionice); err != nil {
+			PrintCliError("invalid --ionice: "+err.Error(), "pass idle or best-effort, or omit --ionice on this platform")
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+	}
+
+	var every time.Duration
+	if value, _ := opts.String("--every"); len(value) > 0 {
+		every, err = time.ParseDuration(value)
+		if err != nil || every <= 0 {
+			PrintCliError("invalid --every '"+value+"'", "pass a positive duration, e.g. 30s")
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+	}
+
+	schedules := []*CronSchedule{}
+	for _, spec := range config.Schedule {
+		cron, err := ParseCron(spec)
+		if err != nil {
+			PrintCliError(err.Error(), "fix the config's schedule, e.g. \"*/5 * * * *\" for every five minutes")
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+
+		schedules = append(schedules, cron)
+	}
+
+	git, _ := opts.Bool("--git")
+
+	profileRun, _ := opts.String("--profile-run")
+	profileOutput := ""
+	if len(profileRun) > 0 {
+		if err := ValidateProfiler(profileRun); err != nil {
+			PrintCliError("invalid --profile-run: "+err.Error(), "install py-spy or perf")
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+
+		profileOutput = filepath.Join(os.TempDir(), fmt.Sprintf("replit-profile-%d", os.Getpid()))
+	}
+
+	sanitize := []string{}
+	if value, _ := opts.String("--sanitize"); len(value) > 0 {
+		sanitize = strings.Split(value, ",")
+	}
+
+	valgrind, _ := opts.Bool("--valgrind")
+	if err := ValidateNativeChecks(lang, sanitize, valgrind); err != nil {
+		PrintCliError(err.Error(), "use a C or C++ compiler as the language, e.g. 'replit gcc main.c --sanitize address'")
+		return ReplitArgs{}, EXIT_BAD_ARGS
+	}
+
+	noBuildCache, _ := opts.Bool("--no-build-cache")
+
+	// start interpreters ahead of runs; the run's own options can't be applied to a started interpreter
+	warm := 0
+	if value, _ := opts.String("--warm"); len(value) > 0 {
+		warm, err = strconv.Atoi(value)
+		if err != nil || warm < 0 {
+			PrintCliError("invalid --warm '"+value+"'", "pass how many interpreters to start ahead of runs, e.g. 2")
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+
+		if warm > 0 && WarmBootstrap(lang) == nil {
+			PrintCliError("--warm can't start "+lang+" ahead of runs", "use python or node without -m, -c, or -e, or omit --warm")
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+	}
+
+	for _, flag := range WARM_CONFLICTS {
+		if value := opts[flag]; warm > 0 && value != nil && value != false {
+			PrintCliError("--warm can't be used with "+flag, "omit --warm, or "+flag)
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+	}
+
+	buildServerName, _ := opts.String("--build-server")
+	buildServer, err := ResolveBuildServer(buildServerName, config.Build)
+	if err != nil {
+		PrintCliError("invalid --build-server: "+err.Error(), "install the build tool, or configure build_server's commands in the config file")
+		return ReplitArgs{}, EXIT_BAD_ARGS
+	}
+
+	if warm > 0 && (buildServer != nil || len(config.Pipeline) > 0) {
+		PrintCliError("--warm can't be used with a build server or pipeline", "omit --warm; runs use the build server or pipeline's commands")
+		return ReplitArgs{}, EXIT_BAD_ARGS
+	}
+
+	traceOutput := ""
+	if trace, _ := opts.Bool("--trace-syscalls"); trace {
+		if err := ValidateTracer(); err != nil {
+			PrintCliError("invalid --trace-syscalls: "+err.Error(), "install strace, or omit --trace-syscalls")
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+
+		traceOutput = filepath.Join(os.TempDir(), fmt.Sprintf("replit-trace-%d", os.Getpid()))
+	}
+
+	var session *Session
+	sessionRoot, _ := opts.String("--session-dir")
+	gitHistory, _ := opts.Bool("--git-history")
+	if persist, _ := opts.Bool("--persist"); persist || gitHistory || len(sessionRoot) > 0 {
+		if len(sessionRoot) == 0 {
+			sessionRoot, err = DefaultSessionRoot()
+		}
+
+		if err == nil {
+			session, err = NewSession(sessionRoot)
+		}
+
+		if err == nil && gitHistory {
+			err = session.EnableGitHistory()
+		}
+
+		if err != nil {
+			PrintCliError("could not create a session directory: "+err.Error(), "pass a writable --session-dir, and install git to use --git-history")
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+	}
+
+	var recorder *CastRecorder
+	if record, _ := opts.String("--record"); len(record) > 0 {
+		recorder, err = NewCastRecorder(record, "replit "+lang)
+		if err != nil {
+			PrintCliError("could not create the recording: "+err.Error(), "pass a writable --record path")
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+	}
+
+	// redraw at the config's draw_fps, or the default rate
+	fps := DEFAULT_FPS
+	if config.DrawFPS != 0 {
+		fps = config.DrawFPS
+		if fps < 1 || fps > MAX_FPS {
+			PrintCliError(fmt.Sprintf("invalid draw_fps %d in the config", fps), fmt.Sprintf("set how many times a second to redraw, from 1 to %d", MAX_FPS))
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+	}
+
+	if value, _ := opts.String("--fps"); len(value) > 0 {
+		fps, err = strconv.Atoi(value)
+		if err != nil || fps < 1 || fps > MAX_FPS {
+			PrintCliError("invalid --fps '"+value+"'", fmt.Sprintf("pass how many times a second to redraw, from 1 to %d", MAX_FPS))
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+	}
+
+	var tracer *Tracer
+	if endpoint, _ := opts.String("--otlp"); len(endpoint) > 0 {
+		if !IsURL(endpoint) {
+			PrintCliError("invalid --otlp '"+endpoint+"'", "pass an OpenTelemetry collector's HTTP URL, e.g. http://localhost:4318")
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+
+		tracer = NewTracer(endpoint)
+	}
+
+	var events *EventLogger
+	if useSyslog, _ := opts.Bool("--syslog"); useSyslog {
+		events, err = NewSyslogLogger()
+		if err != nil {
+			PrintCliError("could not connect to syslog: "+err.Error(), "check syslog or journald is running, or omit --syslog")
+			return ReplitArgs{}, EXIT_BAD_ARGS
+		}
+	}
+
+	charset, _ := opts.String("--charset")
+	if _, err := NewCharsetWriter(ioutil.Discard, charset); err != nil {
+		PrintCliError("unknown --charset '"+charset+"'", "use an encoding name such as utf-8, latin1, or shift_jis")
+		return ReplitArgs{}, EXIT_BAD_ARGS
+	}
+
+	// the file is opened, created, or fetched once the other arguments are known to be valid, so
+	// invalid arguments don't leave a scratch file behind
+	targetFile, err := &EditorFile{}, nil
+	if !hook {
+		targetFile, err = TargetFile(file, lang)
+	}
+
+	if err != nil {
+		PrintCliError("could not open "+file+": "+err.Error(), "check the file exists and is readable, or that the URL is reachable")
+		return ReplitArgs{}, EXIT_BAD_FILE
+	}
+
+	// the scratch file is private to its creator; let the other user read it
+	if credential != nil && targetFile.IsTempFile {
+		targetFile.File.Chmod(0644)
+	}
+
+	return ReplitArgs{
+		EditorFile:    targetFile,
+		Dpath:         dpath,
+		Lang:          lang,
+		OnChange:      onChange,
+		Combined:      combined,
+		Charset:       charset,
+		RawCR:         rawCR,
+		KeepScroll:    keepScroll,
+		RuntimeNotes:  runtimeNotes,
+		Linear:        linear || noTUI,
+		NoMouse:       noMouse,
+		Batch:         batch,
+		Jobs:          jobs,
+		MaxFiles:      maxFiles,
+		WatchAll:      watchAll,
+		Imports:       imports,
+		Credential:    credential,
+		NoNetwork:     noNetwork,
+		Sandbox:       sandbox,
+		Nix:           nix,
+		Direnv:        direnv,
+		Dotenv:        dotenv,
+		DotenvPath:    dotenvPath,
+		SnapshotEvery: snapshotEvery,
+		SnapshotDir:   snapshotDir,
+		JUnitPath:     junitPath,
+		Session:       session,
+		Retries:       retries,
+		RetryDelay:    retryDelay,
+		Every:         every,
+		Schedules:     schedules,
+		Git:           git,
+		Hook:          hook,
+		CI:            ci,
+		ProfileRun:    profileRun,
+		ProfileOutput: profileOutput,
+		TraceOutput:   traceOutput,
+		Sanitize:      sanitize,
+		Valgrind:      valgrind,
+		NoBuildCache:  noBuildCache,
+		BuildServer:   buildServer,
+		Warm:          warm,
+		Timeout:       timeout,
+		DetachOnExit:  detachOnExit,
+		Share:         share,
+		Focus:         focus,
+		Stdin:         stdin,
+		Recorder:      recorder,
+		Tracer:        tracer,
+		FPS:           fps,
+		Events:        events,
+		SQL:           sqlClient,
+		Nice:          nice,
+		IONice:        ionice,
+		Env:           env,
+		Config:        config,
+		Profile:       profile,
+		Options:       SessionOptions(opts),
+	}, -1
+}
+
+type LanguageState struct {
+	Lock      sync.Mutex
+	Cmd       *exec.Cmd
+	Stdin     io.WriteCloser
+	Running   bool
+	Pending   bool
+	Killed    bool
+	TimedOut  bool
+	ChangedAt time.Time
+}
+
+// Run until an attempt succeeds, is interrupted, or the retries run out, waiting twice as long before
+// each retry as the last, up to a limit. Runs that were killed or superseded by a file change, during
+// the attempt or the wait after it, aren't retried. Returns the final attempt, if it ran
+func RetryRun(retries int, delay time.Duration, run func(attempt int) (RunResult, bool), interrupted func() bool, sleep func(time.Duration)) (RunResult, bool) {
+	for attempt := 0; ; attempt++ {
+		result, ran := run(attempt)
+		if !ran {
+			return result, false
+		}
+
+		if result.Succeeded() || interrupted() || attempt >= retries {
+			return result, true
+		}
+
+		sleep(delay)
+
+		if interrupted() {
+			return result, true
+		}
+
+		if delay *= 2; delay > MAX_RETRY_DELAY {
+			delay = MAX_RETRY_DELAY
+		}
+	}
+}
+
+func RunLanguage(args *ReplitArgs, tui *TUI, state *LanguageState) {
+	// kill the running process
+	killProcess := func() {
+		state.Lock.Lock()
+		defer state.Lock.Unlock()
+
+		state.Killed = true
+
+		if state.Cmd != nil {
+			KillProcess(state.Cmd)
+		}
+	}
+
+	// run the language against the file once, and update stdout. Reports whether the program ran
+	runOnce := func() (RunResult, bool) {
+		// trace the run from the change that caused it; retries and reruns start from now
+		state.Lock.Lock()
+		changedAt := state.ChangedAt
+		state.ChangedAt = time.Time{}
+		state.Lock.Unlock()
+
+		if changedAt.IsZero() {
+			changedAt = time.Now()
+		}
+
+		filesChanged := tui.TakeChangedFiles()
+
+		trace := args.Tracer.StartRun(changedAt)
+
+		// clear stdout
+		tui.ClearOutput()
+
+		// re-resolve the file, in case it was replaced since the last run
+		if err := args.EditorFile.Reopen(); err != nil {
+			tui.ReportError(err)
+			return RunResult{}, false
+		}
+
+		// remember the file's content, so the change can be undone and the run's code recovered
+		if content, err := ioutil.ReadFile(args.EditorFile.File.Name()); err == nil {
+			tui.versions.Record(content)
+			tui.source = content
+		}
+
+		var stdinContent []byte
+		if args.Stdin != nil {
+			content, err := args.Stdin.Content()
+			if err != nil {
+				tui.ReportError(fmt.Errorf("could not fetch --stdin-url: %v", err))
+				return RunResult{}, false
+			}
+
+			stdinContent = content
+		}
+
+		// output is recorded and persisted as the program wrote it, and only decoded from its charset where
+		// it's shown as text. The charset was checked when reading arguments
+		var decoders []io.WriteCloser
+		decode := func(dst io.Writer) io.Writer {
+			decoder, err := NewCharsetWriter(dst, args.Charset)
+			if err != nil {
+				return dst
+			}
+
+			decoders = append(decoders, decoder)
+			return decoder
+		}
+
+		// the views are written through bounded queues, so slow views never block the program; the complete
+		// output is recorded for history and parsers, and files get all of it too
+		stdoutPipe := NewOutputPipe(io.MultiWriter(tui.stdoutBuffer, decode(tui.combined.Stream(STDOUT_PREFIX))), OUTPUT_QUEUE_BYTES)
+		stderrPipe := NewOutputPipe(io.MultiWriter(tui.stderrBuffer, decode(tui.combined.Stream(STDERR_PREFIX))), OUTPUT_QUEUE_BYTES)
+
+		stdoutDsts := []io.Writer{tui.stdoutBuffer.Recorder(), stdoutPipe}
+		stderrDsts := []io.Writer{tui.stderrBuffer.Recorder(), stderrPipe}
+
+		// linear sessions print output as it arrives
+		if tui.linear != nil {
+			stdoutDsts = append(stdoutDsts, decode(tui.linear.Writer(LINEAR_STDOUT)))
+			stderrDsts = append(stderrDsts, decode(tui.linear.Writer(LINEAR_STDERR)))
+		}
+
+		// stream output to disk as it arrives, so it survives a crash
+		if args.Session != nil {
+			stdoutFile, stderrFile, err := args.Session.RunFiles(tui.RunCount() + 1)
+			if err != nil {
+				tui.ReportError(fmt.Errorf("could not persist output: %v", err))
+			} else {
+				defer stdoutFile.Close()
+				defer stderrFile.Close()
+
+				stdoutDsts = append(stdoutDsts, stdoutFile)
+				stderrDsts = append(stderrDsts, stderrFile)
+			}
+
+			if args.Stdin != nil {
+				if err := args.Session.SaveStdin(tui.RunCount()+1, stdinContent); err != nil {
+					tui.ReportError(fmt.Errorf("could not persist stdin: %v", err))
+				}
+			}
+		}
+
+		if args.Recorder != nil {
+			args.Recorder.StartRun(tui.RunCount() + 1)
+
+			stdoutDsts = append(stdoutDsts, decode(args.Recorder.Writer("")))
+			stderrDsts = append(stderrDsts, decode(args.Recorder.Writer(CAST_RED)))
+		}
+
+		stdout := io.MultiWriter(stdoutDsts...)
+		stderr := io.MultiWriter(stderrDsts...)
+
+		before := SnapshotDirectory(args.Dpath)
+
+		startCommandTime := time.Now()
+		trace.Span("debounce", changedAt, startCommandTime, nil, false)
+
+		done := make(chan bool)
+		tui.MarkRunning()
+
+		go func() {
+			for {
+				select {
+				case <-done:
+					return
+				case <-time.After(time.Second / time.Duration(args.FPS)):
+				}
+
+				// output arriving between frames is written to the views at once
+				tui.FlushOutput()
+				tui.UpdateRunTime(time.Since(startCommandTime))
+				tui.app.Draw()
+			}
+		}()
+
+		// preserve partial output of long runs, in case they are killed
+		if args.SnapshotEvery > 0 {
+			go func() {
+				for {
+					select {
+					case <-done:
+						return
+					case <-time.After(args.SnapshotEvery):
+					}
+
+					tui.SnapshotRun()
+				}
+			}()
+		}
+
+		result := RunResult{StartedAt: startCommandTime, ExitCode: -1, FilesChanged: filesChanged}
+
+		// kill runs that overrun the timeout, keeping their output so far
+		state.Lock.Lock()
+		state.TimedOut = false
+		state.Lock.Unlock()
+
+		if args.Timeout > 0 {
+			timeout := time.AfterFunc(args.Timeout, func() {
+				state.Lock.Lock()
+				defer state.Lock.Unlock()
+
+				state.TimedOut = true
+				if state.Cmd != nil {
+					KillProcess(state.Cmd)
+				}
+			})
+			defer timeout.Stop()
+		}
+
+		// a detachable program writes to log files directly, so it can outlive replit
+		var logs *DetachLogs
+		if args.DetachOnExit {
+			var err error
+			if logs, err = OpenDetachLogs(args.DetachDir, stdout, stderr); err != nil {
+				tui.ReportError(fmt.Errorf("could not open log files; the program won't outlive replit: %v", err))
+			}
+		}
+
+		// call the language against the file, or each step of the pipeline until one fails
+		for _, command := range Pipeline(args, []string{args.EditorFile.File.Name()}) {
+			cmd := command.Cmd
+
+			var cmdStdout, cmdStderr io.Writer = stdout, stderr
+			if logs != nil {
+				cmdStdout, cmdStderr = logs.Stdout, logs.Stderr
+			}
+
+			// steps writing SARIF keep their own output, to find their problems in
+			var stepStdout, stepStderr bytes.Buffer
+			if len(command.Sarif) > 0 {
+				cmdStdout = io.MultiWriter(cmdStdout, &stepStdout)
+				cmdStderr = io.MultiWriter(cmdStderr, &stepStderr)
+			}
+
+			// interpreters started ahead of the run were configured as they started
+			var stdin io.WriteCloser
+			if command.Warm == nil {
+				cmd.Stdout = cmdStdout
+				cmd.Stderr = cmdStderr
+				cmd.Env = append(ChildEnv(args), command.Env...)
+				ConfigureProcess(cmd, args)
+
+				// a shared session's partner may send the program input
+				if len(args.Share) > 0 {
+					stdin, _ = cmd.StdinPipe()
+				} else if args.Stdin != nil {
+					cmd.Stdin = bytes.NewReader(stdinContent)
+				}
+			}
+
+			// start under the lock, so a kill never sees a half-started process
+			commandStart := time.Now()
+
+			state.Lock.Lock()
+			var err error
+			if command.Warm != nil {
+				err = command.Warm.Begin(cmdStdout, cmdStderr)
+			} else {
+				err = StartProcess(cmd)
+			}
+			if err == nil {
+				state.Cmd = cmd
+				state.Stdin = stdin
+			}
+			state.Lock.Unlock()
+
+			if err != nil {
+				tui.ReportError(fmt.Errorf("could not run %s: %v", command.Name, err))
+				result.ExitCode = -1
+				break
+			}
+
+			tui.ClearError()
+
+			if err := PrioritizeProcess(cmd, args); err != nil {
+				tui.ReportError(fmt.Errorf("could not lower %s's priority: %v", command.Name, err))
+			}
+
+			cmd.Wait()
+
+			state.Lock.Lock()
+			state.Stdin = nil
+			state.Lock.Unlock()
+
+			if len(command.Sarif) > 0 {
+				if err := WriteStepSARIF(args, command, stepStdout.Bytes(), stepStderr.Bytes()); err != nil {
+					tui.ReportError(fmt.Errorf("could not write %s's SARIF: %v", command.Name, err))
+				}
+			}
+
+			result.ExitCode = cmd.ProcessState.ExitCode()
+			result.Signal = ProcessSignal(cmd.ProcessState)
+
+			if result.Succeeded() && command.OnSuccess != nil {
+				if err := command.OnSuccess(); err != nil {
+					tui.ReportError(fmt.Errorf("could not finish %s: %v", command.Name, err))
+				}
+			}
+
+			trace.Span("exec", commandStart, time.Now(), map[string]interface{}{
+				"replit.step":      command.Name,
+				"replit.exit_code": result.ExitCode,
+			}, !result.Succeeded())
+			result.UserTime += cmd.ProcessState.UserTime()
+			result.SysTime += cmd.ProcessState.SystemTime()
+
+			if rss := PeakMemory(cmd.ProcessState); rss > result.MaxRSS {
+				result.MaxRSS = rss
+			}
+
+			if !result.Succeeded() {
+				break
+			}
+		}
+
+		if logs != nil {
+			logs.Close()
+		}
+
+		stdoutPipe.Close()
+		stderrPipe.Close()
+
+		// flush any incomplete sequences once the output has drained
+		for _, decoder := range decoders {
+			decoder.Close()
+		}
+
+		result.Duration = time.Since(startCommandTime)
+		completionStart := time.Now()
+		close(done)
+
+		state.Lock.Lock()
+		result.TimedOut = state.TimedOut
+		state.Lock.Unlock()
+
+		tui.ShowFooter(result)
+		tui.DetectTAP()
+		tui.RefreshOutput()
+
+		tui.ShowArtifacts(ChangedFiles(before, SnapshotDirectory(args.Dpath)), args.Dpath)
+
+		// programs can also name an image to preview, such as one written outside the directory
+		if fpath := ImageSentinel(string(tui.stdoutBuffer.Bytes()), args.Dpath); len(fpath) > 0 {
+			tui.PreviewImage(fpath, args.Dpath)
+		}
+
+		// list problems reported to stderr, by sanitizers or valgrind, and found by the config's matchers
+		problems := StepProblems(args, tui.stdoutBuffer.Bytes(), tui.stderrBuffer.Bytes())
+
+		if len(args.Sanitize) > 0 || args.Valgrind {
+			problems = append(problems, CollectDiagnostics()...)
+		}
+
+		tui.ShowProblems(problems)
+
+		if len(args.ProfileRun) > 0 {
+			if entries, err := ProfileSummary(args.ProfileRun, args.ProfileOutput); err != nil {
+				tui.ReportError(fmt.Errorf("could not read profile: %v", err))
+			} else {
+				tui.ShowProfile(entries)
+			}
+		}
+
+		if len(args.TraceOutput) > 0 {
+			if summary, err := TraceSummary(args.TraceOutput); err != nil {
+				tui.ReportError(fmt.Errorf("could not read syscall trace: %v", err))
+			} else {
+				tui.syscallViewer.SetText(summary.String())
+			}
+		}
+
+		state.Lock.Lock()
+		state.Cmd = nil
+		state.Lock.Unlock()
+
+		trace.Span("completion", completionStart, time.Now(), nil, false)
+		trace.Finish(result, args.EditorFile.File.Name(), tui.ReportError)
+
+		return result, true
+	}
+
+	// run, retrying failures, and record the final attempt
+	runWithRetries := func() {
+		state.Lock.Lock()
+		state.Killed = false
+		state.Lock.Unlock()
+
+		attempt := func(attempt int) (RunResult, bool) {
+			tui.SetAttempt(attempt, args.Retries)
+			return runOnce()
+		}
+
+		interrupted := func() bool {
+			state.Lock.Lock()
+			defer state.Lock.Unlock()
+
+			return state.Killed || state.Pending
+		}
+
+		if result, ran := RetryRun(args.Retries, args.RetryDelay, attempt, interrupted, time.Sleep); ran {
+			tui.RecordRun(result)
+			tui.app.Draw()
+		}
+	}
+
+	// keep running while changes were queued during the previous run
+	runPending := func() {
+		for {
+			runWithRetries()
+
+			state.Lock.Lock()
+			if !state.Pending {
+				state.Running = false
+				state.Lock.Unlock()
+				return
+			}
+
+			state.Pending = false
+			state.Lock.Unlock()
+		}
+	}
+
+	// update stdout, applying the overlap policy if a run is in progress
+	onFileChange := func() {
+		state.Lock.Lock()
+		defer state.Lock.Unlock()
+
+		// the first change a run handles is when its trace starts; skipped changes aren't handled
+		if state.ChangedAt.IsZero() && !(state.Running && args.OnChange == ON_CHANGE_SKIP) {
+			state.ChangedAt = time.Now()
+		}
+
+		if state.Running {
+			switch args.OnChange {
+			case ON_CHANGE_QUEUE:
+				// coalesce into a single pending run
+				state.Pending = true
+			case ON_CHANGE_RESTART:
+				state.Pending = true
+
+				if state.Cmd != nil {
+					KillProcess(state.Cmd)
+				}
+			case ON_CHANGE_SKIP:
+				// drop the change; the in-flight run wins
+			}
+
+			return
+		}
+
+		state.Running = true
+		go runPending()
+	}
+
+	// run on process-kill
+	attachListener(tui.actions.killProcess, killProcess)
+
+	// run on file-change
+	attachListener(tui.actions.fileChange, onFileChange)
+}
+
+// Rerun the session watching a directory
+func RunTrigger(opts docopt.Opts) int {
+	dir, _ := opts.String("--directory")
+	if len(dir) == 0 {
+		dir, _ = os.Getwd()
+	}
+
+	dpath, err := filepath.Abs(dir)
+	if err != nil {
+		println("replit: failed to resolve directory path")
+		return EXIT_BAD_ARGS
+	}
+
+	if remote, _ := opts.String("--remote"); len(remote) > 0 {
+		if err := SendRemote(remote, "/trigger", nil); err != nil {
+			PrintCliError("could not trigger "+remote+": "+err.Error(), "check the session is shared at that address, and $REPLIT_TOKEN matches its token")
+			return EXIT_REMOTE_FAILED
+		}
+
+		return 0
+	}
+
+	if err := SendTrigger(dpath); err != nil {
+		PrintCliError(err.Error(), "start replit in that directory, or pass its --directory")
+		return EXIT_NO_SESSION
+	}
+
+	return 0
+}
+
+// Send input read from stdin to the program running in a shared session
+func RunStdin(opts docopt.Opts) int {
+	remote, _ := opts.String("--remote")
+
+	if err := SendRemote(remote, "/stdin", os.Stdin); err != nil {
+		PrintCliError("could not send input to "+remote+": "+err.Error(), "check the session is shared at that address, and $REPLIT_TOKEN matches its token")
+		return EXIT_REMOTE_FAILED
+	}
+
+	return 0
+}
+
+// Render a session's latest runs as an animated SVG
+func RunSVG(opts docopt.Opts) int {
+	dir, _ := opts.String("<session>")
+	output, _ := opts.String("<output>")
+	value, _ := opts.String("--runs")
+
+	count, err := strconv.Atoi(value)
+	if err != nil || count <= 0 {
+		PrintCliError("invalid --runs '"+value+"'", "pass how many of the latest runs to render, e.g. 5")
+		return EXIT_BAD_ARGS
+	}
+
+	entries, err := LoadSessionRuns(dir)
+	if err == nil && len(entries) == 0 {
+		err = errors.New("it has no finished runs")
+	}
+
+	if err != nil {
+		PrintCliError("could not read the session "+dir+": "+err.Error(), "pass a session directory written with --persist")
+		return EXIT_BAD_FILE
+	}
+
+	if len(entries) > count {
+		entries = entries[len(entries)-count:]
+	}
+
+	if err := ioutil.WriteFile(output, []byte(RenderSVG(entries)), 0644); err != nil {
+		PrintCliError("could not write "+output+": "+err.Error(), "pass a writable output path")
+		return EXIT_BAD_FILE
+	}
+
+	return 0
+}
+
+// Bundle a session into an archive
+func RunExport(opts docopt.Opts) int {
+	archive, _ := opts.String("<archive>")
+	dir, _ := opts.String("--session")
+
+	if len(dir) == 0 {
+		root, err := DefaultSessionRoot()
+		if err == nil {
+			dir, err = LatestSession(root)
+		}
+
+		if err != nil {
+			PrintCliError("could not find a session to export: "+err.Error(), "pass its --session directory")
+			return EXIT_BAD_FILE
+		}
+	}
+
+	if err := ExportSession(dir, archive); err != nil {
+		PrintCliError("could not export "+dir+": "+err.Error(), "pass a session directory written with --persist")
+		return EXIT_BAD_FILE
+	}
+
+	println("replit: exported " + dir + " to " + archive)
+	return 0
+}
+
+// Extract an exported session, and restart it
+func RunImport(opts docopt.Opts) int {
+	archive, _ := opts.String("<archive>")
+	dir, _ := opts.String("--directory")
+	if len(dir) == 0 {
+		dir, _ = os.Getwd()
+	}
+
+	dpath, err := filepath.Abs(dir)
+	if err != nil {
+		println("replit: failed to resolve directory path")
+		return EXIT_BAD_ARGS
+	}
+
+	manifest, err := ImportSession(archive, dpath)
+	if err != nil {
+		PrintCliError("could not import "+archive+": "+err.Error(), "pass an archive written by 'replit export'")
+		return EXIT_BAD_FILE
+	}
+
+	sessionOpts, err := docopt.ParseArgs(Usage, manifest.Argv(dpath), "")
+	if err != nil {
+		PrintCliError("could not restart the imported session: "+err.Error(), "run it with: replit "+manifest.Command)
+		return EXIT_BAD_ARGS
+	}
+
+	return ReplIt(sessionOpts)
+}
+
+// Core application
+func ReplIt(opts docopt.Opts) int {
+	if trigger, _ := opts.Bool("trigger"); trigger {
+		return RunTrigger(opts)
+	}
+
+	if stdin, _ := opts.Bool("stdin"); stdin {
+		return RunStdin(opts)
+	}
+
+	if svg, _ := opts.Bool("svg"); svg {
+		return RunSVG(opts)
+	}
+
+	if export, _ := opts.Bool("export"); export {
+		return RunExport(opts)
+	}
+
+	if imported, _ := opts.Bool("import"); imported {
+		return RunImport(opts)
+	}
+
+	if tabbed, _ := opts.Bool("tabs"); tabbed {
+		return RunTabs(opts)
+	}
+
+	// read and validate arguments
+	started := time.Now()
+
+	args, exitCode := ReadArgs(opts)
+	if exitCode >= 0 {
+		return exitCode
+	}
+
+	if timings, _ := opts.Bool("--timings"); timings {
+		args.Timings = NewStartupTimings(started)
+		args.Timings.Record("arguments", started)
+	}
+
+	if args.Hook || args.CI {
+		began := time.Now()
+
+		if args.Hook {
+			exitCode = RunHook(&args)
+		} else {
+			exitCode = RunHeadless(&args, []string{args.EditorFile.File.Name()})
+		}
+
+		if args.Timings.Record("run", began) {
+			println("replit: startup timings: " + args.Timings.String())
+		}
+
+		return exitCode
+	}
+
+	// record how the session was started, so it can be exported
+	if args.Session != nil {
+		args.Session.SaveManifest(NewSessionManifest(&args, args.DotenvPath))
+	}
+
+	began := time.Now()
+
+	tui := NewUI(&args)
+
+	tui.SetTheme()
+	args.Timings.Record("interface", began)
+
+	stopped := make(chan bool, 1)
+	go func(tui *TUI) {
+		tui.Start(&args)
+		stopped <- true
+	}(tui)
+
+	session := StartSession(&args, tui)
+
+	// Terminate program when an exit signal is received or the interface is quit, and tidy up
+	// termporary files and processes
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case <-sigs:
+	case <-stopped:
+	}
+	signal.Stop(sigs)
+
+	if message := session.Stop(tui.app.Stop); len(message) > 0 {
+		fmt.Print(message)
+	}
+
+	if args.Timings != nil {
+		println("replit: startup timings: " + args.Timings.String())
+	}
+
+	return 0
+}
+
+// A session's editor, file watcher, and program, and what to tidy up once it stops
+type RunningSession struct {
+	args         *ReplitArgs
+	tui          *TUI
+	state        *LanguageState
+	editorChan   chan *exec.Cmd
+	stopTriggers func()
+	buildServer  *RunningBuildServer
+}
+
+// Launch the editor, watch the file, and run the language on each change, reporting to a TUI
+func StartSession(args *ReplitArgs, tui *TUI) *RunningSession {
+	editorChan := make(chan *exec.Cmd)
+
+	// launch an editor asyncronously
+	go LaunchEditor(editorChan, args.EditorFile, tui)
+
+	// start entr; read the file (and optionally a directory) and live-reload
+	state := LanguageState{}
+
+	began := time.Now()
+
+	fileWatcher, err := ObserveFileChanges(args, tui)
+	if err != nil {
+		tui.ReportError(err)
+	} else {
+		go fileWatcher.Start(tui)
+	}
+	args.Timings.Record("watcher", began)
+
+	// warm the build daemon up while the editor opens
+	buildServer := StartBuildServer(args, tui)
+
+	if args.Warm > 0 {
+		args.WarmPool = NewWarmPool(args, tui, args.Warm)
+	}
+
+	// detachable runs write their output to log files, in a directory only the user can read
+	if args.DetachOnExit {
+		if args.DetachDir, err = NewDetachDir(); err != nil {
+			tui.ReportError(fmt.Errorf("could not create a directory for log files; the program won't outlive replit: %v", err))
+			args.DetachOnExit = false
+		}
+	}
+
+	go RunLanguage(args, tui, &state)
+
+	if len(args.Batch) > 0 {
+		go RunBatches(args, tui, NewBatchState(args.Jobs))
+	}
+
+	if args.Every > 0 {
+		go RerunEvery(args.Every, tui)
+	}
+
+	for _, cron := range args.Schedules {
+		go RerunOnSchedule(cron, tui)
+	}
+
+	// let other terminals and scripts trigger a rerun
+	stopTriggers, err := ListenForTriggers(args.Dpath, tui.actions.fileChange.Broadcast)
+	if err != nil {
+		tui.ReportError(fmt.Errorf("could not listen for triggers; replit trigger won't reach this session: %v", err))
+	}
+
+	go RerunOnSignal(tui)
+
+	args.Events.Event("session_start", false, map[string]interface{}{
+		"dir":  args.Dpath,
+		"file": args.EditorFile.File.Name(),
+		"lang": args.Lang,
+		"pid":  os.Getpid(),
+	})
+
+	if len(args.Share) > 0 {
+		if token, err := ShareToken(); err != nil {
+			tui.ReportError(fmt.Errorf("could not share the session: %v", err))
+		} else {
+			tui.SetSharing(args.Share, token)
+
+			go func() {
+				if err := NewShareServer(token, tui, &state).Serve(args.Share); err != nil {
+					tui.ReportError(fmt.Errorf("could not share the session: %v", err))
+				}
+			}()
+		}
+	}
+
+	return &RunningSession{args, tui, &state, editorChan, stopTriggers, buildServer}
+}
+
+// Kill or detach the session's program, stop the UI, and tidy up temporary files and the editor.
+// Returns a message to print once the UI has stopped, if the program was left running
+func (session *RunningSession) Stop(stopUI func()) string {
+	args, tui, state := session.args, session.tui, session.state
+	message := ""
+
+	// leave the program running, or kill it along with replit
+	state.Lock.Lock()
+	detached := state.Cmd != nil && args.DetachOnExit
+	if detached {
+		stdoutLog, stderrLog := DetachLogPaths(args.DetachDir)
+		message = fmt.Sprintf("replit: left %s running as pid %d; its output is written to %s and %s\n", args.Lang, state.Cmd.Process.Pid, stdoutLog, stderrLog)
+	} else if state.Cmd != nil {
+		KillProcess(state.Cmd)
+	}
+	state.Lock.Unlock()
+
+	var doneGroup sync.WaitGroup
+	doneGroup.Add(2)
+
+	// close each channel
+
+	// kill editor
+	go func() {
+		defer doneGroup.Done()
+
+		editor := <-session.editorChan
+		if editor.Process != nil {
+			editor.Process.Kill()
+		}
+		close(session.editorChan)
+	}()
+
+	// remove temporary file
+	go func() {
+		defer doneGroup.Done()
+
+		targetFile := args.EditorFile
 
 		if targetFile.IsTempFile {
 			name := targetFile.File.Name()
 			os.Remove(name)
 		}
+
+		if args.DetachOnExit && !detached {
+			os.RemoveAll(args.DetachDir)
+		}
+
+		if session.stopTriggers != nil {
+			session.stopTriggers()
+		}
+
+		if args.Recorder != nil {
+			args.Recorder.Close()
+		}
+
+		args.Events.Event("session_end", false, map[string]interface{}{"runs": tui.RunCount()})
+		args.Events.Close()
+
+		if len(args.ProfileOutput) > 0 {
+			os.Remove(args.ProfileOutput)
+		}
+
+		if len(args.TraceOutput) > 0 {
+			os.Remove(args.TraceOutput)
+		}
+
+		if len(CompiledLanguage(args.Lang)) > 0 {
+			os.Remove(ScratchPath("build"))
+		}
+
+		if session.buildServer != nil {
+			session.buildServer.Stop(args)
+		}
+
+		if args.WarmPool != nil {
+			args.WarmPool.Close()
+		}
+
+		tui.history.Close()
 	}()
 
-	tui.app.Stop()
+	stopUI()
 	doneGroup.Wait()
 
-	return 0
+	return message
 }
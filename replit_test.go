@@ -1,7 +1,12 @@
 package main
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestGetEditor(t *testing.T) {
@@ -30,3 +35,316 @@ func TestGetEditor(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitLanguage(t *testing.T) {
+	tests := []struct {
+		name        string
+		language    string
+		wantProgram string
+		wantFlags   []string
+	}{
+		{
+			"Plain executable",
+			"python3",
+			"python3",
+			[]string{},
+		},
+		{
+			"Executable with flags",
+			"node --enable-source-maps  --trace-warnings",
+			"node",
+			[]string{"--enable-source-maps", "--trace-warnings"},
+		},
+		{
+			"Empty language",
+			"  ",
+			"",
+			[]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program, flags := SplitLanguage(tt.language)
+
+			if program != tt.wantProgram {
+				t.Errorf("SplitLanguage() program = %v, want %v", program, tt.wantProgram)
+			}
+			if !reflect.DeepEqual(flags, tt.wantFlags) {
+				t.Errorf("SplitLanguage() flags = %v, want %v", flags, tt.wantFlags)
+			}
+		})
+	}
+}
+
+func TestListDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replit-list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.Mkdir(filepath.Join(dir, "src"), 0755)
+	for _, name := range []string{"a.py", "b.py", "src/c.py", "dist.zip"} {
+		ioutil.WriteFile(filepath.Join(dir, name), []byte{}, 0644)
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "main"), []byte("\x7fELF\x00\x00"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "run"), []byte("#!/bin/sh\n"), 0755)
+	os.Truncate(filepath.Join(dir, "b.py"), WATCH_MAX_FILE_SIZE+1)
+
+	tests := []struct {
+		name       string
+		filter     WatchFilter
+		wantCount  int
+		wantCapped bool
+	}{
+		{"Skips binaries, archives, and oversized files", WatchFilter{}, 3, false},
+		{"Keeps the edited file, whatever its size", WatchFilter{Keep: filepath.Join(dir, "b.py")}, 4, false},
+		{"Lists every file with All", WatchFilter{All: true}, 6, false},
+		{"Lists every file within the limit", WatchFilter{Limit: 3}, 3, false},
+		{"Stops at the limit", WatchFilter{Limit: 2}, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, capped, err := ListDirectory(dir, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(*files) != tt.wantCount || capped != tt.wantCapped {
+				t.Errorf("ListDirectory() listed %v, capped %v; want %d files, %v", *files, capped, tt.wantCount, tt.wantCapped)
+			}
+		})
+	}
+}
+
+func TestListDirectoryIgnores(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replit-ignore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.Mkdir(filepath.Join(dir, "node_modules"), 0755)
+	os.Mkdir(filepath.Join(dir, "scratch"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, ".gitignore"), []byte("node_modules/\nscratch/\n*.log\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, ".replitignore"), []byte("!app.log\n"), 0644)
+
+	for _, name := range []string{"main.py", "debug.log", "app.log", "node_modules/x.js", "scratch/try.py"} {
+		ioutil.WriteFile(filepath.Join(dir, name), []byte{}, 0644)
+	}
+
+	files, _, err := ListDirectory(dir, WatchFilter{Keep: filepath.Join(dir, "scratch/try.py")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, fpath := range *files {
+		rel, _ := filepath.Rel(dir, fpath)
+		got = append(got, filepath.ToSlash(rel))
+	}
+
+	want := []string{".gitignore", ".replitignore", "app.log", "main.py", "scratch/try.py"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListDirectory() = %v, want %v", got, want)
+	}
+}
+
+func TestWatchLimitError(t *testing.T) {
+	tests := []struct {
+		name   string
+		stderr string
+		want   bool
+	}{
+		{"Recognises inotify watch exhaustion", "entr: kevent failed: No space left on device\n", true},
+		{"Recognises entr's open file limit", "entr: Too many files listed; the hard limit for your login class is 1024.\n", true},
+		{"Ignores other errors", "entr: cannot open 'x.py': Permission denied\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WatchLimitError(tt.stderr, 10) != nil; got != tt.want {
+				t.Errorf("WatchLimitError() = %v, want an error %v", WatchLimitError(tt.stderr, 10), tt.want)
+			}
+		})
+	}
+}
+
+func TestSettle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replit-settle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"a.py", "b.py", "c.py"} {
+		ioutil.WriteFile(filepath.Join(dir, name), []byte{}, 0644)
+	}
+
+	watch := FileWatcher{Dpath: dir}
+	if err := watch.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	before := watch.Stamps()
+
+	// a burst that creates, modifies, and removes files
+	ioutil.WriteFile(filepath.Join(dir, "a.py"), []byte("print(1)"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "d.py"), []byte{}, 0644)
+	os.Remove(filepath.Join(dir, "b.py"))
+
+	if got := watch.Settle(before); len(got) != 3 {
+		t.Errorf("Settle() = %v, want 3 files", got)
+	}
+}
+
+func TestWithExtraFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		extra []string
+		want  []string
+	}{
+		{"Keeps files without extra files", []string{"a.py", "b.py"}, nil, []string{"a.py", "b.py"}},
+		{"Appends extra files", []string{"a.py"}, []string{".git/HEAD"}, []string{"a.py", ".git/HEAD"}},
+		{"Leaves out extra files already watched", []string{"a.py", ".git/HEAD"}, []string{".git/HEAD", ".git/index"}, []string{"a.py", ".git/HEAD", ".git/index"}},
+		{"Leaves out repeated files", []string{"a.py", "a.py"}, []string{"b.py", "b.py"}, []string{"a.py", "b.py"}},
+		{"Returns nothing for no files", nil, nil, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WithExtraFiles(tt.files, tt.extra); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WithExtraFiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditorFileDiscard(t *testing.T) {
+	dir := t.TempDir()
+
+	existing := filepath.Join(dir, "existing.py")
+	if err := ioutil.WriteFile(existing, []byte("print(1)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		file string
+		kept bool
+	}{
+		{"Keeps an existing file", existing, true},
+		{"Removes a file it created", filepath.Join(dir, "new.py"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := TargetFile(tt.file, "python3")
+			if err != nil {
+				t.Fatalf("TargetFile() error = %v", err)
+			}
+
+			target.Discard()
+
+			if _, err := os.Stat(tt.file); (err == nil) != tt.kept {
+				t.Errorf("file exists = %v after discarding, want %v", err == nil, tt.kept)
+			}
+		})
+	}
+}
+
+func TestContentChanged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replit-hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	same := filepath.Join(dir, "same.py")
+	edited := filepath.Join(dir, "edited.py")
+	created := filepath.Join(dir, "created.py")
+
+	ioutil.WriteFile(same, []byte("print(1)"), 0644)
+	ioutil.WriteFile(edited, []byte("print(1)"), 0644)
+
+	watch := FileWatcher{Files: &[]string{same, edited}}
+	watch.RecordHashes()
+
+	// rewrite one file identically, change another, and create a third
+	ioutil.WriteFile(same, []byte("print(1)"), 0644)
+	ioutil.WriteFile(edited, []byte("print(2)"), 0644)
+	ioutil.WriteFile(created, []byte{}, 0644)
+
+	want := []string{edited, created}
+	if got := watch.ContentChanged([]string{same, edited, created}); !reflect.DeepEqual(got, want) {
+		t.Errorf("ContentChanged() = %v, want %v", got, want)
+	}
+
+	// once recorded, unchanged files are skipped
+	if got := watch.ContentChanged([]string{edited, created}); len(got) != 0 {
+		t.Errorf("ContentChanged() = %v, want none", got)
+	}
+}
+
+func TestRetryRun(t *testing.T) {
+	never := -1
+
+	tests := []struct {
+		name string
+		// the attempt that succeeds, the attempt during which the run is killed or a change is pending,
+		// and the wait during which it is
+		retries, succeedOn, interruptOn, interruptWait int
+		delay                                          time.Duration
+		wantAttempts                                   int
+		wantWaits                                      []time.Duration
+	}{
+		{"Doesn't retry successful runs", 3, 0, never, never, time.Second, 1, nil},
+		{"Doesn't retry without retries", 0, never, never, never, time.Second, 1, nil},
+		{"Stops retrying once a run succeeds", 3, 1, never, never, time.Second, 2, []time.Duration{time.Second}},
+		{"Doubles the delay before each retry", 3, never, never, never, time.Second, 4, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}},
+		{"Limits the delay", 3, never, never, never, 40 * time.Second, 4, []time.Duration{40 * time.Second, MAX_RETRY_DELAY, MAX_RETRY_DELAY}},
+		{"Doesn't retry killed runs", 3, never, 0, never, time.Second, 1, nil},
+		{"Cancels retries on a change during the delay", 3, never, never, 1, time.Second, 2, []time.Duration{time.Second, 2 * time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			var waits []time.Duration
+			interrupted := false
+
+			run := func(attempt int) (RunResult, bool) {
+				attempts++
+				interrupted = attempt == tt.interruptOn
+
+				if attempt == tt.succeedOn {
+					return RunResult{}, true
+				}
+				return RunResult{ExitCode: 1}, true
+			}
+
+			sleep := func(delay time.Duration) {
+				interrupted = len(waits) == tt.interruptWait
+				waits = append(waits, delay)
+			}
+
+			result, ran := RetryRun(tt.retries, tt.delay, run, func() bool { return interrupted }, sleep)
+
+			if !ran {
+				t.Fatal("expected the final attempt to have run")
+			}
+
+			if attempts != tt.wantAttempts || !reflect.DeepEqual(waits, tt.wantWaits) {
+				t.Errorf("RetryRun() made %d attempts, waiting %v; want %d, waiting %v", attempts, waits, tt.wantAttempts, tt.wantWaits)
+			}
+
+			if succeeded := tt.succeedOn == attempts-1; result.Succeeded() != succeeded {
+				t.Errorf("RetryRun() returned %+v, want the final attempt's result", result)
+			}
+		})
+	}
+
+	// runs that were skipped aren't recorded
+	skipped := func(attempt int) (RunResult, bool) { return RunResult{}, false }
+	if _, ran := RetryRun(3, time.Second, skipped, func() bool { return false }, func(time.Duration) {}); ran {
+		t.Error("expected a skipped run not to be recorded")
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// How a sandboxed run may touch the filesystem
+type SandboxProfile struct {
+	WritableHome bool     `json:"writable_home"`
+	TmpfsWorkdir bool     `json:"tmpfs_workdir"`
+	Args         []string `json:"args"`
+}
+
+// Check a sandbox is supported and installed, and that its profile can be honoured
+func ValidateSandbox(sandbox string, profile SandboxProfile) error {
+	switch sandbox {
+	case SANDBOX_BWRAP:
+	case SANDBOX_FIREJAIL:
+		if profile.TmpfsWorkdir {
+			return errors.New("tmpfs_workdir is only supported with " + SANDBOX_BWRAP)
+		}
+	default:
+		return fmt.Errorf("unknown sandbox '%s'; expected %s or %s", sandbox, SANDBOX_BWRAP, SANDBOX_FIREJAIL)
+	}
+
+	if !CommandExists(sandbox) {
+		return errors.New(sandbox + " is not in PATH")
+	}
+
+	return nil
+}
+
+// Wrap a command line in bubblewrap; the root filesystem is read-only, /tmp is a fresh tmpfs,
+// and the watched directory is writable unless the run gets its own tmpfs working directory
+func bwrapArgs(profile SandboxProfile, dpath string, fpath string) []string {
+	argv := []string{
+		SANDBOX_BWRAP, "--die-with-parent",
+		"--ro-bind", "/", "/",
+		"--dev", "/dev",
+		"--proc", "/proc",
+		"--tmpfs", "/tmp",
+	}
+
+	if profile.TmpfsWorkdir {
+		argv = append(argv, "--ro-bind", dpath, dpath, "--tmpfs", SANDBOX_WORKDIR, "--chdir", SANDBOX_WORKDIR)
+	} else {
+		argv = append(argv, "--bind", dpath, dpath, "--chdir", dpath)
+	}
+
+	if home, err := os.UserHomeDir(); err == nil && profile.WritableHome {
+		argv = append(argv, "--bind", home, home)
+	}
+
+	// the file may live in the host's /tmp, which the sandbox replaced
+	if abs, err := filepath.Abs(fpath); err == nil {
+		argv = append(argv, "--ro-bind", abs, abs)
+	}
+
+	return append(append(argv, profile.Args...), "--")
+}
+
+// Wrap a command line in firejail, with a read-only home unless the profile allows writes
+func firejailArgs(profile SandboxProfile, dpath string) []string {
+	argv := []string{SANDBOX_FIREJAIL, "--quiet", "--noprofile"}
+
+	if home, err := os.UserHomeDir(); err == nil && !profile.WritableHome {
+		argv = append(argv, "--read-only="+home, "--read-write="+dpath)
+	}
+
+	return append(append(argv, profile.Args...), "--")
+}
+
+// Prefix a command line with the sandbox it should run in, if any
+func SandboxCommand(sandbox string, profile SandboxProfile, dpath string, fpath string, argv []string) []string {
+	switch sandbox {
+	case SANDBOX_BWRAP:
+		return append(bwrapArgs(profile, dpath, fpath), argv...)
+	case SANDBOX_FIREJAIL:
+		return append(firejailArgs(profile, dpath), argv...)
+	}
+
+	return argv
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSandboxCommand(t *testing.T) {
+	argv := []string{"python3", "/work/main.py"}
+
+	tests := []struct {
+		name    string
+		sandbox string
+		profile SandboxProfile
+		want    []string
+	}{
+		{
+			"Runs unwrapped without a sandbox",
+			"",
+			SandboxProfile{},
+			argv,
+		},
+		{
+			"Gives bwrap runs a tmpfs working directory",
+			SANDBOX_BWRAP,
+			SandboxProfile{TmpfsWorkdir: true},
+			[]string{
+				"bwrap", "--die-with-parent", "--ro-bind", "/", "/", "--dev", "/dev", "--proc", "/proc", "--tmpfs", "/tmp",
+				"--ro-bind", "/work", "/work", "--tmpfs", SANDBOX_WORKDIR, "--chdir", SANDBOX_WORKDIR,
+				"--ro-bind", "/work/main.py", "/work/main.py", "--", "python3", "/work/main.py",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SandboxCommand(tt.sandbox, tt.profile, "/work", "/work/main.py", argv)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SandboxCommand() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name string `json:"name"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId,omitempty"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifact `json:"artifactLocation"`
+	Region           *sarifRegion  `json:"region,omitempty"`
+}
+
+type sarifArtifact struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine,omitempty"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// Render problems a tool found as a SARIF log, for code-scanning tools. Files are made relative to root
+func SARIFReport(tool string, problems []Problem, root string) ([]byte, error) {
+	results := []sarifResult{}
+
+	for _, problem := range problems {
+		result := sarifResult{
+			RuleID:  problem.Source,
+			Level:   ProblemLevel(problem),
+			Message: sarifMessage{problem.Message},
+		}
+
+		if len(problem.File) > 0 {
+			location := sarifPhysicalLocation{ArtifactLocation: sarifArtifact{RelativeProblemFile(problem.File, root)}}
+			if problem.Line > 0 {
+				location.Region = &sarifRegion{problem.Line, problem.Column}
+			}
+
+			result.Locations = []sarifLocation{{location}}
+		}
+
+		results = append(results, result)
+	}
+
+	log := sarifLog{
+		Version: SARIF_VERSION,
+		Schema:  SARIF_SCHEMA,
+		Runs:    []sarifRun{{Tool: sarifTool{sarifDriver{tool}}, Results: results}},
+	}
+
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, '\n'), nil
+}
+
+// Write the problems a pipeline step's output reports to its "sarif" path, relative to the watched directory
+func WriteStepSARIF(args *ReplitArgs, command PipelineCommand, stdout []byte, stderr []byte) error {
+	data, err := SARIFReport(command.Name, StepProblems(args, stdout, stderr), args.Dpath)
+	if err != nil {
+		return err
+	}
+
+	fpath := command.Sarif
+	if !filepath.IsAbs(fpath) {
+		fpath = filepath.Join(args.Dpath, fpath)
+	}
+
+	return ioutil.WriteFile(fpath, data, 0644)
+}
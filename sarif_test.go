@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSARIFReport(t *testing.T) {
+	problems := []Problem{
+		{"lint", "/work/repo/main.py", 3, 5, "unused import", 2},
+		{"lint", "", 0, 0, "warning: no config found", 1},
+	}
+
+	data, err := SARIFReport("ruff", problems, "/work/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var log map[string]interface{}
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"version": "2.1.0",
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"runs": []interface{}{map[string]interface{}{
+			"tool": map[string]interface{}{"driver": map[string]interface{}{"name": "ruff"}},
+			"results": []interface{}{
+				map[string]interface{}{
+					"ruleId":  "lint",
+					"level":   "error",
+					"message": map[string]interface{}{"text": "unused import"},
+					"locations": []interface{}{map[string]interface{}{
+						"physicalLocation": map[string]interface{}{
+							"artifactLocation": map[string]interface{}{"uri": "main.py"},
+							"region":           map[string]interface{}{"startLine": 3.0, "startColumn": 5.0},
+						},
+					}},
+				},
+				map[string]interface{}{
+					"ruleId":  "lint",
+					"level":   "warning",
+					"message": map[string]interface{}{"text": "warning: no config found"},
+				},
+			},
+		}},
+	}
+
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("SARIFReport() = %s", data)
+	}
+}
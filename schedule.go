@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rerun periodically, as well as when files change
+func RerunEvery(interval time.Duration, tui *TUI) {
+	ticker := time.NewTicker(interval)
+
+	for range ticker.C {
+		tui.actions.fileChange.Broadcast()
+	}
+}
+
+// A five-field cron schedule; minute, hour, day of month, month, and day of week
+type CronSchedule struct {
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	anyDom bool
+	anyDow bool
+}
+
+// Parse one cron field, such as "*/5", "1-5", or "0,30", into a set of values
+func parseCronField(field string, min int, max int) (uint64, error) {
+	var set uint64
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[idx+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in '%s'", part)
+			}
+
+			part = part[:idx]
+		}
+
+		start, end := min, max
+
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+
+			var err error
+			start, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return 0, fmt.Errorf("invalid value '%s'", part)
+			}
+
+			end = start
+			if len(bounds) == 2 {
+				end, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return 0, fmt.Errorf("invalid range '%s'", part)
+				}
+			} else if step > 1 {
+				// "5/15" means every 15 from 5
+				end = max
+			}
+		}
+
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("'%s' is outside %d-%d", part, min, max)
+		}
+
+		for value := start; value <= end; value += step {
+			set |= 1 << uint(value)
+		}
+	}
+
+	return set, nil
+}
+
+// Parse a cron expression, such as "*/5 * * * *"
+func ParseCron(spec string) (*CronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected five fields in '%s'", spec)
+	}
+
+	bounds := [][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	sets := make([]uint64, 5)
+
+	for idx, field := range fields {
+		set, err := parseCronField(field, bounds[idx][0], bounds[idx][1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule '%s': %v", spec, err)
+		}
+
+		sets[idx] = set
+	}
+
+	// sunday is both 0 and 7
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+
+	return &CronSchedule{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		anyDom: fields[2] == "*",
+		anyDow: fields[4] == "*",
+	}, nil
+}
+
+// Does the schedule fire in the minute of a time? Like cron, a restricted day of month and day
+// of week match if either does
+func (cron *CronSchedule) Matches(moment time.Time) bool {
+	has := func(set uint64, value int) bool {
+		return set&(1<<uint(value)) != 0
+	}
+
+	if !has(cron.minute, moment.Minute()) || !has(cron.hour, moment.Hour()) || !has(cron.month, int(moment.Month())) {
+		return false
+	}
+
+	domMatch := has(cron.dom, moment.Day())
+	dowMatch := has(cron.dow, int(moment.Weekday()))
+
+	switch {
+	case cron.anyDom && cron.anyDow:
+		return true
+	case cron.anyDom:
+		return dowMatch
+	case cron.anyDow:
+		return domMatch
+	}
+
+	return domMatch || dowMatch
+}
+
+// The next minute after a time that the schedule fires, searching up to a year ahead
+func (cron *CronSchedule) Next(after time.Time) (time.Time, bool) {
+	moment := after.Truncate(time.Minute).Add(time.Minute)
+
+	for limit := moment.AddDate(1, 0, 0); moment.Before(limit); moment = moment.Add(time.Minute) {
+		if cron.Matches(moment) {
+			return moment, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// Rerun whenever a cron schedule fires, as well as when files change
+func RerunOnSchedule(cron *CronSchedule, tui *TUI) {
+	for {
+		next, ok := cron.Next(time.Now())
+		if !ok {
+			return
+		}
+
+		time.Sleep(time.Until(next))
+		tui.actions.fileChange.Broadcast()
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 10, 2, 30, 0, time.UTC) // a monday
+
+	tests := []struct {
+		name string
+		spec string
+		want time.Time
+	}{
+		{"Fires every five minutes", "*/5 * * * *", time.Date(2024, time.January, 1, 10, 5, 0, 0, time.UTC)},
+		{"Fires at a fixed time tomorrow", "30 9 * * *", time.Date(2024, time.January, 2, 9, 30, 0, 0, time.UTC)},
+		{"Fires on a weekday range", "0 0 * * 6-7", time.Date(2024, time.January, 6, 0, 0, 0, 0, time.UTC)},
+		{"Fires on either a day of month or weekday", "0 0 15 * 3", time.Date(2024, time.January, 3, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, err := ParseCron(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, _ := cron.Next(start); !got.Equal(tt.want) {
+				t.Errorf("CronSchedule.Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, spec := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *"} {
+		if _, err := ParseCron(spec); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want an error", spec)
+		}
+	}
+}
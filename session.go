@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// A directory holding this session's run output, written as it arrives
+type Session struct {
+	Dir    string
+	GitDir string
+}
+
+// The directory sessions are stored under by default
+func DefaultSessionRoot() (string, error) {
+	if state := os.Getenv("XDG_STATE_HOME"); len(state) > 0 {
+		return filepath.Join(state, "replit", "sessions"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".local", "state", "replit", "sessions"), nil
+}
+
+// Create a directory for this session beneath a root directory
+func NewSession(root string) (*Session, error) {
+	dir := filepath.Join(root, time.Now().Format(SESSION_DIR_FORMAT)+"-"+fmt.Sprint(os.Getpid()))
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &Session{Dir: dir}, nil
+}
+
+// A finished run's details, as stored beside its output
+type RunMetadata struct {
+	Run       int64     `json:"run"`
+	StartedAt time.Time `json:"started_at"`
+	Duration  string    `json:"duration"`
+	ExitCode  int       `json:"exit_code"`
+	Signal    string    `json:"signal,omitempty"`
+	TimedOut  bool      `json:"timed_out,omitempty"`
+	Source    string    `json:"source"`
+}
+
+// Store a finished run's details, and a copy of the file content that produced its output. The copy
+// keeps the watched file's extension
+func (session *Session) SaveRun(entry HistoryEntry, ext string) error {
+	source := fmt.Sprintf("run-%d.source%s", entry.Run, ext)
+
+	if err := ioutil.WriteFile(filepath.Join(session.Dir, source), entry.Source, 0644); err != nil {
+		return err
+	}
+
+	metadata, err := json.MarshalIndent(RunMetadata{
+		Run:       entry.Run,
+		StartedAt: entry.Result.StartedAt,
+		Duration:  entry.Result.Duration.String(),
+		ExitCode:  entry.Result.ExitCode,
+		Signal:    entry.Result.Signal,
+		TimedOut:  entry.Result.TimedOut,
+		Source:    source,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(session.Dir, fmt.Sprintf("run-%d.json", entry.Run)), metadata, 0644)
+}
+
+// Commit each run to a git repository within the session directory
+func (session *Session) EnableGitHistory() error {
+	gitDir := filepath.Join(session.Dir, SESSION_GIT_DIR)
+	if err := InitGitHistory(gitDir); err != nil {
+		return err
+	}
+
+	session.GitDir = gitDir
+	return nil
+}
+
+// Create the files a run's stdout and stderr are streamed to
+func (session *Session) RunFiles(run int64) (*os.File, *os.File, error) {
+	stdout, err := os.Create(filepath.Join(session.Dir, fmt.Sprintf("run-%d.stdout", run)))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stderr, err := os.Create(filepath.Join(session.Dir, fmt.Sprintf("run-%d.stderr", run)))
+	if err != nil {
+		stdout.Close()
+		return nil, nil, err
+	}
+
+	return stdout, stderr, nil
+}
+
+// Store the content piped to a run's stdin
+func (session *Session) SaveStdin(run int64, content []byte) error {
+	return ioutil.WriteFile(filepath.Join(session.Dir, fmt.Sprintf("run-%d.stdin", run)), content, 0644)
+}
+
+// Read a session directory's finished runs, oldest first
+func LoadSessionRuns(dir string) ([]HistoryEntry, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "run-*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []HistoryEntry{}
+
+	for _, fpath := range matches {
+		content, err := ioutil.ReadFile(fpath)
+		if err != nil {
+			return nil, err
+		}
+
+		var metadata RunMetadata
+		if err := json.Unmarshal(content, &metadata); err != nil {
+			return nil, fmt.Errorf("could not parse %s: %v", fpath, err)
+		}
+
+		duration, _ := time.ParseDuration(metadata.Duration)
+
+		// output is missing if it wasn't streamed to the session; show the run without it
+		stdout, _ := ioutil.ReadFile(filepath.Join(dir, fmt.Sprintf("run-%d.stdout", metadata.Run)))
+		stderr, _ := ioutil.ReadFile(filepath.Join(dir, fmt.Sprintf("run-%d.stderr", metadata.Run)))
+		source, _ := ioutil.ReadFile(filepath.Join(dir, metadata.Source))
+
+		entries = append(entries, HistoryEntry{
+			Run: metadata.Run,
+			Result: RunResult{
+				StartedAt: metadata.StartedAt,
+				Duration:  duration,
+				ExitCode:  metadata.ExitCode,
+				Signal:    metadata.Signal,
+				TimedOut:  metadata.TimedOut,
+			},
+			Stdout: stdout,
+			Stderr: stderr,
+			Source: source,
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Run < entries[j].Run
+	})
+
+	return entries, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestSessionSaveRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replit-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	session := &Session{Dir: dir}
+	entry := HistoryEntry{Run: 3, Result: RunResult{ExitCode: 1}, Source: []byte("print('hi')\n")}
+
+	if err := session.SaveRun(entry, ".py"); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "run-3.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var metadata RunMetadata
+	if err := json.Unmarshal(content, &metadata); err != nil {
+		t.Fatal(err)
+	}
+
+	if metadata.ExitCode != 1 || metadata.Source != "run-3.source.py" {
+		t.Errorf("unexpected metadata %+v", metadata)
+	}
+
+	source, err := ioutil.ReadFile(filepath.Join(dir, metadata.Source))
+	if err != nil || string(source) != "print('hi')\n" {
+		t.Errorf("expected the run's source to be saved, but got %q (%v)", source, err)
+	}
+}
+
+func TestSessionGitHistory(t *testing.T) {
+	if !CommandExists("git") {
+		t.Skip("git is unavailable")
+	}
+
+	dir, err := ioutil.TempDir("", "replit-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	session := &Session{Dir: dir}
+	if err := session.EnableGitHistory(); err != nil {
+		t.Fatal(err)
+	}
+
+	for run, exitCode := range []int{0, 1} {
+		entry := HistoryEntry{Run: int64(run + 1), Result: RunResult{ExitCode: exitCode}, Source: []byte("x")}
+
+		if err := CommitRun(session.GitDir, entry, "main.py"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command("git", "log", "--format=%s")
+	cmd.Dir = session.GitDir
+
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "run 2: exit 1\nrun 1: exit 0\n"; string(out) != want {
+		t.Errorf("expected commits %q, but got %q", want, out)
+	}
+}
+
+func TestLoadSessionRuns(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replit-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	session := &Session{Dir: dir}
+	for _, run := range []int64{2, 1} {
+		if err := session.SaveRun(HistoryEntry{Run: run, Source: []byte("x")}, ".py"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ioutil.WriteFile(filepath.Join(dir, "run-1.stdout"), []byte("out"), 0644)
+
+	entries, err := LoadSessionRuns(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 2 || entries[0].Run != 1 || string(entries[0].Stdout) != "out" || string(entries[1].Source) != "x" {
+		t.Errorf("unexpected runs %+v", entries)
+	}
+}
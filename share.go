@@ -0,0 +1,157 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Serve the session's output to a pairing partner, who may also trigger runs and send the program input
+type ShareServer struct {
+	Token string
+	tui   *TUI
+	state *LanguageState
+}
+
+// The token partners authenticate with; $REPLIT_TOKEN, or a random token
+func ShareToken() (string, error) {
+	if token := os.Getenv("REPLIT_TOKEN"); len(token) > 0 {
+		return token, nil
+	}
+
+	bytes := make([]byte, SHARE_TOKEN_BYTES)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(bytes), nil
+}
+
+func NewShareServer(token string, tui *TUI, state *LanguageState) *ShareServer {
+	return &ShareServer{token, tui, state}
+}
+
+// Reject requests without the session's token, as a bearer token
+func (server *ShareServer) authorize(handler http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+
+		if subtle.ConstantTimeCompare([]byte(token), []byte(server.Token)) != 1 {
+			http.Error(writer, "invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		handler(writer, req)
+	}
+}
+
+// The latest run's output
+func (server *ShareServer) output(writer http.ResponseWriter, req *http.Request) {
+	writer.Header().Set("Content-Type", "application/json")
+
+	json.NewEncoder(writer).Encode(map[string]interface{}{
+		"run":    server.tui.RunCount(),
+		"stdout": string(server.tui.stdoutBuffer.Bytes()),
+		"stderr": string(server.tui.stderrBuffer.Bytes()),
+	})
+}
+
+// Rerun, as though a file changed
+func (server *ShareServer) trigger(writer http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(writer, "use POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	server.tui.actions.fileChange.Broadcast()
+	writer.WriteHeader(http.StatusAccepted)
+}
+
+// Write the request body to the running program's stdin
+func (server *ShareServer) stdin(writer http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(writer, "use POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, SHARE_STDIN_LIMIT))
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	server.state.Lock.Lock()
+	stdin := server.state.Stdin
+	server.state.Lock.Unlock()
+
+	if stdin == nil {
+		http.Error(writer, "no program is running", http.StatusConflict)
+		return
+	}
+
+	if _, err := stdin.Write(body); err != nil {
+		http.Error(writer, "could not write to the program: "+err.Error(), http.StatusConflict)
+		return
+	}
+
+	writer.WriteHeader(http.StatusAccepted)
+}
+
+// The address to serve a session on; the loopback address, unless a host is given. The token is sent in
+// plain HTTP, so sessions are only shared beyond this machine when asked
+func ShareAddress(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || len(host) > 0 {
+		return addr
+	}
+
+	return net.JoinHostPort(SHARE_DEFAULT_HOST, port)
+}
+
+// Serve the session on an address until replit exits
+func (server *ShareServer) Serve(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/output", server.authorize(server.output))
+	mux.HandleFunc("/trigger", server.authorize(server.trigger))
+	mux.HandleFunc("/stdin", server.authorize(server.stdin))
+
+	return http.ListenAndServe(addr, mux)
+}
+
+// Call a shared session's endpoint, authenticating with $REPLIT_TOKEN
+func SendRemote(remote string, endpoint string, body io.Reader) error {
+	token := os.Getenv("REPLIT_TOKEN")
+	if len(token) == 0 {
+		return errors.New("$REPLIT_TOKEN is not set")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(remote, "/")+endpoint, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	client := http.Client{Timeout: SHARE_CLIENT_TIMEOUT}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		message, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("%s: %s", res.Status, strings.TrimSpace(string(message)))
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type closingBuffer struct {
+	bytes.Buffer
+}
+
+func (buffer *closingBuffer) Close() error {
+	return nil
+}
+
+func TestShareServerStdin(t *testing.T) {
+	state := &LanguageState{}
+	server := NewShareServer("secret", &TUI{}, state)
+	handler := server.authorize(server.stdin)
+
+	tests := []struct {
+		name    string
+		token   string
+		running bool
+		want    int
+	}{
+		{"Rejects requests without the token", "wrong", true, http.StatusUnauthorized},
+		{"Rejects input when no program is running", "secret", false, http.StatusConflict},
+		{"Writes input to the running program", "secret", true, http.StatusAccepted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdin := &closingBuffer{}
+
+			state.Stdin = nil
+			if tt.running {
+				state.Stdin = stdin
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/stdin", strings.NewReader("42\n"))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+
+			recorder := httptest.NewRecorder()
+			handler(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Errorf("expected status %d, but got %d", tt.want, recorder.Code)
+			}
+
+			if wrote := stdin.String() == "42\n"; wrote != (tt.want == http.StatusAccepted) {
+				t.Errorf("unexpected input %q written to the program", stdin.String())
+			}
+		})
+	}
+}
+
+func TestShareAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{":8080", "127.0.0.1:8080"},
+		{"localhost:8080", "localhost:8080"},
+		{"0.0.0.0:8080", "0.0.0.0:8080"},
+		{"[::]:8080", "[::]:8080"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := ShareAddress(tt.addr); got != tt.want {
+				t.Errorf("ShareAddress() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
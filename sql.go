@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// A database client that runs .sql files against a DSN and prints their results as tables, and
+// variables that pass it the password, so it isn't visible in the process list
+type SQLClient struct {
+	Program string
+	dsn     *url.URL
+	Env     map[string]string
+}
+
+// Choose the client for a DSN's scheme; psql, mysql, or sqlite3
+func NewSQLClient(dsn string) (*SQLClient, error) {
+	parsed, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DSN: %v", err)
+	}
+
+	client := &SQLClient{dsn: parsed, Env: map[string]string{}}
+	password, hasPassword := parsed.User.Password()
+
+	switch parsed.Scheme {
+	case "postgres", "postgresql":
+		client.Program = "psql"
+		if hasPassword {
+			client.Env["PGPASSWORD"] = password
+		}
+	case "mysql":
+		client.Program = "mysql"
+		if hasPassword {
+			client.Env["MYSQL_PWD"] = password
+		}
+	case "sqlite", "sqlite3":
+		client.Program = "sqlite3"
+	default:
+		return nil, fmt.Errorf("unsupported DSN scheme '%s'", parsed.Scheme)
+	}
+
+	return client, nil
+}
+
+// Check the client is installed
+func (client *SQLClient) Validate() error {
+	if !CommandExists(client.Program) {
+		return errors.New(client.Program + " is not in PATH")
+	}
+
+	return nil
+}
+
+// The command that runs a .sql file, stopping at the first error
+func (client *SQLClient) Command(fpath string) []string {
+	dsn := *client.dsn
+
+	switch client.Program {
+	case "psql":
+		// the password is passed as $PGPASSWORD
+		if dsn.User != nil {
+			dsn.User = url.User(dsn.User.Username())
+		}
+
+		return []string{"psql", "-X", "-q", "-v", "ON_ERROR_STOP=1", "-P", "border=2", "-d", dsn.String(), "-f", fpath}
+	case "mysql":
+		argv := []string{"mysql", "--table"}
+
+		if len(dsn.Hostname()) > 0 {
+			argv = append(argv, "-h", dsn.Hostname())
+		}
+
+		if len(dsn.Port()) > 0 {
+			argv = append(argv, "-P", dsn.Port())
+		}
+
+		if dsn.User != nil {
+			argv = append(argv, "-u", dsn.User.Username())
+		}
+
+		if database := strings.TrimPrefix(dsn.Path, "/"); len(database) > 0 {
+			argv = append(argv, database)
+		}
+
+		return append(argv, "-e", "source "+fpath)
+	}
+
+	// sqlite://data.db is a relative path, sqlite:///tmp/data.db is absolute,
+	// and sqlite:// is an in-memory database
+	database := dsn.Host + dsn.Path
+	if len(database) == 0 {
+		database = ":memory:"
+	}
+
+	return []string{"sqlite3", "-bail", "-box", database, ".read '" + strings.Replace(fpath, "'", "''", -1) + "'"}
+}
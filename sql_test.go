@@ -0,0 +1,68 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSQLClient(t *testing.T) {
+	tests := []struct {
+		name string
+		dsn  string
+		argv []string
+		env  map[string]string
+	}{
+		{
+			"Passes postgres passwords by environment",
+			"postgres://ada:secret@db:5432/app?sslmode=disable",
+			[]string{"psql", "-X", "-q", "-v", "ON_ERROR_STOP=1", "-P", "border=2", "-d", "postgres://ada@db:5432/app?sslmode=disable", "-f", "q.sql"},
+			map[string]string{"PGPASSWORD": "secret"},
+		},
+		{
+			"Splits mysql DSNs into flags",
+			"mysql://ada:secret@db:3306/app",
+			[]string{"mysql", "--table", "-h", "db", "-P", "3306", "-u", "ada", "app", "-e", "source q.sql"},
+			map[string]string{"MYSQL_PWD": "secret"},
+		},
+		{
+			"Opens relative sqlite databases",
+			"sqlite://data.db",
+			[]string{"sqlite3", "-bail", "-box", "data.db", ".read 'q.sql'"},
+			map[string]string{},
+		},
+		{
+			"Opens absolute sqlite databases",
+			"sqlite:///tmp/data.db",
+			[]string{"sqlite3", "-bail", "-box", "/tmp/data.db", ".read 'q.sql'"},
+			map[string]string{},
+		},
+		{
+			"Opens in-memory sqlite databases",
+			"sqlite://",
+			[]string{"sqlite3", "-bail", "-box", ":memory:", ".read 'q.sql'"},
+			map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewSQLClient(tt.dsn)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := client.Command("q.sql"); !reflect.DeepEqual(got, tt.argv) {
+				t.Errorf("Command() = %v, want %v", got, tt.argv)
+			}
+
+			if !reflect.DeepEqual(client.Env, tt.env) {
+				t.Errorf("Env = %v, want %v", client.Env, tt.env)
+			}
+		})
+	}
+}
+
+func TestSQLClientSchemes(t *testing.T) {
+	if _, err := NewSQLClient("oracle://db/app"); err == nil {
+		t.Error("expected unsupported schemes to be rejected")
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// The outcome of a single run
+type RunResult struct {
+	StartedAt    time.Time
+	Duration     time.Duration
+	ExitCode     int
+	MaxRSS       int64
+	UserTime     time.Duration
+	SysTime      time.Duration
+	Signal       string
+	TimedOut     bool
+	FilesChanged int
+}
+
+// Explain a run that was killed or timed out, or "" if it exited by itself
+func (result RunResult) Footer() string {
+	switch {
+	case result.TimedOut:
+		return fmt.Sprintf("✗ timed out (%s) after %s", result.Signal, FormatDuration(result.Duration))
+	case len(result.Signal) > 0:
+		return fmt.Sprintf("✗ killed (%s) after %s", result.Signal, FormatDuration(result.Duration))
+	}
+
+	return ""
+}
+
+// User and system CPU time consumed by the run
+func (result RunResult) CPUTime() time.Duration {
+	return result.UserTime + result.SysTime
+}
+
+// Did the run succeed?
+func (result RunResult) Succeeded() bool {
+	return result.ExitCode == 0
+}
+
+// Statistics gathered across the runs in a session
+type RunStats struct {
+	lock       sync.Mutex
+	Runs       int64
+	Failures   int64
+	Streak     int64
+	FailStreak int64
+	Cumulative time.Duration
+	Last       time.Duration
+	Min        time.Duration
+	Max        time.Duration
+	Recent     []time.Duration
+	ExitCodes  []int
+}
+
+// Record the outcome of a run
+func (stats *RunStats) Record(result RunResult) {
+	stats.lock.Lock()
+	defer stats.lock.Unlock()
+
+	stats.Runs += 1
+	stats.Cumulative += result.Duration
+	stats.Last = result.Duration
+
+	if stats.Runs == 1 || result.Duration < stats.Min {
+		stats.Min = result.Duration
+	}
+
+	if result.Duration > stats.Max {
+		stats.Max = result.Duration
+	}
+
+	stats.ExitCodes = append(stats.ExitCodes, result.ExitCode)
+	if len(stats.ExitCodes) > EXIT_STRIP_LENGTH {
+		stats.ExitCodes = stats.ExitCodes[len(stats.ExitCodes)-EXIT_STRIP_LENGTH:]
+	}
+
+	stats.Recent = append(stats.Recent, result.Duration)
+	if len(stats.Recent) > SPARKLINE_LENGTH {
+		stats.Recent = stats.Recent[len(stats.Recent)-SPARKLINE_LENGTH:]
+	}
+
+	if result.Succeeded() {
+		stats.Streak += 1
+		stats.FailStreak = 0
+	} else {
+		stats.Failures += 1
+		stats.FailStreak += 1
+		stats.Streak = 0
+	}
+}
+
+// Forget all recorded runs
+func (stats *RunStats) Reset() {
+	stats.lock.Lock()
+	defer stats.lock.Unlock()
+
+	stats.Runs = 0
+	stats.Failures = 0
+	stats.Streak = 0
+	stats.FailStreak = 0
+	stats.Cumulative = 0
+	stats.Last = 0
+	stats.Min = 0
+	stats.Max = 0
+	stats.Recent = nil
+	stats.ExitCodes = nil
+}
+
+// The mean run duration
+func (stats *RunStats) Average() time.Duration {
+	if stats.Runs == 0 {
+		return 0
+	}
+
+	return stats.Cumulative / time.Duration(stats.Runs)
+}
+
+// Summarise run durations for display
+func (stats *RunStats) Durations() string {
+	stats.lock.Lock()
+	defer stats.lock.Unlock()
+
+	return fmt.Sprintf("%s (min %s · avg %s · max %s)",
+		FormatDuration(stats.Last), FormatDuration(stats.Min), FormatDuration(stats.Average()), FormatDuration(stats.Max))
+}
+
+// Summarise the statistics for display
+func (stats *RunStats) String() string {
+	stats.lock.Lock()
+	defer stats.lock.Unlock()
+
+	return fmt.Sprintf("runs [blue]%d[reset] · failures [red]%d[reset] · success streak [green]%d[reset] · total runtime %s",
+		stats.Runs, stats.Failures, stats.Streak, stats.Cumulative.Round(time.Millisecond))
+}
+
+// Compare a duration to the recent runs recorded before it
+func (stats *RunStats) CompareRuntime(duration time.Duration) (float64, bool) {
+	stats.lock.Lock()
+	defer stats.lock.Unlock()
+
+	return CompareRuntime(stats.Recent, duration)
+}
+
+// The ratio of a duration to the average of recent durations, and whether it's a significant change; at
+// least REGRESSION_RATIO times slower or faster, by at least REGRESSION_MIN_CHANGE. A few recent
+// durations are needed to tell
+func CompareRuntime(recent []time.Duration, duration time.Duration) (float64, bool) {
+	if len(recent) > REGRESSION_WINDOW {
+		recent = recent[len(recent)-REGRESSION_WINDOW:]
+	}
+
+	if len(recent) < REGRESSION_MIN_RUNS {
+		return 1, false
+	}
+
+	var total time.Duration
+	for _, diff := range recent {
+		total += diff
+	}
+
+	average := total / time.Duration(len(recent))
+	if average <= 0 {
+		return 1, false
+	}
+
+	change := duration - average
+	if change < 0 {
+		change = -change
+	}
+
+	ratio := float64(duration) / float64(average)
+	return ratio, change >= REGRESSION_MIN_CHANGE && (ratio >= REGRESSION_RATIO || ratio <= 1/REGRESSION_RATIO)
+}
+
+// Describe how a run's duration compares to recent runs, as a ratio of their average
+func RuntimeChangeLabel(ratio float64) string {
+	if ratio >= 1 {
+		return fmt.Sprintf("▲ %.1f× slower than recent runs", ratio)
+	}
+
+	return fmt.Sprintf("▼ %.1f× faster than recent runs", 1/ratio)
+}
+
+// Plot recent run durations as a sparkline
+func (stats *RunStats) Sparkline() string {
+	stats.lock.Lock()
+	defer stats.lock.Unlock()
+
+	return Sparkline(stats.Recent)
+}
+
+// Draw recent run outcomes as a strip of green and red blocks
+func (stats *RunStats) ExitStrip() string {
+	stats.lock.Lock()
+	defer stats.lock.Unlock()
+
+	strip := ""
+	for _, code := range stats.ExitCodes {
+		if code == 0 {
+			strip += "[green]■"
+		} else {
+			strip += "[red]■"
+		}
+	}
+
+	if len(strip) > 0 {
+		strip += "[reset]"
+	}
+
+	return strip
+}
+
+// Draw durations as unicode bars, scaled to the largest duration
+func Sparkline(durations []time.Duration) string {
+	var max time.Duration
+	for _, diff := range durations {
+		if diff > max {
+			max = diff
+		}
+	}
+
+	bars := []rune(SPARKLINE_BARS)
+	line := make([]rune, len(durations))
+
+	for idx, diff := range durations {
+		level := 0
+		if max > 0 {
+			level = int(int64(diff) * int64(len(bars)-1) / int64(max))
+		}
+
+		line[idx] = bars[level]
+	}
+
+	return string(line)
+}
+
+// Format a duration as milliseconds, or seconds for longer runs
+func FormatDuration(diff time.Duration) string {
+	if diff < time.Second {
+		return fmt.Sprint(diff.Milliseconds()) + "ms"
+	}
+
+	return fmt.Sprintf("%.1fs", diff.Seconds())
+}
+
+// Format a byte count with a binary unit
+func FormatBytes(count int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	size := float64(count)
+	idx := 0
+
+	for size >= 1024 && idx < len(units)-1 {
+		size /= 1024
+		idx += 1
+	}
+
+	if idx == 0 {
+		return fmt.Sprintf("%d%s", count, units[idx])
+	}
+
+	return fmt.Sprintf("%.1f%s", size, units[idx])
+}
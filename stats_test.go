@@ -0,0 +1,120 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRunStatsRecord(t *testing.T) {
+	tests := []struct {
+		name         string
+		results      []RunResult
+		wantFailures int64
+		wantStreak   int64
+		wantTotal    time.Duration
+	}{
+		{
+			"Counts a success streak",
+			[]RunResult{{Duration: time.Second, ExitCode: 0}, {Duration: time.Second, ExitCode: 0}},
+			0,
+			2,
+			2 * time.Second,
+		},
+		{
+			"Failures end the streak",
+			[]RunResult{{Duration: time.Second, ExitCode: 0}, {Duration: time.Second, ExitCode: 1}, {Duration: time.Second, ExitCode: 0}},
+			1,
+			1,
+			3 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := RunStats{}
+
+			for _, result := range tt.results {
+				stats.Record(result)
+			}
+
+			if stats.Failures != tt.wantFailures {
+				t.Errorf("RunStats.Failures = %v, want %v", stats.Failures, tt.wantFailures)
+			}
+			if stats.Streak != tt.wantStreak {
+				t.Errorf("RunStats.Streak = %v, want %v", stats.Streak, tt.wantStreak)
+			}
+			if stats.Cumulative != tt.wantTotal {
+				t.Errorf("RunStats.Cumulative = %v, want %v", stats.Cumulative, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		name      string
+		durations []time.Duration
+		want      string
+	}{
+		{
+			"Empty history",
+			[]time.Duration{},
+			"",
+		},
+		{
+			"Scales to the slowest run",
+			[]time.Duration{0, 7 * time.Millisecond, 14 * time.Millisecond},
+			"▁▄█",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sparkline(tt.durations); got != tt.want {
+				t.Errorf("Sparkline() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunResultFooter(t *testing.T) {
+	tests := []struct {
+		name   string
+		result RunResult
+		want   string
+	}{
+		{"Exited by itself", RunResult{Duration: time.Second, ExitCode: 1}, ""},
+		{"Killed", RunResult{Duration: 4200 * time.Millisecond, ExitCode: -1, Signal: "SIGKILL"}, "✗ killed (SIGKILL) after 4.2s"},
+		{"Timed out", RunResult{Duration: 10 * time.Second, ExitCode: -1, Signal: "SIGKILL", TimedOut: true}, "✗ timed out (SIGKILL) after 10.0s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.result.Footer(); got != tt.want {
+				t.Errorf("RunResult.Footer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareRuntime(t *testing.T) {
+	ms := time.Millisecond
+	steady := []time.Duration{100 * ms, 100 * ms, 100 * ms}
+
+	tests := []struct {
+		name     string
+		recent   []time.Duration
+		duration time.Duration
+		want     bool
+	}{
+		{"Needs a few runs to compare to", []time.Duration{100 * ms}, time.Second, false},
+		{"Notes much slower runs", steady, 300 * ms, true},
+		{"Notes much faster runs", steady, 40 * ms, true},
+		{"Ignores small changes", steady, 120 * ms, false},
+		{"Ignores large ratios of short runs", []time.Duration{ms, ms, ms}, 10 * ms, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := CompareRuntime(tt.recent, tt.duration); got != tt.want {
+				t.Errorf("CompareRuntime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
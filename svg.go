@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Terminal escape sequences, such as colours, and control characters SVG can't contain
+var terminalEscapes = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]|[\x00-\x08\x0b-\x1f\x7f]`)
+
+// A line of an SVG frame, in a colour
+type svgLine struct {
+	text  string
+	color string
+}
+
+// Truncate text to its first lines, each cut to a width
+func clipLines(text string, rows int, width int) []string {
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if len(text) == 0 {
+		return []string{}
+	}
+
+	if len(lines) > rows {
+		lines = append(lines[:rows-1], fmt.Sprintf("··· %d more lines", len(lines)-rows+1))
+	}
+
+	for idx, line := range lines {
+		line = strings.Replace(line, "\t", "    ", -1)
+		line = terminalEscapes.ReplaceAllString(line, "")
+
+		lines[idx] = TruncateCells(line, width)
+	}
+
+	return lines
+}
+
+// The lines of a run's frame; a summary, its code, and its output
+func svgFrame(entry HistoryEntry) []svgLine {
+	mark, color := "✔", SVG_GREEN
+	if !entry.Result.Succeeded() {
+		mark, color = "✘", SVG_RED
+	}
+
+	summary := fmt.Sprintf("%s run %d · exit %d · %s", mark, entry.Run, entry.Result.ExitCode, FormatDuration(entry.Result.Duration))
+	if footer := entry.Result.Footer(); len(footer) > 0 {
+		summary += " · " + footer
+	}
+
+	lines := []svgLine{{summary, color}, {"", SVG_FOREGROUND}}
+
+	for _, line := range clipLines(string(entry.Source), SVG_SOURCE_ROWS, SVG_COLUMNS) {
+		lines = append(lines, svgLine{line, SVG_GRAY})
+	}
+
+	lines = append(lines, svgLine{strings.Repeat("─", SVG_COLUMNS), SVG_GRAY})
+
+	for _, line := range clipLines(string(entry.Stdout), SVG_OUTPUT_ROWS, SVG_COLUMNS) {
+		lines = append(lines, svgLine{line, SVG_FOREGROUND})
+	}
+
+	for _, line := range clipLines(string(entry.Stderr), SVG_OUTPUT_ROWS, SVG_COLUMNS) {
+		lines = append(lines, svgLine{line, SVG_RED})
+	}
+
+	return lines
+}
+
+// Render runs as an animated SVG, showing each run's code and output in turn and looping
+func RenderSVG(entries []HistoryEntry) string {
+	frames := [][]svgLine{}
+	rows := 0
+
+	for _, entry := range entries {
+		frame := svgFrame(entry)
+		frames = append(frames, frame)
+
+		if len(frame) > rows {
+			rows = len(frame)
+		}
+	}
+
+	width := SVG_COLUMNS*SVG_CHAR_WIDTH + 2*SVG_PADDING
+	height := rows*SVG_LINE_HEIGHT + 2*SVG_PADDING
+	total := float64(len(frames)) * SVG_FRAME_SECONDS
+
+	var out strings.Builder
+
+	fmt.Fprintf(&out, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="monospace" font-size="%d">`+"\n",
+		width, height, SVG_FONT_SIZE)
+
+	// each frame is visible for its share of the loop, offset by its position
+	fmt.Fprintf(&out, "<style>.frame { visibility: hidden; animation: show %.1fs step-end infinite; } "+
+		"@keyframes show { 0%% { visibility: visible; } %.4f%% { visibility: hidden; } }</style>\n",
+		total, 100/float64(len(frames)))
+
+	fmt.Fprintf(&out, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", SVG_BACKGROUND)
+
+	for idx, frame := range frames {
+		fmt.Fprintf(&out, `<g class="frame" style="animation-delay: %.1fs">`+"\n", float64(idx)*SVG_FRAME_SECONDS)
+
+		for row, line := range frame {
+			fmt.Fprintf(&out, `<text x="%d" y="%d" fill="%s" xml:space="preserve">%s</text>`+"\n",
+				SVG_PADDING, SVG_PADDING+(row+1)*SVG_LINE_HEIGHT-SVG_LINE_HEIGHT/4, line.color, html.EscapeString(line.text))
+		}
+
+		out.WriteString("</g>\n")
+	}
+
+	out.WriteString("</svg>\n")
+
+	return out.String()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClipLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		rows  int
+		width int
+		want  []string
+	}{
+		{"Keeps short text", "a\nb\n", 3, 10, []string{"a", "b"}},
+		{"Elides extra lines", "a\nb\nc\nd\n", 3, 20, []string{"a", "b", "··· 2 more lines"}},
+		{"Cuts long lines", "abcdefgh", 3, 5, []string{"abcd…"}},
+		{"Cuts wide characters by cell", "漢字テキスト", 3, 6, []string{"漢字…"}},
+		{"Strips terminal colours", "\x1b[31merror\x1b[0m", 3, 10, []string{"error"}},
+		{"Has no lines for empty text", "", 3, 10, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clipLines(tt.text, tt.rows, tt.width); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("clipLines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderSVG(t *testing.T) {
+	entries := []HistoryEntry{
+		{Run: 1, Result: RunResult{ExitCode: 1}, Source: []byte("if a < b:\n"), Stderr: []byte("SyntaxError\n")},
+		{Run: 2, Source: []byte("print('&')\n"), Stdout: []byte("&\n")},
+	}
+
+	svg := RenderSVG(entries)
+
+	decoder := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		if _, err := decoder.Token(); err != nil {
+			if err.Error() != "EOF" {
+				t.Fatalf("expected valid XML, but got %v", err)
+			}
+			break
+		}
+	}
+
+	if frames := strings.Count(svg, `<g class="frame"`); frames != 2 {
+		t.Errorf("expected a frame per run, but got %d", frames)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package main
+
+import "errors"
+
+// Syslog isn't available on this platform
+func NewSyslogLogger() (*EventLogger, error) {
+	return nil, errors.New("syslog isn't supported on this platform")
+}
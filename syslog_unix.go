@@ -0,0 +1,16 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package main
+
+import "log/syslog"
+
+// Connect to the local syslog daemon, which journald also reads from
+func NewSyslogLogger() (*EventLogger, error) {
+	writer, err := syslog.New(syslog.LOG_INFO|syslog.LOG_USER, SYSLOG_TAG)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EventLogger{writer}, nil
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/docopt/docopt-go"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Host several sessions in one application, as tabs, beneath a strip showing each tab's status
+type Tabs struct {
+	lock    sync.Mutex
+	app     *tview.Application
+	pages   *tview.Pages
+	strip   *tview.TextView
+	tabs    []*TUI
+	names   []string
+	current int
+}
+
+func NewTabs(mouse bool) *Tabs {
+	tabs := &Tabs{
+		pages: tview.NewPages(),
+		strip: tview.NewTextView().SetDynamicColors(true),
+	}
+
+	root := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(tabs.strip, 1, 0, false).
+		AddItem(tabs.pages, 0, 1, true)
+
+	tabs.app = tview.NewApplication().
+		EnableMouse(mouse).
+		SetInputCapture(tabs.onInput).
+		SetMouseCapture(tabs.onMouse).
+		SetAfterDrawFunc(tabs.afterDraw).
+		SetRoot(root, true)
+
+	return tabs
+}
+
+// Host a session's TUI in a new tab
+func (tabs *Tabs) Add(tui *TUI, name string) {
+	tabs.lock.Lock()
+	tabs.tabs = append(tabs.tabs, tui)
+	tabs.names = append(tabs.names, name)
+	tabs.lock.Unlock()
+
+	tui.app = tabs.app
+	tui.tabs = tabs
+	tui.SetRoot()
+	tui.Focus(tui.stdoutViewer)
+
+	tabs.RefreshStrip()
+}
+
+// The tab being shown
+func (tabs *Tabs) Current() *TUI {
+	tabs.lock.Lock()
+	defer tabs.lock.Unlock()
+
+	if tabs.current >= len(tabs.tabs) {
+		return nil
+	}
+
+	return tabs.tabs[tabs.current]
+}
+
+// Show a tab's grid as its page, after it's rebuilt; only the current tab's page is visible
+func (tabs *Tabs) SetPage(tui *TUI, grid *tview.Grid) {
+	tabs.lock.Lock()
+	idx := 0
+	for candidate, tab := range tabs.tabs {
+		if tab == tui {
+			idx = candidate
+		}
+	}
+	current := tabs.tabs[tabs.current]
+	tabs.lock.Unlock()
+
+	tabs.pages.AddPage(strconv.Itoa(idx), grid, true, current == tui)
+
+	// replacing a page resets focus to the visible page's first pane
+	if current != tui && current.focused != nil {
+		tabs.app.SetFocus(current.focused)
+	}
+}
+
+// Show the tab at an index
+func (tabs *Tabs) Select(idx int) {
+	tabs.lock.Lock()
+	if idx < 0 || idx >= len(tabs.tabs) {
+		tabs.lock.Unlock()
+		return
+	}
+
+	tabs.current = idx
+	tui := tabs.tabs[idx]
+	tabs.lock.Unlock()
+
+	tabs.pages.SwitchToPage(strconv.Itoa(idx))
+	tui.Focus(tui.focused)
+	tabs.RefreshStrip()
+}
+
+// Show the next or previous tab, wrapping around
+func (tabs *Tabs) Cycle(offset int) {
+	tabs.lock.Lock()
+	count := len(tabs.tabs)
+	idx := (tabs.current + offset + count) % count
+	tabs.lock.Unlock()
+
+	tabs.Select(idx)
+}
+
+// Describe each tab by its number, name, and status; the current tab is highlighted
+func (tabs *Tabs) StripText() string {
+	tabs.lock.Lock()
+	defer tabs.lock.Unlock()
+
+	labels := []string{}
+
+	for idx, tui := range tabs.tabs {
+		color, symbol := "gray", "·"
+		switch tui.status {
+		case TAB_RUNNING:
+			color, symbol = "yellow", "…"
+		case TAB_PASSED:
+			color, symbol = "green", "✓"
+		case TAB_FAILED:
+			color, symbol = "red", "✗"
+		}
+
+		label := fmt.Sprintf(" %d %s %s ", idx+1, tview.Escape(tabs.names[idx]), symbol)
+		if idx == tabs.current {
+			labels = append(labels, "[black:"+color+"]"+label+"[-:-]")
+		} else {
+			labels = append(labels, "["+color+"]"+label+"[-]")
+		}
+	}
+
+	return strings.Join(labels, " ") + "  [gray]" + tview.Escape(TAB_KEYS) + "[-]"
+}
+
+func (tabs *Tabs) RefreshStrip() {
+	tabs.strip.SetText(tabs.StripText())
+}
+
+// Switch tabs with [ and ], or 1 to 9; other keys go to the current tab
+func (tabs *Tabs) onInput(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Rune() {
+	case '[':
+		tabs.Cycle(-1)
+		return nil
+	case ']':
+		tabs.Cycle(1)
+		return nil
+	case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		tabs.Select(int(event.Rune() - '1'))
+		return nil
+	}
+
+	if tui := tabs.Current(); tui != nil {
+		return tui.onInput(event)
+	}
+
+	return event
+}
+
+// Write each tab's image preview once the frame is drawn; hidden tabs' previews clear themselves
+func (tabs *Tabs) afterDraw(screen tcell.Screen) {
+	tabs.lock.Lock()
+	tuis := append([]*TUI{}, tabs.tabs...)
+	tabs.lock.Unlock()
+
+	for _, tui := range tuis {
+		tui.previewViewer.AfterDraw(screen)
+	}
+}
+
+func (tabs *Tabs) onMouse(event *tcell.EventMouse, action tview.MouseAction) (*tcell.EventMouse, tview.MouseAction) {
+	if tui := tabs.Current(); tui != nil {
+		return tui.onMouse(event, action)
+	}
+
+	return event, action
+}
+
+// Split a command line into words, as a shell would; quotes group words, and backslashes escape characters
+func ShellSplit(line string) ([]string, error) {
+	words := []string{}
+
+	var word strings.Builder
+	inWord := false
+	quote := rune(0)
+	escaped := false
+
+	for _, char := range line {
+		switch {
+		case escaped:
+			word.WriteRune(char)
+			escaped = false
+		case char == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0 && char == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(char)
+		case char == '\'' || char == '"':
+			quote = char
+			inWord = true
+		case char == ' ' || char == '\t' || char == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(char)
+			inWord = true
+		}
+	}
+
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape")
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return words, nil
+}
+
+// Run several sessions as tabs of one application; each tab is replit's arguments for one session
+func RunTabs(opts docopt.Opts) int {
+	specs, _ := opts["<tab>"].([]string)
+
+	sessionArgs := []*ReplitArgs{}
+	mouse := true
+
+	// files created for earlier tabs aren't left behind when a later tab is invalid
+	discard := func() {
+		for _, args := range sessionArgs {
+			args.EditorFile.Discard()
+		}
+	}
+
+	for _, spec := range specs {
+		argv, err := ShellSplit(spec)
+		if err != nil {
+			PrintCliError("could not read tab '"+spec+"': "+err.Error(), "quote each tab's arguments, e.g. replit tabs 'python3 a.py' 'node b.js'")
+			discard()
+			return EXIT_BAD_ARGS
+		}
+
+		tabOpts, err := docopt.ParseArgs(Usage, argv, "")
+		if err != nil {
+			PrintCliError("could not read tab '"+spec+"': "+err.Error(), "quote each tab's arguments, e.g. replit tabs 'python3 a.py' 'node b.js'")
+			discard()
+			return EXIT_BAD_ARGS
+		}
+
+		for _, command := range []string{"trigger", "stdin", "svg", "export", "import", "tabs", "--hook", "--ci"} {
+			if set, _ := tabOpts.Bool(command); set {
+				PrintCliError("tab '"+spec+"' isn't a session", "tabs run a language against a file, e.g. 'python3 a.py'")
+				discard()
+				return EXIT_BAD_ARGS
+			}
+		}
+
+		args, exitCode := ReadArgs(tabOpts)
+		if exitCode >= 0 {
+			discard()
+			return exitCode
+		}
+
+		if args.Linear {
+			PrintCliError("tab '"+spec+"' can't be shown --linear", "run each session with --linear in its own terminal")
+			args.EditorFile.Discard()
+			discard()
+			return EXIT_BAD_ARGS
+		}
+
+		mouse = mouse && !args.NoMouse
+		sessionArgs = append(sessionArgs, &args)
+	}
+
+	tabs := NewTabs(mouse)
+	tuis := []*TUI{}
+
+	for _, args := range sessionArgs {
+		tui := NewUI(args)
+		tui.SetTheme()
+
+		tabs.Add(tui, filepath.Base(args.EditorFile.File.Name()))
+		tuis = append(tuis, tui)
+	}
+
+	tabs.Select(0)
+
+	stopped := make(chan bool, 1)
+	go func() {
+		OpenScreen(tabs.app, mouse)
+
+		if err := tabs.app.Run(); err != nil {
+			fmt.Printf("RL: Application crashed! %v", err)
+		}
+		stopped <- true
+	}()
+
+	sessions := []*RunningSession{}
+	for idx, args := range sessionArgs {
+		sessions = append(sessions, StartSession(args, tuis[idx]))
+	}
+
+	// Terminate program when an exit signal is received or the interface is quit, and tidy up each session
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case <-sigs:
+	case <-stopped:
+	}
+	signal.Stop(sigs)
+
+	messages := ""
+	for _, session := range sessions {
+		messages += session.Stop(func() {})
+	}
+
+	tabs.app.Stop()
+	fmt.Print(messages)
+
+	return 0
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rivo/tview"
+)
+
+func TestShellSplit(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []string
+	}{
+		{"Splits on whitespace", "python3  main.py\t--every 30s", []string{"python3", "main.py", "--every", "30s"}},
+		{"Groups quoted words", `"node --inspect" 'my file.js'`, []string{"node --inspect", "my file.js"}},
+		{"Escapes characters", `a\ b "c\"d"`, []string{"a b", `c"d`}},
+		{"Keeps empty quoted words", `a ""`, []string{"a", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ShellSplit(tt.line)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ShellSplit() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ShellSplit(`"unterminated`); err == nil {
+		t.Error("expected unterminated quotes to be rejected")
+	}
+}
+
+func TestTabsStripText(t *testing.T) {
+	tabs := &Tabs{
+		tabs:    []*TUI{{status: TAB_PASSED}, {status: TAB_FAILED}, {}},
+		names:   []string{"a.py", "b.js", "c.sql"},
+		current: 1,
+	}
+
+	want := "[green] 1 a.py ✓ [-] [black:red] 2 b.js ✗ [-:-] [gray] 3 c.sql · [-]  [gray]" + tview.Escape(TAB_KEYS) + "[-]"
+	if got := tabs.StripText(); got != want {
+		t.Errorf("StripText() = %q, want %q", got, want)
+	}
+}
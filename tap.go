@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// "ok 1 - adds numbers # SKIP no network" test lines, "1..4" plans, and "Bail out! reason" aborts
+var tapTestPattern = regexp.MustCompile(`^(not )?ok\b\s*(\d+)?\s*(?:-\s*)?([^#]*?)\s*(?:#\s*(?i:(SKIP|TODO))\S*\s*(.*))?$`)
+var tapPlanPattern = regexp.MustCompile(`^1\.\.(\d+)`)
+var tapBailPattern = regexp.MustCompile(`^Bail out!\s*(.*)$`)
+
+// A test point of a TAP stream, and the diagnostics that follow it
+type TAPResult struct {
+	Number      int
+	OK          bool
+	Description string
+	Directive   string
+	Reason      string
+	Details     []string
+}
+
+// Whether the test counts as failed; failing TODO tests are expected to fail
+func (result TAPResult) Failed() bool {
+	return !result.OK && result.Directive != "TODO"
+}
+
+// A parsed TAP stream
+type TAPReport struct {
+	Planned int
+	HasPlan bool
+	Results []TAPResult
+	Bailout string
+}
+
+// Parse TAP output, from prove, bats, node-tap, and similar. Output is TAP if it has a plan or
+// version line and at least one test line; other lines, such as program output, are ignored
+func ParseTAP(data []byte) (TAPReport, bool) {
+	report := TAPReport{}
+	isTAP := false
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		if strings.HasPrefix(line, "TAP version") {
+			isTAP = true
+			continue
+		}
+
+		if match := tapPlanPattern.FindStringSubmatch(line); match != nil {
+			report.Planned, _ = strconv.Atoi(match[1])
+			report.HasPlan = true
+			isTAP = true
+			continue
+		}
+
+		if match := tapBailPattern.FindStringSubmatch(line); match != nil {
+			report.Bailout = match[1]
+			continue
+		}
+
+		if match := tapTestPattern.FindStringSubmatch(line); match != nil {
+			number, err := strconv.Atoi(match[2])
+			if err != nil {
+				number = len(report.Results) + 1
+			}
+
+			report.Results = append(report.Results, TAPResult{
+				Number:      number,
+				OK:          len(match[1]) == 0,
+				Description: match[3],
+				Directive:   strings.ToUpper(match[4]),
+				Reason:      match[5],
+			})
+			continue
+		}
+
+		// diagnostics and YAML blocks describe the test before them
+		if len(report.Results) > 0 && (strings.HasPrefix(line, "#") || strings.HasPrefix(line, " ")) {
+			last := &report.Results[len(report.Results)-1]
+			last.Details = append(last.Details, line)
+		}
+	}
+
+	return report, isTAP && len(report.Results) > 0
+}
+
+// Count passed, failed, and skipped tests
+func (report TAPReport) Counts() (int, int, int) {
+	passed, failed, skipped := 0, 0, 0
+
+	for _, result := range report.Results {
+		switch {
+		case result.Directive == "SKIP":
+			skipped += 1
+		case result.Failed():
+			failed += 1
+		default:
+			passed += 1
+		}
+	}
+
+	return passed, failed, skipped
+}
+
+// Render TAP output as a summary and a line per test, with failures' diagnostics beneath them; or
+// leave it unchanged with a notice if it isn't TAP
+func RenderTAP(data []byte) string {
+	report, ok := ParseTAP(data)
+	if !ok {
+		return "[yellow]stdout is not TAP[reset]\n" + string(data)
+	}
+
+	passed, failed, skipped := report.Counts()
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "[green]✔ %d passed[-] · [red]✘ %d failed[-] · [yellow]○ %d skipped[-]", passed, failed, skipped)
+
+	if report.HasPlan && report.Planned != len(report.Results) {
+		fmt.Fprintf(&out, " · [red]%d of %d planned tests ran[-]", len(report.Results), report.Planned)
+	}
+
+	out.WriteString("\n")
+
+	if len(report.Bailout) > 0 {
+		out.WriteString("[red]bailed out: " + tview.Escape(report.Bailout) + "[-]\n")
+	}
+
+	out.WriteString("\n")
+
+	for _, result := range report.Results {
+		line := fmt.Sprintf("%d %s", result.Number, result.Description)
+		if len(result.Directive) > 0 {
+			line += " # " + result.Directive + " " + result.Reason
+		}
+
+		line = tview.Escape(strings.TrimSpace(line))
+
+		switch {
+		case result.Directive == "SKIP":
+			out.WriteString("[yellow]○ " + line + "[-]\n")
+		case result.Failed():
+			out.WriteString("[red]✘ " + line + "[-]\n")
+
+			for _, detail := range result.Details {
+				out.WriteString("    " + tview.Escape(detail) + "\n")
+			}
+		default:
+			out.WriteString("[green]✔[-] " + line + "\n")
+		}
+	}
+
+	return out.String()
+}
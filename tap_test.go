@@ -0,0 +1,79 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTAP(t *testing.T) {
+	data := `TAP version 13
+1..4
+ok 1 - adds numbers
+not ok 2 - subtracts numbers
+  ---
+  expected: 1
+  ...
+# starting slow tests
+ok 3 fetches # SKIP no network
+not ok 4 # TODO later
+`
+
+	want := TAPReport{
+		Planned: 4,
+		HasPlan: true,
+		Results: []TAPResult{
+			{1, true, "adds numbers", "", "", nil},
+			{2, false, "subtracts numbers", "", "", []string{"  ---", "  expected: 1", "  ...", "# starting slow tests"}},
+			{3, true, "fetches", "SKIP", "no network", nil},
+			{4, false, "", "TODO", "later", nil},
+		},
+	}
+
+	got, ok := ParseTAP([]byte(data))
+	if !ok {
+		t.Fatal("expected TAP")
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseTAP() = %+v, want %+v", got, want)
+	}
+
+	if passed, failed, skipped := got.Counts(); passed != 2 || failed != 1 || skipped != 1 {
+		t.Errorf("Counts() = %d, %d, %d", passed, failed, skipped)
+	}
+}
+
+func TestParseTAPDetection(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want bool
+	}{
+		{"Detects bats output", "1..2\nok 1 first\nok 2 second\n", true},
+		{"Detects trailing plans", "ok 1\nok 2\n1..2\n", true},
+		{"Ignores test lines without a plan", "ok, that worked\n", false},
+		{"Ignores plans without tests", "1..0\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := ParseTAP([]byte(tt.data)); got != tt.want {
+				t.Errorf("ParseTAP() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderTAP(t *testing.T) {
+	got := RenderTAP([]byte("1..3\nok 1 a\nnot ok 2 b\n# got [1]\n"))
+
+	for _, want := range []string{
+		"[green]✔ 1 passed[-] · [red]✘ 1 failed[-] · [yellow]○ 0 skipped[-] · [red]2 of 3 planned tests ran[-]",
+		"[green]✔[-] 1 a\n",
+		"[red]✘ 2 b[-]\n    # got [1[]\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("RenderTAP() = %q, missing %q", got, want)
+		}
+	}
+}
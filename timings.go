@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long each phase of starting replit took, for --timings. Nil timings record nothing, so
+// callers needn't check whether --timings was passed
+type StartupTimings struct {
+	lock     sync.Mutex
+	start    time.Time
+	finished time.Time
+	phases   []StartupPhase
+}
+
+// A phase of startup, and how long it took
+type StartupPhase struct {
+	Name string
+	Took time.Duration
+}
+
+// Time phases of a startup that began at a time
+func NewStartupTimings(start time.Time) *StartupTimings {
+	return &StartupTimings{start: start}
+}
+
+// Note that a phase which began at a time has finished. Each phase is only recorded once, so
+// repeated phases such as runs keep their first time; returns whether this was the first
+func (timings *StartupTimings) Record(name string, began time.Time) bool {
+	if timings == nil {
+		return false
+	}
+
+	timings.lock.Lock()
+	defer timings.lock.Unlock()
+
+	for _, phase := range timings.phases {
+		if phase.Name == name {
+			return false
+		}
+	}
+
+	timings.finished = time.Now()
+	timings.phases = append(timings.phases, StartupPhase{name, timings.finished.Sub(began)})
+
+	return true
+}
+
+// List each phase's duration in the order they finished, and the time from startup until the last finished
+func (timings *StartupTimings) String() string {
+	timings.lock.Lock()
+	defer timings.lock.Unlock()
+
+	parts := []string{}
+	for _, phase := range timings.phases {
+		parts = append(parts, phase.Name+" "+FormatDuration(phase.Took))
+	}
+
+	if len(timings.phases) > 0 {
+		parts = append(parts, "total "+FormatDuration(timings.finished.Sub(timings.start)))
+	}
+
+	return strings.Join(parts, " · ")
+}
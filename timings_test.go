@@ -0,0 +1,29 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStartupTimings(t *testing.T) {
+	var unset *StartupTimings
+	if unset.Record("arguments", time.Now()) {
+		t.Errorf("nil timings recorded a phase")
+	}
+
+	timings := NewStartupTimings(time.Now())
+	began := time.Now()
+
+	if !timings.Record("first run", began) {
+		t.Errorf("Record() = false, want the first run recorded")
+	}
+
+	if timings.Record("first run", began) {
+		t.Errorf("Record() = true, want later runs ignored")
+	}
+
+	if got := timings.String(); !strings.HasPrefix(got, "first run ") || !strings.Contains(got, " · total ") {
+		t.Errorf("String() = %q, want the first run and total", got)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// The files, connections, and programs a traced run touched
+type SyscallSummary struct {
+	Opened    []string
+	Connected []string
+	Executed  []string
+}
+
+// strace's open and openat calls that succeeded; failures return -1
+var traceOpenPattern = regexp.MustCompile(`open(?:at)?\((?:[^,]+, )?"([^"]+)", [^)]*\)\s+=\s+\d+`)
+
+// strace's connect calls, for IPv4, IPv6, and unix sockets
+var traceConnectPattern = regexp.MustCompile(`connect\(\d+, \{sa_family=(\w+), (?:sin6?_port=htons\((\d+)\), )?(?:sin_addr=inet_addr\("([^"]+)"\)|.*?inet_pton\(AF_INET6, "([^"]+)"|sun_path="([^"]+)")`)
+
+// strace's execve calls
+var traceExecPattern = regexp.MustCompile(`execve\("([^"]+)"`)
+
+// Check syscalls can be traced on this platform
+func ValidateTracer() error {
+	if runtime.GOOS != "linux" {
+		return errors.New("syscall tracing requires strace, on linux")
+	}
+
+	if !CommandExists("strace") {
+		return errors.New("strace is not in PATH")
+	}
+
+	return nil
+}
+
+// Wrap a command line in strace, recording file, network, and exec calls of it and its children
+func TraceCommand(output string, argv []string) []string {
+	return append([]string{"strace", "-f", "-qq", "-e", "trace=%file,%network,execve", "-o", output, "--"}, argv...)
+}
+
+// Is a path noise, rather than something the program chose to open?
+func isTraceNoise(fpath string) bool {
+	for _, prefix := range []string{"/proc/", "/sys/", "/dev/", "/etc/ld.so"} {
+		if strings.HasPrefix(fpath, prefix) {
+			return true
+		}
+	}
+
+	return strings.Contains(fpath, ".so.") || strings.HasSuffix(fpath, ".so")
+}
+
+// Collect the distinct values of a set, sorted
+func sortedKeys(set map[string]bool) []string {
+	keys := []string{}
+	for key := range set {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// Summarise an strace log; the files opened, connections made, and programs executed
+func ParseTrace(data []byte) SyscallSummary {
+	opened, connected, executed := map[string]bool{}, map[string]bool{}, map[string]bool{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if match := traceOpenPattern.FindStringSubmatch(line); match != nil && !isTraceNoise(match[1]) {
+			opened[match[1]] = true
+		}
+
+		if match := traceConnectPattern.FindStringSubmatch(line); match != nil {
+			switch {
+			case len(match[3]) > 0:
+				connected[match[3]+":"+match[2]] = true
+			case len(match[4]) > 0:
+				connected["["+match[4]+"]:"+match[2]] = true
+			case len(match[5]) > 0:
+				connected[match[5]] = true
+			}
+		}
+
+		if match := traceExecPattern.FindStringSubmatch(line); match != nil {
+			executed[match[1]] = true
+		}
+	}
+
+	return SyscallSummary{sortedKeys(opened), sortedKeys(connected), sortedKeys(executed)}
+}
+
+// Read and summarise an strace log
+func TraceSummary(output string) (SyscallSummary, error) {
+	data, err := ioutil.ReadFile(output)
+	if err != nil {
+		return SyscallSummary{}, err
+	}
+
+	return ParseTrace(data), nil
+}
+
+// Describe a syscall summary for the syscalls pane
+func (summary SyscallSummary) String() string {
+	text := ""
+
+	sections := []struct {
+		title string
+		items []string
+	}{
+		{"connected", summary.Connected},
+		{"executed", summary.Executed},
+		{"opened", summary.Opened},
+	}
+
+	for _, section := range sections {
+		text += fmt.Sprintf("[yellow]%s (%d)[reset]\n", section.title, len(section.items))
+
+		for _, item := range section.items {
+			text += "  " + tview.Escape(item) + "\n"
+		}
+	}
+
+	return text
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTrace(t *testing.T) {
+	data := `123 execve("/usr/bin/python3", ["python3", "main.py"], 0x7ffd /* 20 vars */) = 0
+123 openat(AT_FDCWD, "/lib/x86_64-linux-gnu/libc.so.6", O_RDONLY|O_CLOEXEC) = 3
+123 openat(AT_FDCWD, "data.csv", O_RDONLY|O_CLOEXEC) = 3
+123 openat(AT_FDCWD, "missing.txt", O_RDONLY) = -1 ENOENT (No such file or directory)
+123 connect(4, {sa_family=AF_INET, sin_port=htons(443), sin_addr=inet_addr("93.184.216.34")}, 16) = 0
+123 connect(5, {sa_family=AF_UNIX, sun_path="/run/nscd/socket"}, 110) = -1 ENOENT (No such file or directory)
+`
+
+	want := SyscallSummary{
+		Opened:    []string{"data.csv"},
+		Connected: []string{"/run/nscd/socket", "93.184.216.34:443"},
+		Executed:  []string{"/usr/bin/python3"},
+	}
+
+	if got := ParseTrace([]byte(data)); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseTrace() = %v, want %v", got, want)
+	}
+}
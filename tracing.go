@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Export spans of each run's lifecycle to an OpenTelemetry collector, as OTLP over HTTP with JSON
+type Tracer struct {
+	Endpoint string
+	client   *http.Client
+}
+
+// A timed step of a run
+type Span struct {
+	Name       string
+	SpanID     string
+	ParentID   string
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]interface{}
+	Failed     bool
+}
+
+// The spans of one run, sharing a trace ID. Methods of a nil trace do nothing, so runs needn't
+// check whether tracing is enabled
+type RunTrace struct {
+	lock    sync.Mutex
+	tracer  *Tracer
+	TraceID string
+	Root    Span
+	Spans   []Span
+}
+
+// Construct a tracer exporting to a collector's base URL, such as http://localhost:4318
+func NewTracer(endpoint string) *Tracer {
+	return &Tracer{
+		Endpoint: strings.TrimSuffix(endpoint, "/") + OTLP_TRACES_PATH,
+		client:   &http.Client{Timeout: OTLP_TIMEOUT},
+	}
+}
+
+// A random hex identifier of a number of bytes
+func randomID(size int) string {
+	data := make([]byte, size)
+	rand.Read(data)
+
+	return hex.EncodeToString(data)
+}
+
+// Start tracing a run, from the file change that caused it
+func (tracer *Tracer) StartRun(changedAt time.Time) *RunTrace {
+	if tracer == nil {
+		return nil
+	}
+
+	return &RunTrace{
+		tracer:  tracer,
+		TraceID: randomID(16),
+		Root:    Span{Name: "run", SpanID: randomID(8), Start: changedAt, Attributes: map[string]interface{}{}},
+	}
+}
+
+// Record a step of the run
+func (trace *RunTrace) Span(name string, start time.Time, end time.Time, attributes map[string]interface{}, failed bool) {
+	if trace == nil {
+		return
+	}
+
+	trace.lock.Lock()
+	defer trace.lock.Unlock()
+
+	trace.Spans = append(trace.Spans, Span{name, randomID(8), trace.Root.SpanID, start, end, attributes, failed})
+}
+
+// End the run's trace, and export it in the background, reporting any failure
+func (trace *RunTrace) Finish(result RunResult, fpath string, onError func(error)) {
+	if trace == nil {
+		return
+	}
+
+	trace.lock.Lock()
+	trace.Root.End = time.Now()
+	trace.Root.Failed = !result.Succeeded()
+	trace.Root.Attributes["replit.file"] = fpath
+	trace.Root.Attributes["replit.exit_code"] = result.ExitCode
+	trace.Root.Attributes["replit.duration_ms"] = result.Duration.Milliseconds()
+	trace.lock.Unlock()
+
+	go func() {
+		if err := trace.tracer.Export(trace); err != nil {
+			onError(err)
+		}
+	}()
+}
+
+// Convert attributes to OTLP key-value pairs, in a stable order
+func otlpAttributes(attributes map[string]interface{}) []map[string]interface{} {
+	keys := []string{}
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := []map[string]interface{}{}
+
+	for _, key := range keys {
+		var value map[string]interface{}
+
+		switch typed := attributes[key].(type) {
+		case int:
+			value = map[string]interface{}{"intValue": strconv.Itoa(typed)}
+		case int64:
+			value = map[string]interface{}{"intValue": strconv.FormatInt(typed, 10)}
+		case bool:
+			value = map[string]interface{}{"boolValue": typed}
+		default:
+			value = map[string]interface{}{"stringValue": fmt.Sprint(typed)}
+		}
+
+		pairs = append(pairs, map[string]interface{}{"key": key, "value": value})
+	}
+
+	return pairs
+}
+
+// Render a span as OTLP JSON
+func otlpSpan(traceID string, span Span) map[string]interface{} {
+	status := OTLP_STATUS_OK
+	if span.Failed {
+		status = OTLP_STATUS_ERROR
+	}
+
+	rendered := map[string]interface{}{
+		"traceId":           traceID,
+		"spanId":            span.SpanID,
+		"name":              span.Name,
+		"kind":              OTLP_SPAN_KIND_INTERNAL,
+		"startTimeUnixNano": strconv.FormatInt(span.Start.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(span.End.UnixNano(), 10),
+		"attributes":        otlpAttributes(span.Attributes),
+		"status":            map[string]interface{}{"code": status},
+	}
+
+	if len(span.ParentID) > 0 {
+		rendered["parentSpanId"] = span.ParentID
+	}
+
+	return rendered
+}
+
+// Render a run's trace as an OTLP export request
+func OTLPRequest(trace *RunTrace) ([]byte, error) {
+	trace.lock.Lock()
+	defer trace.lock.Unlock()
+
+	spans := []map[string]interface{}{otlpSpan(trace.TraceID, trace.Root)}
+	for _, span := range trace.Spans {
+		spans = append(spans, otlpSpan(trace.TraceID, span))
+	}
+
+	request := map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": otlpAttributes(map[string]interface{}{"service.name": "replit"}),
+			},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]interface{}{"name": "replit"},
+				"spans": spans,
+			}},
+		}},
+	}
+
+	return json.Marshal(request)
+}
+
+// Send a run's trace to the collector
+func (tracer *Tracer) Export(trace *RunTrace) error {
+	data, err := OTLPRequest(trace)
+	if err != nil {
+		return err
+	}
+
+	res, err := tracer.client.Post(tracer.Endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("could not export trace: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("could not export trace: %s responded %s", tracer.Endpoint, res.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRunTraceExport(t *testing.T) {
+	received := make(chan map[string]interface{}, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("exported to %s", r.URL.Path)
+		}
+
+		data, _ := ioutil.ReadAll(r.Body)
+
+		var request map[string]interface{}
+		if err := json.Unmarshal(data, &request); err != nil {
+			t.Error(err)
+		}
+
+		received <- request
+	}))
+	defer server.Close()
+
+	changedAt := time.Unix(100, 0)
+
+	trace := NewTracer(server.URL + "/").StartRun(changedAt)
+	trace.Span("debounce", changedAt, time.Unix(101, 0), nil, false)
+	trace.Span("exec", time.Unix(101, 0), time.Unix(103, 0), map[string]interface{}{"replit.exit_code": 1}, true)
+	trace.Finish(RunResult{ExitCode: 1, Duration: 2 * time.Second}, "main.py", func(err error) {
+		t.Error(err)
+	})
+
+	var request map[string]interface{}
+	select {
+	case request = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no trace was exported")
+	}
+
+	scopes := request["resourceSpans"].([]interface{})[0].(map[string]interface{})["scopeSpans"].([]interface{})
+	spans := scopes[0].(map[string]interface{})["spans"].([]interface{})
+
+	if len(spans) != 3 {
+		t.Fatalf("exported %d spans, want 3", len(spans))
+	}
+
+	root := spans[0].(map[string]interface{})
+	exec := spans[2].(map[string]interface{})
+
+	if root["name"] != "run" || root["startTimeUnixNano"] != "100000000000" {
+		t.Errorf("root span = %v", root)
+	}
+
+	if exec["parentSpanId"] != root["spanId"] || exec["traceId"] != root["traceId"] {
+		t.Errorf("exec span = %v isn't a child of %v", exec, root)
+	}
+
+	if exec["status"].(map[string]interface{})["code"] != float64(OTLP_STATUS_ERROR) {
+		t.Errorf("exec span status = %v", exec["status"])
+	}
+
+	want := []interface{}{map[string]interface{}{"key": "replit.exit_code", "value": map[string]interface{}{"intValue": "1"}}}
+	if got := exec["attributes"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("exec attributes = %v, want %v", got, want)
+	}
+}
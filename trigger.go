@@ -0,0 +1,13 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+)
+
+// The name of the file the session watching a directory is triggered through, in the user's trigger
+// directory. The latest session to watch a directory receives its triggers
+func triggerName(dpath string) string {
+	sum := sha1.Sum([]byte(dpath))
+	return "replit-" + hex.EncodeToString(sum[:])[:12]
+}
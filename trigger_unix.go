@@ -0,0 +1,121 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+)
+
+// The directory sessions' trigger sockets are kept in, in $XDG_RUNTIME_DIR or $TMPDIR. It's created
+// readable only by the user, and rejected if anyone else could have made it, so other users can't
+// trigger sessions or impersonate them
+func TriggerDir() (string, error) {
+	base := os.Getenv("XDG_RUNTIME_DIR")
+	if len(base) == 0 {
+		base = os.TempDir()
+	}
+
+	dir := filepath.Join(base, fmt.Sprintf("replit-%d", os.Getuid()))
+	if err := os.Mkdir(dir, 0700); err != nil && !os.IsExist(err) {
+		return "", err
+	}
+
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return "", err
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !info.IsDir() || !ok || int(stat.Uid) != os.Getuid() || info.Mode().Perm()&0077 != 0 {
+		return "", fmt.Errorf("%s isn't a directory only you can access", dir)
+	}
+
+	return dir, nil
+}
+
+// Where the session watching a directory listens for triggers
+func TriggerPath(dpath string) (string, error) {
+	dir, err := TriggerDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, triggerName(dpath)+".sock"), nil
+}
+
+// Ask the session watching a directory to rerun, by connecting to its socket
+func SendTrigger(dpath string) error {
+	fpath, err := TriggerPath(dpath)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.Dial("unix", fpath)
+	if err != nil {
+		return fmt.Errorf("no replit session is watching %s", dpath)
+	}
+
+	return conn.Close()
+}
+
+// Rerun on each connection to the directory's trigger socket, replacing any earlier session's.
+// Returns a function to stop listening, which removes the socket unless another session has since
+// replaced it
+func ListenForTriggers(dpath string, rerun func()) (func(), error) {
+	fpath, err := TriggerPath(dpath)
+	if err != nil {
+		return nil, err
+	}
+
+	os.Remove(fpath)
+
+	listener, err := net.Listen("unix", fpath)
+	if err != nil {
+		return nil, err
+	}
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+
+	created, err := os.Stat(fpath)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			conn.Close()
+			rerun()
+		}
+	}()
+
+	stop := func() {
+		listener.Close()
+
+		if current, err := os.Stat(fpath); err == nil && os.SameFile(created, current) {
+			os.Remove(fpath)
+		}
+	}
+
+	return stop, nil
+}
+
+// Rerun whenever SIGUSR1 is received, as from 'kill -USR1'
+func RerunOnSignal(tui *TUI) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGUSR1)
+
+	for range sigs {
+		tui.actions.fileChange.Broadcast()
+	}
+}
@@ -0,0 +1,87 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSendTrigger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replit-trigger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer os.Setenv("XDG_RUNTIME_DIR", os.Getenv("XDG_RUNTIME_DIR"))
+	os.Setenv("XDG_RUNTIME_DIR", dir)
+
+	if err := SendTrigger(dir); err == nil {
+		t.Fatal("expected an error when no session is watching the directory")
+	}
+
+	triggered := make(chan bool, 1)
+	stop, err := ListenForTriggers(dir, func() { triggered <- true })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SendTrigger(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-triggered:
+	case <-time.After(time.Second):
+		t.Error("expected the session to be triggered")
+	}
+
+	fpath, _ := TriggerPath(dir)
+	stop()
+
+	if _, err := os.Stat(fpath); err == nil {
+		t.Error("expected the socket to be removed")
+	}
+
+	if err := SendTrigger(dir); err == nil {
+		t.Error("expected an error once the session stopped listening")
+	}
+}
+
+func TestTriggerDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replit-trigger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer os.Setenv("XDG_RUNTIME_DIR", os.Getenv("XDG_RUNTIME_DIR"))
+	os.Setenv("XDG_RUNTIME_DIR", dir)
+
+	triggerDir, err := TriggerDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info, err := os.Stat(triggerDir); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("expected a directory only the user can access, but got %v, %v", info.Mode(), err)
+	}
+
+	// a directory others can write to could have been made by them
+	os.Chmod(triggerDir, 0777)
+	if _, err := TriggerDir(); err == nil {
+		t.Error("expected a directory others can write to to be rejected")
+	}
+
+	os.RemoveAll(triggerDir)
+	os.Symlink(dir, filepath.Join(dir, fmt.Sprintf("replit-%d", os.Getuid())))
+	if _, err := TriggerDir(); err == nil {
+		t.Error("expected a symlink to be rejected")
+	}
+}
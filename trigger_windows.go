@@ -0,0 +1,132 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// Windows has no unix sockets to rely on, so sessions wait on a named event instead, and record their
+// pid so 'replit trigger' can find it
+var (
+	procCreateEvent = kernel32.NewProc("CreateEventW")
+	procOpenEvent   = kernel32.NewProc("OpenEventW")
+	procSetEvent    = kernel32.NewProc("SetEvent")
+)
+
+// OpenEventW's access right to set an event
+const eventModifyState = 0x0002
+
+// The directory sessions' pid files are kept in. Windows' temporary directory is the user's own
+func TriggerDir() (string, error) {
+	dir := filepath.Join(os.TempDir(), "replit")
+	return dir, os.MkdirAll(dir, 0700)
+}
+
+// Where the session watching a directory records its pid
+func TriggerPath(dpath string) (string, error) {
+	dir, err := TriggerDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, triggerName(dpath)+".pid"), nil
+}
+
+// The name of the event a session with a pid reruns on. Only sessions create these, so a stale pid
+// file can't trigger another program
+func triggerEventName(pid int) *uint16 {
+	name, _ := syscall.UTF16PtrFromString(`Local\replit-trigger-` + strconv.Itoa(pid))
+	return name
+}
+
+// Ask the session watching a directory to rerun
+func SendTrigger(dpath string) error {
+	fpath, err := TriggerPath(dpath)
+	if err != nil {
+		return err
+	}
+
+	content, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return fmt.Errorf("no replit session is watching %s", dpath)
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return fmt.Errorf("%s is not a valid pid file", fpath)
+	}
+
+	event, _, _ := procOpenEvent.Call(eventModifyState, 0, uintptr(unsafe.Pointer(triggerEventName(pid))))
+	if event == 0 {
+		return fmt.Errorf("the session watching %s (pid %d) is no longer running", dpath, pid)
+	}
+	defer syscall.CloseHandle(syscall.Handle(event))
+
+	if ok, _, err := procSetEvent.Call(event); ok == 0 {
+		return fmt.Errorf("could not trigger the session watching %s: %v", dpath, err)
+	}
+
+	return nil
+}
+
+// Rerun whenever the session's event is set, and record its pid for the directory, replacing any
+// earlier session's. Returns a function to stop, which removes the pid file unless another session
+// has since replaced it
+func ListenForTriggers(dpath string, rerun func()) (func(), error) {
+	fpath, err := TriggerPath(dpath)
+	if err != nil {
+		return nil, err
+	}
+
+	// an auto-reset event, so each trigger reruns once
+	event, _, err := procCreateEvent.Call(0, 0, 0, uintptr(unsafe.Pointer(triggerEventName(os.Getpid()))))
+	if event == 0 {
+		return nil, err
+	}
+
+	pid := strconv.Itoa(os.Getpid())
+	if err := ioutil.WriteFile(fpath, []byte(pid+"\n"), 0600); err != nil {
+		syscall.CloseHandle(syscall.Handle(event))
+		return nil, err
+	}
+
+	stopped := make(chan bool)
+
+	go func() {
+		for {
+			if _, err := syscall.WaitForSingleObject(syscall.Handle(event), syscall.INFINITE); err != nil {
+				return
+			}
+
+			select {
+			case <-stopped:
+				return
+			default:
+				rerun()
+			}
+		}
+	}()
+
+	stop := func() {
+		close(stopped)
+
+		content, err := ioutil.ReadFile(fpath)
+		if err == nil && strings.TrimSpace(string(content)) == pid {
+			os.Remove(fpath)
+		}
+	}
+
+	return stop, nil
+}
+
+// Windows has no SIGUSR1; sessions are triggered through their event
+func RerunOnSignal(tui *TUI) {}
@@ -1,8 +1,14 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gdamore/tcell/v2"
@@ -12,14 +18,79 @@ import (
 type TUI struct {
 	actions          *TuiActions
 	header           *tview.TextView
+	headerText       string
 	app              *tview.Application
-	stdoutViewer     *tview.TextView
-	stderrViewer     *tview.TextView
+	onInput          func(event *tcell.EventKey) *tcell.EventKey
+	onMouse          func(event *tcell.EventMouse, action tview.MouseAction) (*tcell.EventMouse, tview.MouseAction)
+	tabs             *Tabs
+	status           string
+	stdoutViewer     *LogView
+	stderrViewer     *LogView
+	stdoutBuffer     *OutputBuffer
+	stderrBuffer     *OutputBuffer
+	stderrFolded     bool
+	stdoutMode       string
+	tapDismissed     bool
+	combinedViewer   *LogView
+	combined         *CombinedView
+	showCombined     bool
+	artifactsViewer  *tview.List
+	showArtifacts    bool
+	previewViewer    *ImageView
+	pinnedViewer     *LogView
+	showPinned       bool
+	envViewer        *tview.TextView
+	showEnv          bool
+	showPreview      bool
+	batchViewer      *tview.List
+	batchOutput      *tview.TextView
+	showBatch        bool
+	showBatchOutput  bool
+	history          *History
+	historyViewer    *tview.List
+	historyOutput    *tview.TextView
+	showHistory      bool
+	showHistoryOut   bool
+	runtimeNotes     bool
+	timings          *StartupTimings
+	linear           *LinearReport
+	snapshotDir      string
+	junitPath        string
+	events           *EventLogger
+	webhooks         []Webhook
+	lang             string
+	attempt          int
+	retries          int
+	profileViewer    *tview.TextView
+	showProfile      bool
+	diffViewer       *tview.TextView
+	showDiff         bool
+	syscallViewer    *tview.TextView
+	showSyscalls     bool
+	problemsViewer   *tview.List
+	showProblems     bool
+	coverage         string
+	lastCoverage     float64
+	hasCoverage      bool
+	dpath            string
+	editorFile       *EditorFile
+	versions         *FileVersions
+	focus            *TestFocus
+	source           []byte
+	session          *Session
+	focused          FocusablePane
 	helpBar          *tview.TextView
+	errorBar         *tview.TextView
 	runCountViewer   *tview.TextView
 	runSecondsViewer *tview.TextView
+	sparklineViewer  *tview.TextView
+	memoryViewer     *tview.TextView
+	statsViewer      *tview.TextView
+	stats            *RunStats
+	runLock          sync.Mutex
 	runCount         int64
 	runTime          int64
+	changedFiles     int32
 }
 
 // Set initial theme overrides, so tview uses default
@@ -32,7 +103,18 @@ func (tui *TUI) SetTheme() {
 func NewHeader(tui *TUI) *tview.TextView {
 	return tview.NewTextView().
 		SetDynamicColors(true).
-		SetText(HEADER_TEXT)
+		SetText(tui.headerText)
+}
+
+// Values substituted into the header and help bar templates
+func TemplateValues(args *ReplitArgs) map[string]string {
+	return map[string]string{
+		"file":    tview.Escape(args.EditorFile.File.Name()),
+		"lang":    tview.Escape(args.Lang),
+		"dir":     tview.Escape(args.Dpath),
+		"profile": tview.Escape(args.Profile),
+		"keys":    HELP_KEYS,
+	}
 }
 
 type TuiActions struct {
@@ -67,29 +149,169 @@ func attachListener(cd *sync.Cond, listener func()) {
 }
 
 // TView application
-func NewApplication(tui *TUI) *tview.Application {
+func NewApplication(tui *TUI, args *ReplitArgs) *tview.Application {
 	onInput := func(event *tcell.EventKey) *tcell.EventKey {
+		if args.Recorder != nil && event.Key() == tcell.KeyRune {
+			args.Recorder.Event("i", string(event.Rune()))
+		}
+
 		if event.Rune() == 'k' {
 			tui.actions.killProcess.Broadcast()
 			return nil
 		}
 
+		if event.Rune() == 'c' {
+			tui.ToggleCombined()
+			return nil
+		}
+
+		if event.Rune() == 'a' {
+			tui.ToggleArtifacts()
+			return nil
+		}
+
+		if event.Rune() == 'x' {
+			tui.ToggleStdoutMode(STDOUT_HEX)
+			return nil
+		}
+
+		if event.Rune() == 'j' {
+			tui.ToggleStdoutMode(STDOUT_JSON)
+			return nil
+		}
+
+		if event.Rune() == 'i' {
+			tui.TogglePreview()
+			return nil
+		}
+
+		if event.Rune() == 'm' {
+			tui.ToggleStdoutMode(STDOUT_MARKDOWN)
+			return nil
+		}
+
+		if event.Rune() == 'l' {
+			// once TAP output is shown raw, it isn't summarised again automatically
+			tui.tapDismissed = tui.stdoutMode == STDOUT_TAP
+			tui.ToggleStdoutMode(STDOUT_TAP)
+			return nil
+		}
+
+		if event.Rune() == 'p' {
+			tui.TogglePin()
+			return nil
+		}
+
+		if event.Rune() == 'e' {
+			tui.ToggleEnv()
+			return nil
+		}
+
+		if event.Rune() == 'b' {
+			tui.ToggleBatch()
+			return nil
+		}
+
+		if event.Rune() == 'h' {
+			tui.ToggleHistory()
+			return nil
+		}
+
+		if event.Rune() == 'o' {
+			tui.ToggleProfile()
+			return nil
+		}
+
+		if event.Rune() == 't' {
+			tui.ToggleSyscalls()
+			return nil
+		}
+
+		if event.Rune() == 'd' {
+			tui.ToggleProblems()
+			return nil
+		}
+
+		if event.Rune() == 'r' {
+			tui.ResetStats()
+			return nil
+		}
+
+		if event.Rune() == 'u' {
+			tui.UndoFile()
+			return nil
+		}
+
+		if event.Rune() == 'v' {
+			tui.ToggleDiff()
+			return nil
+		}
+
+		if event.Rune() == 'f' && args.Focus != nil {
+			args.Focus.Toggle()
+			tui.RefreshHeader()
+			tui.actions.fileChange.Broadcast()
+			return nil
+		}
+
+		// expand or fold long stack traces, unless Enter selects from the focused list
+		if _, isList := tui.focused.(*tview.List); event.Key() == tcell.KeyEnter && !isList {
+			tui.ToggleFold()
+			return nil
+		}
+
+		if event.Key() == tcell.KeyTab {
+			tui.CycleFocus(1)
+			return nil
+		}
+
+		if event.Key() == tcell.KeyBacktab {
+			tui.CycleFocus(-1)
+			return nil
+		}
+
+		// quit as Ctrl-C does; the session is tidied up once the interface stops
 		if event.Key() == tcell.KeyEscape || event.Rune() == 'q' {
-			panic("implement quit")
+			tui.app.Stop()
+			return nil
 		}
 
 		return event
 	}
 
+	// clicks focus the pane beneath them, and the wheel scrolls the focused pane
+	onMouse := func(event *tcell.EventMouse, action tview.MouseAction) (*tcell.EventMouse, tview.MouseAction) {
+		switch action {
+		case tview.MouseLeftClick:
+			for _, pane := range tui.FocusablePanes() {
+				if pane.InRect(event.Position()) {
+					tui.Focus(pane)
+				}
+			}
+		case tview.MouseScrollUp:
+			tui.ScrollFocused(-WHEEL_SCROLL_LINES)
+			return nil, action
+		case tview.MouseScrollDown:
+			tui.ScrollFocused(WHEEL_SCROLL_LINES)
+			return nil, action
+		}
+
+		return event, action
+	}
+
+	// kept, so tabbed sessions can pass the current tab its events
+	tui.onInput = onInput
+	tui.onMouse = onMouse
+
 	return tview.NewApplication().
-		EnableMouse(true).
+		EnableMouse(!args.NoMouse).
+		SetMouseCapture(onMouse).
 		SetInputCapture(onInput)
 }
 
 // Show command output text
-func NewStdoutViewer(tui *TUI) *tview.TextView {
-	view := tview.NewTextView().
-		SetDynamicColors(true)
+func NewStdoutViewer(tui *TUI) *LogView {
+	view := NewLogView()
 
 	view.
 		SetText(STDOUT_TEXT).Box.SetBorder(true)
@@ -98,9 +320,8 @@ func NewStdoutViewer(tui *TUI) *tview.TextView {
 }
 
 // Show command output text
-func NewStderrViewer(tui *TUI) *tview.TextView {
-	view := tview.NewTextView().
-		SetDynamicColors(true)
+func NewStderrViewer(tui *TUI) *LogView {
+	view := NewLogView()
 
 	view.
 		SetText(STDERR_TEXT).Box.SetBorder(true)
@@ -108,10 +329,148 @@ func NewStderrViewer(tui *TUI) *tview.TextView {
 	return view
 }
 
+// Show stdout and stderr interleaved, in the order they were written
+func NewCombinedViewer(tui *TUI) *LogView {
+	view := NewLogView()
+
+	view.
+		SetText(STDOUT_TEXT).Box.SetBorder(true)
+
+	return view
+}
+
+// List files created or modified by the last run
+func NewArtifactsViewer(tui *TUI) *tview.List {
+	list := tview.NewList().
+		ShowSecondaryText(false)
+
+	list.SetBorder(true).SetTitle(" Artifacts ")
+
+	return list
+}
+
+// Preview the latest image a run wrote
+func NewImagePreview(tui *TUI) *ImageView {
+	view := NewImageView(ImageProtocol())
+	view.SetBorder(true).SetTitle(" Preview ")
+
+	return view
+}
+
+// Show a pinned run's stdout, for comparison with later runs
+func NewPinnedViewer(tui *TUI) *LogView {
+	view := NewLogView()
+
+	view.SetBorder(true)
+
+	return view
+}
+
+// List the environment variables the program receives
+func NewEnvViewer(tui *TUI, args *ReplitArgs) *tview.TextView {
+	view := tview.NewTextView().
+		SetDynamicColors(true)
+
+	view.SetBorder(true).SetTitle(" Environment ")
+
+	for _, pair := range ChildEnv(args) {
+		parts := strings.SplitN(pair, "=", 2)
+
+		value := tview.Escape(parts[1])
+		if IsMasked(args, parts[0]) {
+			value = MASKED_VALUE
+		}
+
+		fmt.Fprintf(view, "[blue]%s[reset]=%s\n", tview.Escape(parts[0]), value)
+	}
+
+	return view
+}
+
+// List each batch file's status; selecting one expands its output
+func NewBatchViewer(tui *TUI) *tview.List {
+	list := tview.NewList().
+		ShowSecondaryText(false)
+
+	list.SetBorder(true).SetTitle(" Batch ")
+
+	return list
+}
+
+// Show the output of the selected batch file
+func NewBatchOutput(tui *TUI) *tview.TextView {
+	view := tview.NewTextView().
+		SetDynamicColors(true)
+
+	view.SetBorder(true)
+
+	return view
+}
+
+// List previous runs; selecting one shows its output
+func NewHistoryViewer(tui *TUI) *tview.List {
+	list := tview.NewList().
+		ShowSecondaryText(false)
+
+	list.SetBorder(true).SetTitle(" History ")
+
+	return list
+}
+
+// Show the output of the selected previous run
+func NewHistoryOutput(tui *TUI) *tview.TextView {
+	view := tview.NewTextView().
+		SetDynamicColors(true)
+
+	view.SetBorder(true)
+
+	return view
+}
+
+// Show what changed in the code and its output between the last two runs
+func NewDiffViewer(tui *TUI) *tview.TextView {
+	view := tview.NewTextView().
+		SetDynamicColors(true)
+
+	view.SetBorder(true).SetTitle(" Diff ")
+
+	return view
+}
+
+// Show the functions the last profiled run spent most time in
+func NewProfileViewer(tui *TUI) *tview.TextView {
+	view := tview.NewTextView().
+		SetDynamicColors(true)
+
+	view.SetBorder(true).SetTitle(" Profile ")
+
+	return view
+}
+
+// Show the files, connections, and programs the last traced run touched
+func NewSyscallViewer(tui *TUI) *tview.TextView {
+	view := tview.NewTextView().
+		SetDynamicColors(true)
+
+	view.SetBorder(true).SetTitle(" Syscalls ")
+
+	return view
+}
+
+// List the problems diagnosed in the last run
+func NewProblemsViewer(tui *TUI) *tview.List {
+	list := tview.NewList().
+		ShowSecondaryText(false)
+
+	list.SetBorder(true).SetTitle(" Problems ")
+
+	return list
+}
+
 func NewRunCount(tui *TUI) *tview.TextView {
 	return tview.NewTextView().
 		SetDynamicColors(true).
-		SetText("run " + fmt.Sprint(tui.runCount) + " times")
+		SetText("run " + fmt.Sprint(tui.RunCount()) + " times")
 }
 
 func NewRunTime(tui *TUI) *tview.TextView {
@@ -120,60 +479,1072 @@ func NewRunTime(tui *TUI) *tview.TextView {
 		SetText(fmt.Sprint(tui.runTime) + "ms")
 }
 
+// Plot recent run durations
+func NewSparkline(tui *TUI) *tview.TextView {
+	return tview.NewTextView().
+		SetDynamicColors(true).
+		SetTextColor(tcell.ColorYellow)
+}
+
+// Show the peak memory used by the last run
+func NewMemoryViewer(tui *TUI) *tview.TextView {
+	return tview.NewTextView().
+		SetDynamicColors(true)
+}
+
+// Show statistics for the runs in this session
+func NewStatsViewer(tui *TUI) *tview.TextView {
+	return tview.NewTextView().
+		SetDynamicColors(true).
+		SetText(tui.stats.String())
+}
+
 // Construct all UI components
 func NewUI(args *ReplitArgs) *TUI {
 	tui := TUI{}
 	tui.SetTheme()
 
+	tui.showCombined = args.Combined
+	tui.stats = &RunStats{}
+	tui.headerText = HEADER_TEXT
+	if len(args.Config.Header) > 0 {
+		tui.headerText = ExpandTemplate(args.Config.Header, TemplateValues(args))
+	}
+
 	tui.actions = NewActions(&tui)
-	tui.app = NewApplication(&tui)
+	tui.app = NewApplication(&tui, args)
 	tui.header = NewHeader(&tui)
 	tui.helpBar = NewHelpbar(&tui, args)
+	tui.errorBar = NewErrorBar(&tui)
 	tui.stdoutViewer = NewStdoutViewer(&tui)
 	tui.stderrViewer = NewStderrViewer(&tui)
+	tui.stdoutBuffer = NewOutputBuffer(tui.stdoutViewer)
+	tui.stderrBuffer = NewOutputBuffer(tui.stderrViewer)
+	tui.combinedViewer = NewCombinedViewer(&tui)
+	tui.combined = NewCombinedView(tui.combinedViewer)
+	tui.artifactsViewer = NewArtifactsViewer(&tui)
+	tui.previewViewer = NewImagePreview(&tui)
+	tui.app.SetAfterDrawFunc(tui.previewViewer.AfterDraw)
+	tui.pinnedViewer = NewPinnedViewer(&tui)
+	tui.envViewer = NewEnvViewer(&tui, args)
+	tui.batchViewer = NewBatchViewer(&tui)
+	tui.batchOutput = NewBatchOutput(&tui)
+	tui.showBatch = len(args.Batch) > 0
+	tui.dpath = args.Dpath
+	tui.editorFile = args.EditorFile
+	tui.versions = &FileVersions{}
+	tui.focus = args.Focus
+	tui.session = args.Session
+	tui.history = NewHistory(HISTORY_MEMORY_RUNS, args.Session)
+	tui.historyViewer = NewHistoryViewer(&tui)
+	tui.historyOutput = NewHistoryOutput(&tui)
+	tui.snapshotDir = args.SnapshotDir
+	tui.junitPath = args.JUnitPath
+	tui.events = args.Events
+	tui.webhooks = args.Config.Webhooks
+	tui.lang = args.Lang
+	tui.profileViewer = NewProfileViewer(&tui)
+	tui.showProfile = len(args.ProfileRun) > 0
+	tui.syscallViewer = NewSyscallViewer(&tui)
+	tui.diffViewer = NewDiffViewer(&tui)
+	tui.problemsViewer = NewProblemsViewer(&tui)
+	tui.showSyscalls = len(args.TraceOutput) > 0
+
+	tui.stdoutBuffer.SetInterpretCR(!args.RawCR)
+	tui.stderrBuffer.SetInterpretCR(!args.RawCR)
+	if len(args.Charset) > 0 {
+		tui.stdoutBuffer.SetCharset(args.Charset)
+		tui.stderrBuffer.SetCharset(args.Charset)
+	}
+	tui.stdoutBuffer.SetKeepScroll(args.KeepScroll)
+	tui.runtimeNotes = args.RuntimeNotes
+	tui.timings = args.Timings
+	if args.Linear {
+		tui.linear = NewLinearReport(os.Stdout)
+	}
+	tui.stderrBuffer.SetKeepScroll(args.KeepScroll)
+	tui.stdoutBuffer.SetBatched(true)
+	tui.stderrBuffer.SetBatched(true)
+
+	tui.stdoutMode = STDOUT_RAW
+	tui.stderrFolded = true
+	tui.stderrBuffer.SetTransform(FoldStderr)
 	tui.runCountViewer = NewRunCount(&tui)
 	tui.runSecondsViewer = NewRunTime(&tui)
+	tui.statsViewer = NewStatsViewer(&tui)
+	tui.sparklineViewer = NewSparkline(&tui)
+	tui.memoryViewer = NewMemoryViewer(&tui)
+
+	if tui.focus != nil {
+		tui.RefreshHeader()
+	}
 
 	return &tui
 }
 
+// Restore the file as it was at the previous run. Writing it triggers a rerun
+func (tui *TUI) UndoFile() {
+	content, ok := tui.versions.Previous()
+	if !ok {
+		tui.ReportError(errors.New("no earlier version of the file to undo to"))
+		return
+	}
+
+	if err := ioutil.WriteFile(tui.editorFile.File.Name(), content, 0644); err != nil {
+		tui.ReportError(fmt.Errorf("could not restore the previous version: %v", err))
+	}
+}
+
+// Show runtime failures in a banner, so any subsystem can report problems without ending the session
+func NewErrorBar(tui *TUI) *tview.TextView {
+	return tview.NewTextView().
+		SetDynamicColors(true)
+}
+
+// Post an error to the error banner
+func (tui *TUI) ReportError(err error) {
+	tui.errorBar.SetText("[red]error:[reset] " + tview.Escape(err.Error()))
+	tui.linear.Error(err)
+	tui.app.Draw()
+}
+
+// Remove any error from the error banner
+func (tui *TUI) ClearError() {
+	tui.errorBar.Clear()
+}
+
 func (tui *TUI) UpdateRunCount() {
+	tui.runLock.Lock()
 	tui.runCount += 1
-	tui.runCountViewer.SetText("run " + fmt.Sprint(tui.runCount) + " times")
+	count := tui.runCount
+	tui.runLock.Unlock()
+
+	tui.runCountViewer.SetText("run " + fmt.Sprint(count) + " times")
 }
 
-func (tui *TUI) UpdateRunTime(diff time.Duration) {
-	tui.runTime = diff.Milliseconds()
-	tui.runSecondsViewer.SetText(fmt.Sprint(tui.runTime) + "ms")
+// How many runs have finished; read from the share server's goroutines as well as the session's
+func (tui *TUI) RunCount() int64 {
+	tui.runLock.Lock()
+	defer tui.runLock.Unlock()
+
+	return tui.runCount
 }
 
-// Arrange TUI components into a grid
-func (tui *TUI) Grid() *tview.Grid {
-	return tview.NewGrid().
-		SetBorders(false).
-		SetRows(1, 0, 1, 1).
-		SetColumns(-4, -2, -1, -1).
-		AddItem(tui.header, ROW_0, COL_0, ROWSPAN_1, COLSPAN_2, MINWIDTH_0, MINHEIGHT_0, true).
-		AddItem(tui.runCountViewer, ROW_0, COL_2, ROWSPAN_1, COLSPAN_1, MINWIDTH_0, MINHEIGHT_0, true).
-		AddItem(tui.runSecondsViewer, ROW_0, COL_3, ROWSPAN_1, COLSPAN_1, MINWIDTH_0, MINHEIGHT_0, true).
-		AddItem(tui.stdoutViewer, ROW_1, COL_0, ROWSPAN_1, COLSPAN_1, MINWIDTH_0, MINHEIGHT_0, true).
-		AddItem(tui.stderrViewer, ROW_1, COL_1, ROWSPAN_1, COLSPAN_3, MINWIDTH_0, MINHEIGHT_0, true).
-		AddItem(tview.NewTextView(), ROW_2, COL_0, ROWSPAN_1, COLSPAN_4, MINWIDTH_0, MINHEIGHT_0, false).
-		AddItem(tui.helpBar, ROW_3, COL_0, ROWSPAN_1, COLSPAN_4, MINWIDTH_0, MINHEIGHT_0, false)
+// Colour the output pane borders, as an ambient signal of the run's state
+func (tui *TUI) SetBorderColor(color tcell.Color) {
+	tui.stdoutViewer.SetBorderColor(color)
+	tui.stderrViewer.SetBorderColor(color)
+	tui.combinedViewer.SetBorderColor(color)
 }
 
-// Start the TUI
-func (tui *TUI) Start() {
-	grid := tui.Grid()
+// Clear all output views ahead of a run. The views are rewritten as its output arrives, rather than
+// blanked, so output that is the same as the last run's doesn't flicker
+func (tui *TUI) ClearOutput() {
+	tui.stdoutBuffer.Clear()
+	tui.stderrBuffer.Clear()
 
-	if err := tui.app.SetRoot(grid, true).SetFocus(grid).Run(); err != nil {
-		fmt.Printf("RL: Application crashed! %v", err)
+	tui.combinedViewer.Rewrite()
+
+	tui.combined.Reset()
+}
+
+// Explain why a run stopped beneath its partial output, if it was killed or timed out, and note
+// if it ran much slower or faster than recent runs, and how long startup took, if requested
+func (tui *TUI) ShowFooter(result RunResult) {
+	lines := []string{}
+	if footer := result.Footer(); len(footer) > 0 {
+		lines = append(lines, "[red]"+footer+"[reset]")
+	}
+
+	if ratio, changed := tui.stats.CompareRuntime(result.Duration); tui.runtimeNotes && changed {
+		lines = append(lines, "[yellow]"+RuntimeChangeLabel(ratio)+"[reset]")
+	}
+
+	if tui.timings.Record("first run", result.StartedAt) {
+		lines = append(lines, "[gray]startup timings: "+tui.timings.String()+"[reset]")
+	}
+
+	if len(lines) == 0 {
+		return
 	}
+
+	footer := strings.Join(lines, "\n")
+	tui.stdoutBuffer.SetFooter(footer)
+	fmt.Fprintf(tui.combinedViewer, "\n%s\n", footer)
 }
 
-// Show help-text to help user's use Replit
-func NewHelpbar(tui *TUI, args *ReplitArgs) *tview.TextView {
+// Write output held since the last frame to the views
+func (tui *TUI) FlushOutput() {
+	tui.stdoutBuffer.Flush()
+	tui.stderrBuffer.Flush()
+}
+
+// Re-render output views once a run finishes, removing output left from the last run
+func (tui *TUI) RefreshOutput() {
+	tui.stdoutBuffer.Refresh()
+	tui.stderrBuffer.Refresh()
+	tui.combinedViewer.EndRewrite()
+}
+
+// Fold each long stack trace in stderr to its first and last lines, keeping them readable
+func FoldStderr(data []byte) string {
+	return FoldTraces(data, FOLD_HEAD_LINES, FOLD_TAIL_LINES)
+}
+
+// Select how stdout is rendered
+func StdoutTransform(mode string) func(data []byte) string {
+	switch mode {
+	case STDOUT_HEX:
+		return HexDump
+	case STDOUT_JSON:
+		return PrettyJSON
+	case STDOUT_MARKDOWN:
+		return RenderMarkdown
+	case STDOUT_TAP:
+		return RenderTAP
+	}
+
+	return nil
+}
+
+// Render stdout in another mode, or as raw text if that mode is already selected
+func (tui *TUI) ToggleStdoutMode(mode string) {
+	if tui.stdoutMode == mode {
+		mode = STDOUT_RAW
+	}
+
+	tui.stdoutMode = mode
+	tui.stdoutBuffer.SetBinary(mode == STDOUT_HEX)
+	tui.stdoutBuffer.SetTransform(StdoutTransform(mode))
+	tui.stdoutBuffer.Refresh()
+
+	if mode == STDOUT_RAW {
+		tui.stdoutViewer.SetTitle("")
+	} else {
+		tui.stdoutViewer.SetTitle(" " + mode + " ")
+	}
+}
+
+// Summarise stdout as TAP if the run printed TAP, unless raw stdout was asked for
+func (tui *TUI) DetectTAP() {
+	if tui.stdoutMode != STDOUT_RAW || tui.tapDismissed {
+		return
+	}
+
+	if _, ok := ParseTAP(tui.stdoutBuffer.Bytes()); ok {
+		tui.ToggleStdoutMode(STDOUT_TAP)
+	}
+}
+
+// Expand or fold long stderr output
+func (tui *TUI) ToggleFold() {
+	tui.stderrFolded = !tui.stderrFolded
+
+	if tui.stderrFolded {
+		tui.stderrBuffer.SetTransform(FoldStderr)
+	} else {
+		tui.stderrBuffer.SetTransform(nil)
+	}
+
+	tui.stderrBuffer.Refresh()
+}
+
+// Switch between separate stdout and stderr panes, and a combined view
+func (tui *TUI) ToggleCombined() {
+	tui.showCombined = !tui.showCombined
+	tui.Relayout()
+}
+
+// Show or hide the artifacts pane, focusing it when shown so files can be opened
+func (tui *TUI) ToggleArtifacts() {
+	tui.showArtifacts = !tui.showArtifacts
+	tui.Relayout()
+
+	if tui.showArtifacts {
+		tui.Focus(tui.artifactsViewer)
+	}
+}
+
+// List the files a run created or modified; selecting one opens it
+func (tui *TUI) ShowArtifacts(files []string, dpath string) {
+	tui.app.QueueUpdateDraw(func() {
+		tui.artifactsViewer.Clear()
+		tui.artifactsViewer.SetTitle(" Artifacts (" + fmt.Sprint(len(files)) + ") ")
+
+		for _, fpath := range files {
+			fpath := fpath
+
+			name, err := filepath.Rel(dpath, fpath)
+			if err != nil {
+				name = fpath
+			}
+
+			tui.artifactsViewer.AddItem(tview.Escape(name), "", 0, func() {
+				if err := OpenFile(fpath); err != nil {
+					tui.ReportError(fmt.Errorf("could not open %s: %v", fpath, err))
+				}
+			})
+		}
+	})
+
+	// preview the last image written, if any
+	for idx := len(files) - 1; idx >= 0; idx-- {
+		if IsImage(files[idx]) {
+			tui.PreviewImage(files[idx], dpath)
+			break
+		}
+	}
+}
+
+// Pin the current stdout beside the output panes, or unpin it
+func (tui *TUI) TogglePin() {
+	tui.showPinned = !tui.showPinned
+
+	if tui.showPinned {
+		tui.pinnedViewer.SetText(tui.stdoutViewer.GetText())
+		tui.pinnedViewer.SetTitle(" Pinned: run " + fmt.Sprint(tui.RunCount()) + " ")
+	}
+
+	tui.Relayout()
+}
+
+// Show or hide the environment pane
+func (tui *TUI) ToggleEnv() {
+	tui.showEnv = !tui.showEnv
+	tui.Relayout()
+}
+
+// Show or hide the batch pane
+func (tui *TUI) ToggleBatch() {
+	tui.showBatch = !tui.showBatch
+	tui.Relayout()
+}
+
+// List each batch file's latest status, keeping the current selection
+func (tui *TUI) ShowBatch(results []BatchResult) {
+	tui.app.QueueUpdateDraw(func() {
+		current := tui.batchViewer.GetCurrentItem()
+		passed := 0
+
+		tui.batchViewer.Clear()
+
+		for _, result := range results {
+			result := result
+
+			if result.Status == BATCH_PASSED {
+				passed++
+			}
+
+			tui.batchViewer.AddItem(BatchLabel(result, tui.dpath), "", 0, func() {
+				tui.ExpandBatchResult(result)
+			})
+		}
+
+		tui.batchViewer.SetCurrentItem(current)
+		tui.batchViewer.SetTitle(fmt.Sprintf(" Batch (%d/%d passed) ", passed, len(results)))
+	})
+}
+
+// Show a batch file's output beside the batch list, or hide it if already shown
+func (tui *TUI) ExpandBatchResult(result BatchResult) {
+	title := " " + tview.Escape(filepath.Base(result.File)) + " "
+
+	if tui.showBatchOutput && tui.batchOutput.GetTitle() == title {
+		tui.showBatchOutput = false
+		tui.Relayout()
+		return
+	}
+
+	tui.batchOutput.SetTitle(title)
+	tui.batchOutput.SetText(tview.Escape(string(result.Output)))
+	tui.batchOutput.ScrollToBeginning()
+
+	tui.showBatchOutput = true
+	tui.Relayout()
+}
+
+// Show or hide the history pane
+func (tui *TUI) ToggleHistory() {
+	tui.showHistory = !tui.showHistory
+	tui.Relayout()
+}
+
+// Add a run's output to the history, and to disk if snapshots are saved, the session is persisted,
+// or a JUnit report is written
+func (tui *TUI) RecordHistory(entry HistoryEntry) {
+	tui.history.Record(entry)
+
+	if len(tui.snapshotDir) > 0 {
+		if err := SaveSnapshot(tui.snapshotDir, entry); err != nil {
+			tui.ReportError(fmt.Errorf("could not save snapshot: %v", err))
+		}
+	}
+
+	if len(tui.junitPath) > 0 && !entry.Partial {
+		if err := WriteJUnitReport(tui.junitPath, entry, filepath.Base(tui.editorFile.File.Name())); err != nil {
+			tui.ReportError(fmt.Errorf("could not write JUnit report: %v", err))
+		}
+	}
+
+	if tui.session != nil && !entry.Partial {
+		if err := tui.session.SaveRun(entry, filepath.Ext(tui.editorFile.File.Name())); err != nil {
+			tui.ReportError(fmt.Errorf("could not persist run %d: %v", entry.Run, err))
+		}
+
+		if len(tui.session.GitDir) > 0 {
+			if err := CommitRun(tui.session.GitDir, entry, filepath.Base(tui.editorFile.File.Name())); err != nil {
+				tui.ReportError(fmt.Errorf("could not commit run %d: %v", entry.Run, err))
+			}
+		}
+	}
+
+	tui.ShowHistory()
+}
+
+// Preserve the output of the run in progress, in case it is killed before finishing
+func (tui *TUI) SnapshotRun() {
+	tui.RecordHistory(HistoryEntry{
+		Run:     tui.RunCount() + 1,
+		Stdout:  tui.stdoutBuffer.Bytes(),
+		Stderr:  tui.stderrBuffer.Bytes(),
+		Source:  tui.source,
+		Partial: true,
+		SavedAt: time.Now(),
+	})
+}
+
+// List previous runs, newest first, keeping the current selection
+func (tui *TUI) ShowHistory() {
+	entries := tui.history.List()
+
+	tui.app.QueueUpdateDraw(func() {
+		current := tui.historyViewer.GetCurrentItem()
+		tui.historyViewer.Clear()
+
+		for idx := len(entries) - 1; idx >= 0; idx-- {
+			entry := entries[idx]
+
+			tui.historyViewer.AddItem(HistoryLabel(entry), "", 0, func() {
+				tui.ExpandHistoryEntry(entry)
+			})
+		}
+
+		tui.historyViewer.SetCurrentItem(current)
+		tui.historyViewer.SetTitle(" History (" + fmt.Sprint(len(entries)) + ") ")
+	})
+}
+
+// Show a previous run's output beside the history list, or hide it if already shown
+func (tui *TUI) ExpandHistoryEntry(entry HistoryEntry) {
+	title := " Run " + fmt.Sprint(entry.Run) + " "
+
+	if tui.showHistoryOut && tui.historyOutput.GetTitle() == title {
+		tui.showHistoryOut = false
+		tui.Relayout()
+		return
+	}
+
+	entry = tui.history.Load(entry)
+
+	text := tview.Escape(string(entry.Stdout))
+	if len(entry.Stderr) > 0 {
+		text += "\n[red]stderr[reset]\n" + tview.Escape(string(entry.Stderr))
+	}
+
+	if len(entry.Source) > 0 {
+		text += "\n[yellow]source[reset]\n" + tview.Escape(string(entry.Source))
+	}
+
+	tui.historyOutput.SetTitle(title)
+	tui.historyOutput.SetText(text)
+	tui.historyOutput.ScrollToBeginning()
+
+	tui.showHistoryOut = true
+	tui.Relayout()
+}
+
+// Show or hide the diff pane
+func (tui *TUI) ToggleDiff() {
+	tui.showDiff = !tui.showDiff
+	tui.Relayout()
+}
+
+// Diff the code and output of the last two finished runs
+func (tui *TUI) ShowDiff() {
+	finished := []HistoryEntry{}
+	for _, entry := range tui.history.List() {
+		if !entry.Partial {
+			finished = append(finished, entry)
+		}
+	}
+
+	if len(finished) < 2 {
+		return
+	}
+
+	before, after := tui.history.Load(finished[len(finished)-2]), tui.history.Load(finished[len(finished)-1])
+
+	text := "[yellow]source[reset]\n" + DiffText(string(before.Source), string(after.Source)) +
+		"\n[yellow]stdout[reset]\n" + DiffText(string(before.Stdout), string(after.Stdout))
+
+	if len(before.Stderr) > 0 || len(after.Stderr) > 0 {
+		text += "\n[yellow]stderr[reset]\n" + DiffText(string(before.Stderr), string(after.Stderr))
+	}
+
+	tui.diffViewer.SetTitle(fmt.Sprintf(" Diff · run %d → %d ", before.Run, after.Run))
+	tui.diffViewer.SetText(text)
+	tui.diffViewer.ScrollToBeginning()
+}
+
+// Show or hide the profile pane
+func (tui *TUI) ToggleProfile() {
+	tui.showProfile = !tui.showProfile
+	tui.Relayout()
+}
+
+// Show or hide the syscalls pane
+func (tui *TUI) ToggleSyscalls() {
+	tui.showSyscalls = !tui.showSyscalls
+	tui.Relayout()
+}
+
+// Show or hide the problems pane
+func (tui *TUI) ToggleProblems() {
+	tui.showProblems = !tui.showProblems
+	tui.Relayout()
+}
+
+// List the problems diagnosed in the last run, showing the pane if there are any
+func (tui *TUI) ShowProblems(problems []Problem) {
+	tui.app.QueueUpdateDraw(func() {
+		tui.problemsViewer.Clear()
+		tui.problemsViewer.SetTitle(" Problems (" + fmt.Sprint(len(problems)) + ") ")
+
+		for _, problem := range problems {
+			problem := problem
+
+			tui.problemsViewer.AddItem(ProblemLabel(problem, tui.dpath), "", 0, func() {
+				tui.JumpToProblem(problem)
+			})
+		}
+
+		if len(problems) > 0 && !tui.showProblems {
+			tui.showProblems = true
+			tui.Relayout()
+		}
+	})
+}
+
+// Scroll stderr to the raw text a problem was parsed from, unfolding and separating it from stdout if needed
+func (tui *TUI) JumpToProblem(problem Problem) {
+	if problem.RawLine == 0 {
+		return
+	}
+
+	if tui.showCombined {
+		tui.ToggleCombined()
+	}
+
+	if tui.stderrFolded {
+		tui.ToggleFold()
+	}
+
+	tui.stderrViewer.ScrollToLine(problem.RawLine - 1)
+	tui.Focus(tui.stderrViewer)
+}
+
+// List the busiest functions of the last profiled run
+func (tui *TUI) ShowProfile(entries []ProfileEntry) {
+	if len(entries) > PROFILE_TOP_FUNCTIONS {
+		entries = entries[:PROFILE_TOP_FUNCTIONS]
+	}
+
+	text := ""
+	for _, entry := range entries {
+		text += fmt.Sprintf("[yellow]%5.1f%%[reset]  %s\n", entry.Percent, tview.Escape(entry.Function))
+	}
+
+	tui.profileViewer.SetText(text)
+}
+
+// Show or hide the image preview pane
+func (tui *TUI) TogglePreview() {
+	tui.showPreview = !tui.showPreview
+	tui.Relayout()
+}
+
+// Show an image in the preview pane
+func (tui *TUI) PreviewImage(fpath string, dpath string) {
+	if err := tui.previewViewer.Load(fpath); err != nil {
+		tui.ReportError(fmt.Errorf("could not preview %s: %v", fpath, err))
+		return
+	}
+
+	name, err := filepath.Rel(dpath, fpath)
+	if err != nil {
+		name = fpath
+	}
+
+	tui.app.QueueUpdateDraw(func() {
+		tui.previewViewer.SetTitle(" Preview: " + name + " ")
+
+		if !tui.showPreview {
+			tui.showPreview = true
+			tui.Relayout()
+		}
+	})
+}
+
+// Rebuild the grid, after the set of visible panes has changed
+func (tui *TUI) Relayout() {
+	tui.SetRoot()
+	tui.Focus(tui.focused)
+}
+
+// Show the grid as the application's root, or as the session's page if sessions are tabbed
+func (tui *TUI) SetRoot() {
+	if tui.tabs != nil {
+		tui.tabs.SetPage(tui, tui.Grid())
+		return
+	}
+
+	tui.app.SetRoot(tui.Grid(), true)
+}
+
+// A pane that can hold focus, and show that it does
+type FocusablePane interface {
+	tview.Primitive
+	SetBorderAttributes(attr tcell.AttrMask) *tview.Box
+	InRect(x, y int) bool
+}
+
+// The visible panes that can hold focus, in the order Tab visits them
+func (tui *TUI) FocusablePanes() []FocusablePane {
+	panes := []FocusablePane{}
+
+	if tui.showCombined {
+		panes = append(panes, tui.combinedViewer)
+	} else {
+		panes = append(panes, tui.stdoutViewer)
+
+		if tui.showPinned {
+			panes = append(panes, tui.pinnedViewer)
+		}
+
+		panes = append(panes, tui.stderrViewer)
+	}
+
+	if tui.showPreview {
+		panes = append(panes, tui.previewViewer)
+	}
+
+	if tui.showDiff {
+		panes = append(panes, tui.diffViewer)
+	}
+
+	if tui.showProfile {
+		panes = append(panes, tui.profileViewer)
+	}
+
+	if tui.showSyscalls {
+		panes = append(panes, tui.syscallViewer)
+	}
+
+	if tui.showArtifacts {
+		panes = append(panes, tui.artifactsViewer)
+	}
+
+	if tui.showEnv {
+		panes = append(panes, tui.envViewer)
+	}
+
+	if tui.showProblems {
+		panes = append(panes, tui.problemsViewer)
+	}
+
+	if tui.showHistory {
+		panes = append(panes, tui.historyViewer)
+
+		if tui.showHistoryOut {
+			panes = append(panes, tui.historyOutput)
+		}
+	}
+
+	if tui.showBatch {
+		panes = append(panes, tui.batchViewer)
+
+		if tui.showBatchOutput {
+			panes = append(panes, tui.batchOutput)
+		}
+	}
+
+	return panes
+}
+
+// Focus a pane, or the first visible pane if it is hidden; the focused pane's border is bold
+func (tui *TUI) Focus(target FocusablePane) {
+	panes := tui.FocusablePanes()
+	tui.focused = panes[0]
+
+	for _, pane := range panes {
+		if pane == target {
+			tui.focused = target
+		}
+	}
+
+	for _, pane := range panes {
+		if pane == tui.focused {
+			pane.SetBorderAttributes(tcell.AttrBold)
+		} else {
+			pane.SetBorderAttributes(tcell.AttrNone)
+		}
+	}
+
+	// background tabs keep their focus until they're shown
+	if tui.tabs == nil || tui.tabs.Current() == tui {
+		tui.app.SetFocus(tui.focused)
+	}
+}
+
+// Scroll the focused pane by a number of lines, or list items
+func (tui *TUI) ScrollFocused(lines int) {
+	switch pane := tui.focused.(type) {
+	case *tview.TextView:
+		row, column := pane.GetScrollOffset()
+		if row+lines < 0 {
+			lines = -row
+		}
+
+		pane.ScrollTo(row+lines, column)
+	case *LogView:
+		row, column := pane.GetScrollOffset()
+		if row+lines < 0 {
+			lines = -row
+		}
+
+		pane.ScrollTo(row+lines, column)
+	case *tview.List:
+		item := pane.GetCurrentItem() + lines
+		if item < 0 {
+			item = 0
+		}
+
+		if item >= pane.GetItemCount() {
+			item = pane.GetItemCount() - 1
+		}
+
+		pane.SetCurrentItem(item)
+	}
+}
+
+// Move focus forwards or backwards through the visible panes
+func (tui *TUI) CycleFocus(step int) {
+	panes := tui.FocusablePanes()
+	current := 0
+
+	for idx, pane := range panes {
+		if pane == tui.focused {
+			current = idx
+		}
+	}
+
+	next := (current + step + len(panes)) % len(panes)
+	tui.Focus(panes[next])
+}
+
+// Show that a run is in progress
+func (tui *TUI) MarkRunning() {
+	tui.SetBorderColor(RUNNING_COLOR)
+	tui.SetStatus(TAB_RUNNING)
+	tui.linear.Started(tui.RunCount()+1, tui.editorFile.File.Name())
+}
+
+// Note whether the session is running, passed, or failed, for its tab
+func (tui *TUI) SetStatus(status string) {
+	tui.status = status
+
+	if tui.tabs != nil {
+		tui.tabs.RefreshStrip()
+	}
+}
+
+// Record a finished run, updating the run counter and statistics
+func (tui *TUI) RecordRun(result RunResult) {
+	if result.Succeeded() {
+		tui.SetBorderColor(SUCCESS_COLOR)
+		tui.SetStatus(TAB_PASSED)
+	} else {
+		tui.SetBorderColor(FAILURE_COLOR)
+		tui.SetStatus(TAB_FAILED)
+	}
+
+	// compare the run to those before it
+	ratio, changed := tui.stats.CompareRuntime(result.Duration)
+
+	tui.stats.Record(result)
+	tui.UpdateRunCount()
+	tui.linear.Finished(tui.RunCount(), result)
+	tui.events.Event("run", !result.Succeeded(), map[string]interface{}{
+		"run":         tui.RunCount(),
+		"file":        tui.editorFile.File.Name(),
+		"exit_code":   result.ExitCode,
+		"duration_ms": result.Duration.Milliseconds(),
+		"timed_out":   result.TimedOut,
+	})
+	tui.RecordCoverage()
+	entry := HistoryEntry{
+		Run:     tui.RunCount(),
+		Result:  result,
+		Stdout:  tui.stdoutBuffer.Bytes(),
+		Stderr:  tui.stderrBuffer.Bytes(),
+		Source:  tui.source,
+		SavedAt: time.Now(),
+	}
+
+	tui.RecordHistory(entry)
+	tui.ShowDiff()
+	tui.CallWebhooks(entry)
+	summary := "run " + fmt.Sprint(tui.RunCount()) + " times · last at " + result.StartedAt.Format(LAST_RUN_FORMAT)
+	if result.FilesChanged > 1 {
+		summary += fmt.Sprintf(" · %d files changed", result.FilesChanged)
+	}
+
+	tui.runCountViewer.SetText(summary)
+	runtime := tui.stats.Durations() + " · cpu " + FormatDuration(result.CPUTime())
+	if changed && ratio > 1 {
+		runtime = "[red]▲ " + runtime + "[reset]"
+	} else if changed {
+		runtime = "[green]▼ " + runtime + "[reset]"
+	}
+
+	tui.runSecondsViewer.SetText(runtime)
+	tui.sparklineViewer.SetText(tui.stats.Sparkline())
+	tui.statsViewer.SetText(tui.stats.String())
+	tui.RefreshHeader()
+
+	if result.MaxRSS > 0 {
+		tui.memoryViewer.SetText("peak memory " + FormatBytes(result.MaxRSS))
+	}
+}
+
+// POST a finished run to the config's webhooks whose thresholds it meets, in the background
+func (tui *TUI) CallWebhooks(entry HistoryEntry) {
+	if len(tui.webhooks) == 0 {
+		return
+	}
+
+	payload := NewWebhookPayload(entry, tui.editorFile.File.Name(), tui.lang, tui.stats.FailStreak)
+
+	for _, hook := range tui.webhooks {
+		if !hook.ShouldSend(payload) {
+			continue
+		}
+
+		go func(hook Webhook) {
+			if err := SendWebhook(hook, payload); err != nil {
+				tui.ReportError(err)
+			}
+		}(hook)
+	}
+}
+
+// Show the coverage a test run reported, compared with the previous run that reported it
+func (tui *TUI) RecordCoverage() {
+	output := append(tui.stdoutBuffer.Bytes(), tui.stderrBuffer.Bytes()...)
+
+	coverage, ok := ParseCoverage(output)
+	if !ok {
+		return
+	}
+
+	tui.coverage = FormatCoverage(coverage, tui.lastCoverage, tui.hasCoverage)
+	tui.lastCoverage = coverage
+	tui.hasCoverage = true
+}
+
+// Show which attempt of a retried run is in progress
+func (tui *TUI) SetAttempt(attempt int, retries int) {
+	tui.attempt = attempt
+	tui.retries = retries
+	tui.RefreshHeader()
+}
+
+// Show recent exit codes, and the attempt number while a failed run is retried
+func (tui *TUI) RefreshHeader() {
+	text := tui.headerText + "  " + tui.stats.ExitStrip()
+
+	if len(tui.coverage) > 0 {
+		text += "  " + tui.coverage
+	}
+
+	if tui.attempt > 0 {
+		text += fmt.Sprintf("  [yellow]retry %d/%d[reset]", tui.attempt, tui.retries)
+	}
+
+	if tui.focus != nil {
+		text += "  " + tui.focus.String()
+	}
+
+	tui.header.SetText(text)
+}
+
+// Show where the session is shared, and the token partners need, in the help bar
+func (tui *TUI) SetSharing(addr string, token string) {
+	sharing := "[blue]sharing on " + tview.Escape(addr) + " · token " + tview.Escape(token) + "[reset]"
+	tui.helpBar.SetText(tui.helpBar.GetText(false) + "    " + sharing)
+}
+
+// Count the files a burst of changes touched, to report with the run it causes
+func (tui *TUI) NoteChangedFiles(count int) {
+	atomic.AddInt32(&tui.changedFiles, int32(count))
+}
+
+// Take the count of files changed since the last run started
+func (tui *TUI) TakeChangedFiles() int {
+	return int(atomic.SwapInt32(&tui.changedFiles, 0))
+}
+
+// Reset the run counter and statistics
+func (tui *TUI) ResetStats() {
+	tui.stats.Reset()
+
+	tui.runLock.Lock()
+	tui.runCount = 0
+	tui.runLock.Unlock()
+
+	tui.runCountViewer.SetText("run 0 times")
+	tui.runSecondsViewer.SetText(tui.stats.Durations())
+	tui.sparklineViewer.SetText(tui.stats.Sparkline())
+	tui.statsViewer.SetText(tui.stats.String())
+}
+
+func (tui *TUI) UpdateRunTime(diff time.Duration) {
+	tui.runTime = diff.Milliseconds()
+	tui.runSecondsViewer.SetText(fmt.Sprint(tui.runTime) + "ms")
+}
+
+// Arrange the output panes, with any optional panes beneath them
+func (tui *TUI) Body() *tview.Flex {
+	outputs := tview.NewFlex()
+
+	if tui.showCombined {
+		outputs.AddItem(tui.combinedViewer, 0, 1, true)
+	} else {
+		outputs.AddItem(tui.stdoutViewer, 0, 1, true)
+
+		if tui.showPinned {
+			outputs.AddItem(tui.pinnedViewer, 0, 1, false)
+		}
+
+		outputs.AddItem(tui.stderrViewer, 0, 1, false)
+	}
+
+	if tui.showPreview {
+		outputs.AddItem(tui.previewViewer, 0, 1, false)
+	}
+
+	if tui.showDiff {
+		outputs.AddItem(tui.diffViewer, 0, 1, false)
+	}
+
+	if tui.showProfile {
+		outputs.AddItem(tui.profileViewer, 0, 1, false)
+	}
+
+	if tui.showSyscalls {
+		outputs.AddItem(tui.syscallViewer, 0, 1, false)
+	}
+
+	body := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(outputs, 0, 1, true)
+
+	if tui.showArtifacts {
+		body.AddItem(tui.artifactsViewer, ARTIFACTS_ROWS, 0, false)
+	}
+
+	if tui.showEnv {
+		body.AddItem(tui.envViewer, ENV_ROWS, 0, false)
+	}
+
+	if tui.showProblems {
+		body.AddItem(tui.problemsViewer, PROBLEMS_ROWS, 0, false)
+	}
+
+	if tui.showHistory {
+		history := tview.NewFlex().
+			AddItem(tui.historyViewer, 0, 1, false)
+
+		if tui.showHistoryOut {
+			history.AddItem(tui.historyOutput, 0, 2, false)
+		}
+
+		body.AddItem(history, HISTORY_ROWS, 0, false)
+	}
+
+	if tui.showBatch {
+		batch := tview.NewFlex().
+			AddItem(tui.batchViewer, 0, 1, false)
+
+		if tui.showBatchOutput {
+			batch.AddItem(tui.batchOutput, 0, 2, false)
+		}
+
+		body.AddItem(batch, BATCH_ROWS, 0, false)
+	}
+
+	return body
+}
+
+// Arrange TUI components into a grid
+func (tui *TUI) Grid() *tview.Grid {
+	grid := tview.NewGrid().
+		SetBorders(false).
+		SetRows(1, 1, 0, 1, 1).
+		SetColumns(-3, -2, -2, -2, SPARKLINE_LENGTH+1).
+		AddItem(tui.header, ROW_0, COL_0, ROWSPAN_1, COLSPAN_1, MINWIDTH_0, MINHEIGHT_0, true).
+		AddItem(tui.memoryViewer, ROW_0, COL_1, ROWSPAN_1, COLSPAN_1, MINWIDTH_0, MINHEIGHT_0, false).
+		AddItem(tui.runCountViewer, ROW_0, COL_2, ROWSPAN_1, COLSPAN_1, MINWIDTH_0, MINHEIGHT_0, true).
+		AddItem(tui.runSecondsViewer, ROW_0, COL_3, ROWSPAN_1, COLSPAN_1, MINWIDTH_0, MINHEIGHT_0, true).
+		AddItem(tui.sparklineViewer, ROW_0, COL_4, ROWSPAN_1, COLSPAN_1, MINWIDTH_0, MINHEIGHT_0, false).
+		AddItem(tui.statsViewer, ROW_1, COL_0, ROWSPAN_1, COLSPAN_5, MINWIDTH_0, MINHEIGHT_0, false)
+
+	return grid.
+		AddItem(tui.Body(), ROW_2, COL_0, ROWSPAN_1, COLSPAN_5, MINWIDTH_0, MINHEIGHT_0, true).
+		AddItem(tui.errorBar, ROW_3, COL_0, ROWSPAN_1, COLSPAN_5, MINWIDTH_0, MINHEIGHT_0, false).
+		AddItem(tui.helpBar, ROW_4, COL_0, ROWSPAN_1, COLSPAN_5, MINWIDTH_0, MINHEIGHT_0, false)
+}
+
+// Start the TUI
+func (tui *TUI) Start(args *ReplitArgs) {
+	tui.SetRoot()
+	tui.Focus(tui.stdoutViewer)
+
+	// linear sessions draw the interface offscreen, and print runs instead
+	if args.Linear {
+		screen := tcell.NewSimulationScreen("")
+		screen.Init()
+		tui.app.SetScreen(screen)
+		tui.previewViewer.protocol = IMAGE_BLOCKS
+
+		tui.linear.Print(fmt.Sprintf("Running %s with %s on each save. Press Ctrl-C to stop.", args.EditorFile.File.Name(), args.Lang))
+	} else {
+		OpenScreen(tui.app, !args.NoMouse)
+	}
+
+	if err := tui.app.Run(); err != nil {
+		fmt.Printf("RL: Application crashed! %v", err)
+	}
+}
+
+// Show help-text to help user's use Replit
+func NewHelpbar(tui *TUI, args *ReplitArgs) *tview.TextView {
+	template := HELP_TEMPLATE
+	if len(args.Config.Help) > 0 {
+		template = args.Config.Help
+	}
+
 	return tview.NewTextView().
 		SetDynamicColors(true).
-		SetText("Edit [red]" + args.EditorFile.File.Name() + "[reset] & save to run with [red]" + args.Lang + "[reset]")
+		SetText(ExpandTemplate(template, TemplateValues(args)))
 }
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Is the file argument a URL to download, rather than a path?
+func IsURL(file string) bool {
+	return strings.HasPrefix(file, "http://") || strings.HasPrefix(file, "https://")
+}
+
+// The URL of a page's raw content; gists and GitHub files link to HTML pages by default
+func RawURL(link string) string {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+
+	parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+
+	switch {
+	case parsed.Host == "gist.github.com" && len(parts) == 2:
+		// gist.github.com/<user>/<id>
+		parsed.Host = "gist.githubusercontent.com"
+		parsed.Path = "/" + strings.Join(parts, "/") + "/raw"
+	case parsed.Host == "github.com" && len(parts) > 4 && parts[2] == "blob":
+		// github.com/<owner>/<repo>/blob/<ref>/<path>
+		parsed.Host = "raw.githubusercontent.com"
+		parsed.Path = "/" + strings.Join(append(parts[:2], parts[3:]...), "/")
+	}
+
+	return parsed.String()
+}
+
+// The file extension a URL's content likely has, or "" if it has none
+func URLExtension(link string) string {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+
+	return path.Ext(parsed.Path)
+}
+
+// Download a URL's raw content, up to a limit
+func FetchURL(link string) ([]byte, error) {
+	client := http.Client{Timeout: FETCH_TIMEOUT}
+
+	res, err := client.Get(RawURL(link))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", RawURL(link), res.Status)
+	}
+
+	content, err := ioutil.ReadAll(io.LimitReader(res.Body, FETCH_LIMIT+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(content) > FETCH_LIMIT {
+		return nil, fmt.Errorf("%s is larger than %s", RawURL(link), FormatBytes(FETCH_LIMIT))
+	}
+
+	return content, nil
+}
+
+// A URL whose content is piped to the program's stdin; fetched once and cached, or fetched every run
+type StdinSource struct {
+	lock    sync.Mutex
+	URL     string
+	Refetch bool
+	content []byte
+	fetched bool
+}
+
+// The content to pipe to this run
+func (source *StdinSource) Content() ([]byte, error) {
+	source.lock.Lock()
+	defer source.lock.Unlock()
+
+	if source.fetched && !source.Refetch {
+		return source.content, nil
+	}
+
+	content, err := FetchURL(source.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	source.content = content
+	source.fetched = true
+
+	return content, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRawURL(t *testing.T) {
+	tests := []struct {
+		name string
+		link string
+		want string
+	}{
+		{"Links gists to their raw content", "https://gist.github.com/user/abc123", "https://gist.githubusercontent.com/user/abc123/raw"},
+		{"Links GitHub files to their raw content", "https://github.com/owner/repo/blob/main/src/app.py", "https://raw.githubusercontent.com/owner/repo/main/src/app.py"},
+		{"Leaves other URLs unchanged", "https://example.com/script.py", "https://example.com/script.py"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RawURL(tt.link); got != tt.want {
+				t.Errorf("RawURL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/missing.py" {
+			http.NotFound(writer, req)
+			return
+		}
+
+		if req.URL.Path == "/large.txt" {
+			writer.Write(make([]byte, FETCH_LIMIT+1))
+			return
+		}
+
+		fmt.Fprint(writer, "print('hi')\n")
+	}))
+	defer server.Close()
+
+	content, err := FetchURL(server.URL + "/script.py")
+	if err != nil || string(content) != "print('hi')\n" {
+		t.Errorf("expected the script's content, but got %q (%v)", content, err)
+	}
+
+	if _, err := FetchURL(server.URL + "/missing.py"); err == nil {
+		t.Error("expected an error for a missing page")
+	}
+
+	if _, err := FetchURL(server.URL + "/large.txt"); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("expected an error for a page over the limit, but got %v", err)
+	}
+}
+
+func TestStdinSource(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		requests += 1
+		fmt.Fprintf(writer, "response %d", requests)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		refetch bool
+		want    []string
+	}{
+		{"Reuses the first response", false, []string{"response 1", "response 1"}},
+		{"Fetches again before each run", true, []string{"response 2", "response 3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &StdinSource{URL: server.URL, Refetch: tt.refetch}
+
+			for _, want := range tt.want {
+				content, err := source.Content()
+				if err != nil || string(content) != want {
+					t.Errorf("expected %q, but got %q (%v)", want, content, err)
+				}
+			}
+		})
+	}
+}
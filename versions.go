@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"sync"
+)
+
+// The watched file's content at each run, so a change can be undone without the editor's history
+type FileVersions struct {
+	lock     sync.Mutex
+	versions [][]byte
+}
+
+// Record the content a run used, unless it's unchanged since the last run. Only the latest versions are kept
+func (versions *FileVersions) Record(content []byte) {
+	versions.lock.Lock()
+	defer versions.lock.Unlock()
+
+	count := len(versions.versions)
+	if count > 0 && bytes.Equal(versions.versions[count-1], content) {
+		return
+	}
+
+	versions.versions = append(versions.versions, content)
+
+	if len(versions.versions) > FILE_VERSION_LIMIT {
+		versions.versions = versions.versions[len(versions.versions)-FILE_VERSION_LIMIT:]
+	}
+}
+
+// Forget the latest version, and return the one before it; restoring it records it again
+func (versions *FileVersions) Previous() ([]byte, bool) {
+	versions.lock.Lock()
+	defer versions.lock.Unlock()
+
+	count := len(versions.versions)
+	if count < 2 {
+		return nil, false
+	}
+
+	versions.versions = versions.versions[:count-1]
+	return versions.versions[count-2], true
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestFileVersions(t *testing.T) {
+	versions := &FileVersions{}
+
+	if _, ok := versions.Previous(); ok {
+		t.Fatal("expected no previous version before any runs")
+	}
+
+	for _, content := range []string{"a", "b", "b", "c"} {
+		versions.Record([]byte(content))
+	}
+
+	// restoring a version reruns, recording it again; that shouldn't duplicate it
+	for _, want := range []string{"b", "a"} {
+		content, ok := versions.Previous()
+		if !ok || string(content) != want {
+			t.Fatalf("expected to undo to %q, but got %q", want, content)
+		}
+
+		versions.Record(content)
+	}
+
+	if _, ok := versions.Previous(); ok {
+		t.Error("expected no version before the first")
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+)
+
+// Python and node programs that wait for a path on file descriptor 3, then run it as the main module
+const (
+	warmPythonBootstrap = `import os, runpy, sys
+path = os.read(3, 4096).decode()
+os.close(3)
+sys.argv = [path]
+sys.path[0] = os.path.dirname(path)
+runpy.run_path(path, run_name="__main__")`
+	warmNodeBootstrap = `const fs = require("fs");
+const buffer = Buffer.alloc(4096);
+const count = fs.readSync(3, buffer);
+fs.closeSync(3);
+process.argv[1] = require("path").resolve(buffer.toString("utf8", 0, count));
+require("module").runMain();`
+)
+
+// A writer that holds output until it is pointed at another writer
+type SwitchWriter struct {
+	lock   sync.Mutex
+	held   bytes.Buffer
+	target io.Writer
+}
+
+func (writer *SwitchWriter) Write(data []byte) (int, error) {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	if writer.target == nil {
+		return writer.held.Write(data)
+	}
+
+	return writer.target.Write(data)
+}
+
+// Write the held output to a writer, and everything after it
+func (writer *SwitchWriter) Switch(target io.Writer) {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	target.Write(writer.held.Bytes())
+	writer.held.Reset()
+	writer.target = target
+}
+
+// An interpreter started ahead of a run, waiting to be told which file to run
+type WarmProcess struct {
+	Cmd    *exec.Cmd
+	fpath  string
+	start  *os.File
+	stdout *SwitchWriter
+	stderr *SwitchWriter
+}
+
+// Send the interpreter its output's writers and the file to run
+func (warm *WarmProcess) Begin(stdout io.Writer, stderr io.Writer) error {
+	warm.stdout.Switch(stdout)
+	warm.stderr.Switch(stderr)
+
+	_, err := warm.start.Write([]byte(warm.fpath))
+	warm.start.Close()
+
+	// an interpreter that exited before its run is reaped, and reported
+	if err != nil {
+		KillProcess(warm.Cmd)
+		warm.Cmd.Wait()
+		return fmt.Errorf("the interpreter exited before the run: %v", err)
+	}
+
+	return nil
+}
+
+// Interpreters started ahead of runs, so that runs don't wait for their interpreter to start
+type WarmPool struct {
+	lock   sync.Mutex
+	args   *ReplitArgs
+	tui    *TUI
+	size   int
+	idle   []*WarmProcess
+	closed bool
+}
+
+// The interpreter flags that wait for a file to run, or nil if the language can't be started ahead.
+// Flags that already choose what to run, such as python's -m, can't be started ahead either
+func WarmBootstrap(lang string) []string {
+	program, flags := SplitLanguage(lang)
+
+	for _, flag := range flags {
+		switch flag {
+		case "-m", "-c", "-e", "-p", "--eval", "--print":
+			return nil
+		}
+	}
+
+	if name := filepath.Base(program); name == "node" {
+		return []string{"-e", warmNodeBootstrap}
+	} else if ImportLanguage(lang) == IMPORTS_PYTHON {
+		return []string{"-c", warmPythonBootstrap}
+	}
+
+	return nil
+}
+
+// Keep size interpreters started, reporting those that can't be started to a TUI
+func NewWarmPool(args *ReplitArgs, tui *TUI, size int) *WarmPool {
+	pool := &WarmPool{args: args, tui: tui, size: size}
+	go pool.Fill()
+
+	return pool
+}
+
+// Start interpreters until the pool is full
+func (pool *WarmPool) Fill() {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	for !pool.closed && len(pool.idle) < pool.size {
+		warm, err := pool.spawn()
+		if err != nil {
+			pool.tui.ReportError(fmt.Errorf("could not start an interpreter ahead of the next run: %v", err))
+			return
+		}
+
+		pool.idle = append(pool.idle, warm)
+	}
+}
+
+// Start an interpreter, waiting on a pipe for its file
+func (pool *WarmPool) spawn() (*WarmProcess, error) {
+	args := pool.args
+	program, flags := SplitLanguage(args.Lang)
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	argv := append(append([]string{program}, flags...), WarmBootstrap(args.Lang)...)
+	warm := &WarmProcess{Cmd: exec.Command(argv[0], argv[1:]...), start: writer, stdout: &SwitchWriter{}, stderr: &SwitchWriter{}}
+
+	cmd := warm.Cmd
+	cmd.Stdout = warm.stdout
+	cmd.Stderr = warm.stderr
+	cmd.Env = ChildEnv(args)
+	cmd.ExtraFiles = []*os.File{reader}
+	ConfigureProcess(cmd, args)
+
+	if err := StartProcess(cmd); err != nil {
+		writer.Close()
+		return nil, err
+	}
+
+	return warm, nil
+}
+
+// Take a started interpreter to run a file, starting another in its place. Returns nil if
+// none is ready, or the pool is nil
+func (pool *WarmPool) Take(fpath string) *WarmProcess {
+	if pool == nil {
+		return nil
+	}
+
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	if len(pool.idle) == 0 {
+		return nil
+	}
+
+	warm := pool.idle[0]
+	pool.idle = pool.idle[1:]
+	warm.fpath = fpath
+
+	go pool.Fill()
+
+	return warm
+}
+
+// Kill the idle interpreters, and start no more
+func (pool *WarmPool) Close() {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	pool.closed = true
+
+	for _, warm := range pool.idle {
+		warm.start.Close()
+		KillProcess(warm.Cmd)
+		warm.Cmd.Wait()
+	}
+
+	pool.idle = nil
+}
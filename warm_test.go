@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestWarmBootstrap(t *testing.T) {
+	tests := []struct {
+		name string
+		lang string
+		want bool
+	}{
+		{"Starts python ahead", "python3", true},
+		{"Starts python with flags ahead", "python3 -X dev", true},
+		{"Starts node ahead", "node --enable-source-maps", true},
+		{"Doesn't start modules ahead", "python3 -m pytest", false},
+		{"Doesn't start other languages ahead", "ruby", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WarmBootstrap(tt.lang) != nil; got != tt.want {
+				t.Errorf("WarmBootstrap(%s) = %v, want %v", tt.lang, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWarmProcess(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 is not installed")
+	}
+
+	fpath := filepath.Join(t.TempDir(), "main.py")
+	ioutil.WriteFile(fpath, []byte("import sys\nprint(__name__, sys.argv[0])\n"), 0644)
+
+	pool := &WarmPool{args: &ReplitArgs{Lang: "python3"}, size: 1}
+	pool.Fill()
+
+	warm := pool.Take(fpath)
+	if warm == nil {
+		t.Fatal("Take() returned no interpreter")
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := warm.Begin(&stdout, &stderr); err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+
+	if err := warm.Cmd.Wait(); err != nil {
+		t.Fatalf("Wait() error = %v, stderr %s", err, stderr.String())
+	}
+
+	if want := "__main__ " + fpath + "\n"; stdout.String() != want {
+		t.Errorf("stdout = %q, want %q", stdout.String(), want)
+	}
+
+	pool.Close()
+}
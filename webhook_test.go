@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOutputTail(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		lines int
+		want  string
+	}{
+		{"Keeps the last lines", "a\nb\nc\n", 2, "b\nc"},
+		{"Keeps short output", "a\n", 2, "a"},
+		{"Handles empty output", "", 2, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := OutputTail([]byte(tt.data), tt.lines); got != tt.want {
+				t.Errorf("OutputTail() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendWebhook(t *testing.T) {
+	received := make(chan WebhookPayload, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+
+		received <- payload
+	}))
+	defer server.Close()
+
+	entry := HistoryEntry{
+		Run:    3,
+		Result: RunResult{ExitCode: 2, Duration: 1500 * time.Millisecond},
+		Stderr: []byte("boom\n"),
+	}
+
+	if err := SendWebhook(Webhook{URL: server.URL}, NewWebhookPayload(entry, "main.py", "python3", 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	payload := <-received
+	if payload.Status != "failed" || payload.ExitCode != 2 || payload.DurationMs != 1500 || payload.Run != 3 || payload.Stderr != "boom" || payload.File != "main.py" {
+		t.Errorf("sent %+v", payload)
+	}
+}
+
+func TestSendWebhookFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if err := SendWebhook(Webhook{URL: server.URL}, WebhookPayload{}); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestWebhookShouldSend(t *testing.T) {
+	tests := []struct {
+		name    string
+		hook    Webhook
+		payload WebhookPayload
+		want    bool
+	}{
+		{"Sends every run without thresholds", Webhook{}, WebhookPayload{DurationMs: 10}, true},
+		{"Skips short runs", Webhook{MinDuration: "1m"}, WebhookPayload{DurationMs: 10}, false},
+		{"Sends long runs", Webhook{MinDuration: "1m"}, WebhookPayload{DurationMs: 60000}, true},
+		{"Skips short failure streaks", Webhook{FailureStreak: 3}, WebhookPayload{FailureStreak: 2}, false},
+		{"Sends long failure streaks", Webhook{FailureStreak: 3}, WebhookPayload{FailureStreak: 3}, true},
+		{"Sends runs meeting either threshold", Webhook{MinDuration: "1m", FailureStreak: 3}, WebhookPayload{DurationMs: 10, FailureStreak: 4}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hook.ShouldSend(tt.payload); got != tt.want {
+				t.Errorf("ShouldSend() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		hook Webhook
+	}{
+		{"Rejects non-http urls", Webhook{URL: "ftp://x"}},
+		{"Rejects unknown formats", Webhook{URL: "https://x", Format: "teams"}},
+		{"Rejects invalid durations", Webhook{URL: "https://x", MinDuration: "soon"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.hook.Validate(); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestWebhookBody(t *testing.T) {
+	payload := WebhookPayload{Status: "failed", ExitCode: 1, DurationMs: 90000, Run: 4, FailureStreak: 2, File: "/tmp/train.py", Lang: "python3", Stderr: "abcdefghij"}
+
+	data, err := WebhookBody(Webhook{Format: WEBHOOK_SLACK}, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body map[string]string
+	json.Unmarshal(data, &body)
+
+	want := "✗ train.py failed (exit 1) after " + FormatDuration(90*time.Second) + " · run 4 · python3 · 2 failures in a row\n```\nabcdefghij\n```"
+	if body["text"] != want {
+		t.Errorf("WebhookBody() = %q, want %q", body["text"], want)
+	}
+
+	// messages are clipped to the service's limit, keeping the end of stderr
+	message := WebhookMessage(payload, len(want)-4)
+	if len(message) != len(want)-4 {
+		t.Errorf("WebhookMessage() = %q is %d long, want %d", message, len(message), len(want)-4)
+	}
+
+	// multi-byte characters aren't cut in two
+	payload.Stderr = "エラー"
+	if message := WebhookMessage(payload, len(want)-5); !strings.HasSuffix(message, "\n```\nー\n```") {
+		t.Errorf("WebhookMessage() = %q, want the tail cut between characters", message)
+	}
+}
+
+func TestNtfyRequest(t *testing.T) {
+	payload := WebhookPayload{Status: "failed", ExitCode: 1, Run: 2, File: "train.py", Lang: "python3", Stderr: "OOM"}
+
+	req, err := NtfyRequest(Webhook{URL: "https://ntfy.sh/runs", Format: WEBHOOK_NTFY}, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := ioutil.ReadAll(req.Body)
+	if string(body) != "OOM" {
+		t.Errorf("body = %q, want the tail of stderr", body)
+	}
+
+	if title, _ := new(mime.WordDecoder).DecodeHeader(req.Header.Get("Title")); !strings.HasPrefix(title, "✗ train.py failed (exit 1)") {
+		t.Errorf("title = %q", title)
+	}
+
+	if req.Header.Get("Priority") != "high" {
+		t.Errorf("failures should be sent at high priority")
+	}
+}
+
+func TestEmailMessage(t *testing.T) {
+	hook := Webhook{URL: "smtp://mail:25", Format: WEBHOOK_EMAIL, To: []string{"a@example.com", "b@example.com"}}
+	payload := WebhookPayload{Status: "passed", Run: 1, File: "etl.py", Lang: "python3"}
+
+	message := string(EmailMessage(hook, payload))
+
+	for _, want := range []string{
+		"From: replit@localhost\r\n",
+		"To: a@example.com, b@example.com\r\n",
+		"Subject: =?utf-8?q?replit:_=E2=9C=93_etl.py_passed",
+		"\r\n\r\n✓ etl.py passed",
+	} {
+		if !strings.Contains(message, want) {
+			t.Errorf("EmailMessage() = %q, missing %q", message, want)
+		}
+	}
+
+	if err := (Webhook{URL: "smtp://mail:25", Format: WEBHOOK_EMAIL}).Validate(); err == nil {
+		t.Error("expected email webhooks without recipients to be rejected")
+	}
+}